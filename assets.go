@@ -22,3 +22,8 @@ var clearSoundBytes []byte
 
 //go:embed assets/shot.mp3
 var shotSoundBytes []byte
+
+// Embed the sprite texture atlas (generated by cmd/atlasgen)
+//
+//go:embed assets/atlas.png
+var atlasImageBytes []byte
@@ -0,0 +1,172 @@
+package main
+
+import "fmt"
+
+// GameSpeedSteps are the selectable simulation speeds (1.0 = normal)
+var GameSpeedSteps = []float64{1.0, 0.75, 0.5}
+
+// Assist constants
+const (
+	// SpikeBounceStrength is the upward velocity given to a unit that lands on a spike in no-spike-death mode
+	SpikeBounceStrength = JUMP_STRENGTH
+
+	// Safe points for infinite checkpoint mode
+	safePointInterval = 30 // Ticks between recorded safe points
+	safePointMinAge   = 90 // A restored safe point is at least this many ticks older than the death
+	maxSafePoints     = 16
+)
+
+// AssistOptions are accessibility options that make stages easier.
+// Clears made with any assist enabled are flagged as assisted in the stats.
+type AssistOptions struct {
+	GameSpeed          float64 // Simulation speed multiplier (1.0 = normal)
+	NoSpikeDeath       bool    // Spikes bounce units instead of ending the attempt (hazards still do)
+	InfiniteCheckpoint bool    // Retrying restarts shortly before the death instead of from the spawn
+}
+
+// DefaultAssistOptions returns the options for normal play
+func DefaultAssistOptions() AssistOptions {
+	return AssistOptions{GameSpeed: 1.0}
+}
+
+// Active reports whether any assist is enabled
+func (a AssistOptions) Active() bool {
+	return a.speed() != 1.0 || a.NoSpikeDeath || a.InfiniteCheckpoint
+}
+
+// speed returns the simulation speed, treating an unset value as normal speed
+func (a AssistOptions) speed() float64 {
+	if a.GameSpeed <= 0 {
+		return 1.0
+	}
+	return a.GameSpeed
+}
+
+// nextGameSpeed returns the speed step after the current one
+func (a AssistOptions) nextGameSpeed() float64 {
+	for i, speed := range GameSpeedSteps {
+		if speed == a.speed() {
+			return GameSpeedSteps[(i+1)%len(GameSpeedSteps)]
+		}
+	}
+	return GameSpeedSteps[0]
+}
+
+// safePoint is a snapshot of the attempt taken while both units were standing safely
+type safePoint struct {
+	Tick        int
+	Blue        Unit
+	Red         Unit
+	collected   []bool
+	hazards     []Hazard
+	projectiles []Projectile
+}
+
+// handleAssistKeys toggles assist options (F5-F7)
+func (g *Game) handleAssistKeys(gameSpeed, noSpikeDeath, infiniteCheckpoint bool) {
+	if gameSpeed {
+		g.Assist.GameSpeed = g.Assist.nextGameSpeed()
+		g.showNotice(fmt.Sprintf("Game speed: %d%%", int(g.Assist.GameSpeed*100)))
+	}
+	if noSpikeDeath {
+		g.Assist.NoSpikeDeath = !g.Assist.NoSpikeDeath
+		g.showNotice("No spike death: " + onOff(g.Assist.NoSpikeDeath))
+	}
+	if infiniteCheckpoint {
+		g.Assist.InfiniteCheckpoint = !g.Assist.InfiniteCheckpoint
+		g.showNotice("Infinite checkpoint: " + onOff(g.Assist.InfiniteCheckpoint))
+	}
+	if g.State == StatePlaying && g.Assist.Active() {
+		g.attemptAssisted = true
+	}
+}
+
+// onOff formats a boolean setting for notices
+func onOff(enabled bool) string {
+	if enabled {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// bounceOffSpikes launches units touching a spike upward instead of killing them
+func (g *Game) bounceOffSpikes() {
+	for _, unit := range []*Unit{g.BlueUnit, g.RedUnit} {
+		for _, spike := range g.Stage.Spikes {
+			if !g.checkUnitSpikeCollision(unit, spike) {
+				continue
+			}
+			// Push the unit out past the spike's tip side and bounce it away
+			switch spike.Direction {
+			case SpikeDown:
+				unit.Y = spike.Y + CellSize
+				unit.VY = max(unit.VY, 0)
+			case SpikeLeft:
+				unit.X = spike.X - UnitSize
+				unit.Direction = -1
+			case SpikeRight:
+				unit.X = spike.X + CellSize
+				unit.Direction = 1
+			default:
+				if unit.Y+UnitSize > spike.Y {
+					unit.Y = spike.Y - UnitSize
+				}
+				unit.VY = -SpikeBounceStrength
+				unit.OnGround = false
+			}
+			unit.emitUnitEvent(EventBounced)
+			break
+		}
+	}
+}
+
+// recordSafePoint remembers where both units stood safely for infinite checkpoint mode
+func (g *Game) recordSafePoint() {
+	if g.Tick%safePointInterval != 0 || !g.BlueUnit.OnGround || !g.RedUnit.OnGround {
+		return
+	}
+	g.safePoints = append(g.safePoints, safePoint{
+		Tick:        g.Tick,
+		Blue:        *g.BlueUnit,
+		Red:         *g.RedUnit,
+		collected:   append([]bool(nil), g.collected...),
+		hazards:     append([]Hazard(nil), g.hazards...),
+		projectiles: append([]Projectile(nil), g.projectiles...),
+	})
+	if len(g.safePoints) > maxSafePoints {
+		g.safePoints = g.safePoints[1:]
+	}
+}
+
+// restoreSafePoint puts the attempt back to a safe point recorded well before
+// the last death. It returns false if there is no suitable safe point.
+func (g *Game) restoreSafePoint() bool {
+	for i := len(g.safePoints) - 1; i >= 0; i-- {
+		point := g.safePoints[i]
+		if point.Tick > g.Tick-safePointMinAge {
+			continue
+		}
+		// Drop newer safe points; they led to the death
+		g.safePoints = g.safePoints[:i+1]
+		restoreUnit(g.BlueUnit, point.Blue)
+		restoreUnit(g.RedUnit, point.Red)
+		g.collected = append(g.collected[:0], point.collected...)
+		g.hazards = append(g.hazards[:0], point.hazards...)
+		g.projectiles = append(g.projectiles[:0], point.projectiles...)
+		g.Tick = point.Tick
+		return true
+	}
+	return false
+}
+
+// restoreUnit copies simulation state from a snapshot while keeping the unit's
+// identity (colour, event bus)
+func restoreUnit(dst *Unit, snapshot Unit) {
+	dst.X, dst.Y = snapshot.X, snapshot.Y
+	dst.VX, dst.VY = snapshot.VX, snapshot.VY
+	dst.Direction = snapshot.Direction
+	dst.OnGround = snapshot.OnGround
+	dst.Stopped = snapshot.Stopped
+	dst.SpeedModifier = snapshot.SpeedModifier
+	dst.OnReverse = snapshot.OnReverse
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ChallengeKind identifies the rule of an optional stage objective
+type ChallengeKind int
+
+const (
+	ChallengeTime       ChallengeKind = iota // Clear in fewer than Limit seconds
+	ChallengeJumps                           // Clear with at most Limit jumps
+	ChallengeCollectAll                      // Collect every star in one clear
+)
+
+// Challenge is an optional objective on top of bringing both units to the goal
+type Challenge struct {
+	Kind  ChallengeKind
+	Limit int
+}
+
+// ClearResult summarises a clear for evaluating challenges
+type ClearResult struct {
+	Ticks        int // Simulation ticks from the start of the attempt
+	Jumps        int // Jumps by either unit during the attempt
+	Collected    int // Stars collected
+	Collectibles int // Stars available in the stage
+}
+
+// StageChallenges lists the optional objectives of each stage (none yet;
+// stages without an entry only show their stats)
+var StageChallenges = map[int][]Challenge{}
+
+// Description returns the text shown on the stage select and cleared screens
+func (c Challenge) Description() string {
+	switch c.Kind {
+	case ChallengeTime:
+		return fmt.Sprintf("Clear in under %ds", c.Limit)
+	case ChallengeJumps:
+		return fmt.Sprintf("Clear with %d jumps or fewer", c.Limit)
+	case ChallengeCollectAll:
+		return "Collect every star"
+	default:
+		return "Unknown challenge"
+	}
+}
+
+// Met reports whether a clear fulfils the challenge
+func (c Challenge) Met(result ClearResult) bool {
+	switch c.Kind {
+	case ChallengeTime:
+		return result.Ticks < c.Limit*ebiten.DefaultTPS
+	case ChallengeJumps:
+		return result.Jumps <= c.Limit
+	case ChallengeCollectAll:
+		return result.Collectibles > 0 && result.Collected == result.Collectibles
+	default:
+		return false
+	}
+}
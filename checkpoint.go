@@ -0,0 +1,125 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Checkpoint is a flag that one unit can touch to save its respawn position.
+// A checkpoint pair becomes active once both units have touched a flag of their colour.
+type Checkpoint struct {
+	X, Y float64
+	Unit UnitKind // Unit that can touch this flag
+}
+
+// CreateGridCheckpoint creates a checkpoint flag using grid coordinates
+func CreateGridCheckpoint(x, y int, unit UnitKind) Checkpoint {
+	return Checkpoint{
+		X:    GridToPixelX(x),
+		Y:    GridToPixelY(y),
+		Unit: unit,
+	}
+}
+
+// RespawnPoint is where both units restart after a death
+type RespawnPoint struct {
+	BlueX, BlueY   float64
+	BlueDirection  int
+	RedX, RedY     float64
+	RedDirection   int
+	BlueCheckpoint int    // Index into Stage.Checkpoints
+	RedCheckpoint  int    // Index into Stage.Checkpoints
+	Collected      []bool // Collectibles picked up before the checkpoint was activated
+}
+
+// unitKindColor returns the current palette colour of a unit kind
+func unitKindColor(kind UnitKind) color.Color {
+	switch kind {
+	case UnitBlue:
+		return BlueUnitColor
+	case UnitRed:
+		return RedUnitColor
+	default:
+		return WhiteColor
+	}
+}
+
+// touchingCheckpoint returns the index of the checkpoint of the unit's colour it overlaps, or -1
+func (u *Unit) touchingCheckpoint(stage *Stage) int {
+	for i, checkpoint := range stage.Checkpoints {
+		if checkpoint.Unit != u.Kind {
+			continue
+		}
+		if u.X+UnitSize > checkpoint.X && u.X < checkpoint.X+CellSize &&
+			u.Y+UnitSize > checkpoint.Y && u.Y < checkpoint.Y+CellSize {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkpointProgress tracks flags touched since the last checkpoint activation
+type checkpointProgress struct {
+	blueTouched, redTouched     bool
+	blueIndex, redIndex         int // Index into Stage.Checkpoints
+	blueDirection, redDirection int // Walking direction when the flag was touched
+}
+
+// updateCheckpoints records touched flags and activates a new respawn pair
+// once both units have touched a flag since the last activation
+func (g *Game) updateCheckpoints() {
+	if len(g.Stage.Checkpoints) == 0 {
+		return
+	}
+	progress := &g.checkpointProgress
+	if i := g.BlueUnit.touchingCheckpoint(g.Stage); i >= 0 && (g.Checkpoint == nil || g.Checkpoint.BlueCheckpoint != i) {
+		progress.blueTouched = true
+		progress.blueIndex = i
+		progress.blueDirection = g.BlueUnit.Direction
+	}
+	if i := g.RedUnit.touchingCheckpoint(g.Stage); i >= 0 && (g.Checkpoint == nil || g.Checkpoint.RedCheckpoint != i) {
+		progress.redTouched = true
+		progress.redIndex = i
+		progress.redDirection = g.RedUnit.Direction
+	}
+	if !progress.blueTouched || !progress.redTouched {
+		return
+	}
+
+	blue := g.Stage.Checkpoints[progress.blueIndex]
+	red := g.Stage.Checkpoints[progress.redIndex]
+	g.Checkpoint = &RespawnPoint{
+		BlueX:          blue.X,
+		BlueY:          blue.Y,
+		BlueDirection:  progress.blueDirection,
+		RedX:           red.X,
+		RedY:           red.Y,
+		RedDirection:   progress.redDirection,
+		BlueCheckpoint: progress.blueIndex,
+		RedCheckpoint:  progress.redIndex,
+		Collected:      append([]bool(nil), g.collected...),
+	}
+	g.checkpointProgress = checkpointProgress{}
+	g.Events.Emit(Event{Type: EventCheckpointActivated, Unit: UnitBlue, X: blue.X, Y: blue.Y})
+	g.Events.Emit(Event{Type: EventCheckpointActivated, Unit: UnitRed, X: red.X, Y: red.Y})
+}
+
+// clearCheckpoint makes the next reset start from the stage's spawn positions
+func (g *Game) clearCheckpoint() {
+	g.Checkpoint = nil
+	g.checkpointProgress = checkpointProgress{}
+}
+
+// isCheckpointActive reports whether a flag belongs to the active respawn pair
+func (g *Game) isCheckpointActive(index int) bool {
+	return g.Checkpoint != nil && (g.Checkpoint.BlueCheckpoint == index || g.Checkpoint.RedCheckpoint == index)
+}
+
+// drawCheckpoints draws all checkpoint flags, highlighting the active pair
+func (g *Game) drawCheckpoints(screen *ebiten.Image) {
+	renderer := g.renderer()
+	for i, checkpoint := range g.Stage.Checkpoints {
+		renderer.DrawCheckpoint(screen, checkpoint, g.isCheckpointActive(i))
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !js || !wasm
+
+package main
+
+import "errors"
+
+// errNoClipboard is returned on desktop, where the game has no clipboard access
+var errNoClipboard = errors.New("no clipboard on desktop (use -code)")
+
+// writeClipboard is unavailable for non-WASM builds
+func writeClipboard(text string) error {
+	return errNoClipboard
+}
+
+// readClipboard is unavailable for non-WASM builds
+func readClipboard(done func(text string, err error)) {
+	done("", errNoClipboard)
+}
@@ -0,0 +1,53 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// clipboard returns navigator.clipboard, or an error if it is unavailable (e.g. insecure context)
+func clipboard() (js.Value, error) {
+	clipboard := js.Global().Get("navigator").Get("clipboard")
+	if !clipboard.Truthy() {
+		return js.Value{}, errors.New("clipboard is not available")
+	}
+	return clipboard, nil
+}
+
+// writeClipboard copies text to the clipboard for WASM builds
+func writeClipboard(text string) error {
+	clipboard, err := clipboard()
+	if err != nil {
+		return err
+	}
+	clipboard.Call("writeText", text)
+	return nil
+}
+
+// readClipboard reads text from the clipboard for WASM builds.
+// The browser may ask the player for permission, so done is called asynchronously.
+func readClipboard(done func(text string, err error)) {
+	clipboard, err := clipboard()
+	if err != nil {
+		done("", err)
+		return
+	}
+	var onRead, onError js.Func
+	release := func() {
+		onRead.Release()
+		onError.Release()
+	}
+	onRead = js.FuncOf(func(this js.Value, args []js.Value) any {
+		release()
+		done(args[0].String(), nil)
+		return nil
+	})
+	onError = js.FuncOf(func(this js.Value, args []js.Value) any {
+		release()
+		done("", errors.New("clipboard access was denied"))
+		return nil
+	})
+	clipboard.Call("readText").Call("then", onRead, onError)
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+)
+
+// Atlas layout (must match sprites.go in the game package)
+const (
+	tileSize = 20
+
+	// Row 0: 16 auto-tile variants indexed by neighbour mask (N=1, E=2, S=4, W=8)
+	autoTileRow = 0
+	// Row 1: themed tiles
+	themedRow    = 1
+	spikeCol     = 0
+	goalCol      = 1
+	speedUpCol   = 2
+	speedDownCol = 3
+	flagCol      = 4
+	oneWayCol    = 5
+	reverseCol   = 6
+	springCol    = 7
+	conveyorCol  = 8
+	starCol      = 9
+	walkerCol    = 10
+	blockCol     = 11
+	laserCol     = 12
+	turretCol    = 13
+	// Row 2: unit animation frames (facing right)
+	unitRow        = 2
+	unitFrameCount = 8 // idle0, idle1, run0-3, jump, fall
+
+	atlasCols = 16
+	atlasRows = 3
+)
+
+var (
+	transparent = color.RGBA{0, 0, 0, 0}
+	light       = color.RGBA{255, 255, 255, 255}
+	base        = color.RGBA{225, 225, 225, 255}
+	shade       = color.RGBA{170, 170, 170, 255}
+	edge        = color.RGBA{120, 120, 120, 255}
+	dark        = color.RGBA{40, 40, 40, 255}
+)
+
+// All tiles are drawn in grayscale so the game can tint them with
+// platform and unit colours (including colour-blind palettes)
+func main() {
+	output := "assets/atlas.png"
+	if len(os.Args) == 2 {
+		output = os.Args[1]
+	}
+
+	atlas := image.NewRGBA(image.Rect(0, 0, atlasCols*tileSize, atlasRows*tileSize))
+
+	for mask := 0; mask < 16; mask++ {
+		drawAutoTile(atlas, mask*tileSize, autoTileRow*tileSize, mask)
+	}
+
+	drawSpikeTile(atlas, spikeCol*tileSize, themedRow*tileSize)
+	drawGoalTile(atlas, goalCol*tileSize, themedRow*tileSize)
+	drawSpeedUpTile(atlas, speedUpCol*tileSize, themedRow*tileSize)
+	drawSpeedDownTile(atlas, speedDownCol*tileSize, themedRow*tileSize)
+	drawFlagTile(atlas, flagCol*tileSize, themedRow*tileSize)
+	drawOneWayTile(atlas, oneWayCol*tileSize, themedRow*tileSize)
+	drawReverseTile(atlas, reverseCol*tileSize, themedRow*tileSize)
+	drawSpringTile(atlas, springCol*tileSize, themedRow*tileSize)
+	drawConveyorTile(atlas, conveyorCol*tileSize, themedRow*tileSize)
+	drawStarTile(atlas, starCol*tileSize, themedRow*tileSize)
+	drawWalkerTile(atlas, walkerCol*tileSize, themedRow*tileSize)
+	drawBlockTile(atlas, blockCol*tileSize, themedRow*tileSize)
+	drawLaserTile(atlas, laserCol*tileSize, themedRow*tileSize)
+	drawTurretTile(atlas, turretCol*tileSize, themedRow*tileSize)
+
+	for frame := 0; frame < unitFrameCount; frame++ {
+		drawUnitFrame(atlas, frame*tileSize, unitRow*tileSize, frame)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("出力ファイル作成エラー: %v", err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, atlas); err != nil {
+		log.Fatalf("PNGエンコードエラー: %v", err)
+	}
+
+	fmt.Printf("テクスチャアトラスを生成しました: %s\n", output)
+}
+
+// drawAutoTile draws a platform tile with borders on the sides that have no neighbour
+func drawAutoTile(img *image.RGBA, ox, oy, mask int) {
+	const border = 2
+	hasN := mask&1 != 0
+	hasE := mask&2 != 0
+	hasS := mask&4 != 0
+	hasW := mask&8 != 0
+
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := base
+			// Subtle brick texture so large areas are not flat
+			if (y%10 == 0) || ((x+(y/10)*10)%20 == 0) {
+				c = shade
+			}
+			switch {
+			case !hasN && y < border:
+				c = light
+			case !hasS && y >= tileSize-border:
+				c = edge
+			case !hasW && x < border:
+				c = light
+			case !hasE && x >= tileSize-border:
+				c = edge
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawSpikeTile draws an upward spike with the same shape as the vector renderer
+func drawSpikeTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			// Triangle from bottom corners to top center
+			halfWidth := float64(y) / 2
+			dx := math.Abs(float64(x) + 0.5 - tileSize/2)
+			c := transparent
+			if dx <= halfWidth {
+				c = light
+				if dx > halfWidth-1.5 {
+					c = shade
+				}
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawGoalTile draws a checkered flag pattern
+func drawGoalTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := light
+			if ((x/5)+(y/5))%2 == 1 {
+				c = shade
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawSpeedUpTile draws double chevrons on a platform surface
+func drawSpeedUpTile(img *image.RGBA, ox, oy int) {
+	drawAutoTile(img, ox, oy, 0)
+	for y := 4; y < 16; y++ {
+		offset := y - 4
+		if offset > 5 {
+			offset = 11 - offset
+		}
+		for _, start := range []int{4, 10} {
+			for w := 0; w < 2; w++ {
+				img.Set(ox+start+offset+w, oy+y, dark)
+			}
+		}
+	}
+}
+
+// drawSpeedDownTile draws horizontal wave bars on a platform surface
+func drawSpeedDownTile(img *image.RGBA, ox, oy int) {
+	drawAutoTile(img, ox, oy, 0)
+	for _, row := range []int{6, 12} {
+		for x := 3; x < tileSize-3; x++ {
+			wave := int(math.Round(math.Sin(float64(x)/2) * 1.5))
+			img.Set(ox+x, oy+row+wave, dark)
+			img.Set(ox+x, oy+row+wave+1, dark)
+		}
+	}
+}
+
+// drawFlagTile draws a checkpoint flag on a pole
+func drawFlagTile(img *image.RGBA, ox, oy int) {
+	// Pole
+	for y := 1; y < tileSize; y++ {
+		for x := 4; x < 6; x++ {
+			img.Set(ox+x, oy+y, edge)
+		}
+	}
+	// Triangular pennant pointing right
+	for y := 1; y < 10; y++ {
+		width := 12 - int(math.Abs(float64(y)-5)*2.5)
+		for x := 6; x < 6+width; x++ {
+			c := light
+			if x == 6+width-1 || y == 1 || y == 9 {
+				c = shade
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawOneWayTile draws a thin plank on two struts; the lower part stays transparent
+// so the tile reads as something units can jump through
+func drawOneWayTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < 6; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := base
+			switch {
+			case y == 0:
+				c = light
+			case y == 5:
+				c = edge
+			case x%5 == 0:
+				c = shade // Board seams
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+	for y := 6; y < 12; y++ {
+		for _, x := range []int{3, 4, 15, 16} {
+			img.Set(ox+x, oy+y, shade)
+		}
+	}
+}
+
+// drawReverseTile draws opposing arrows on a platform surface
+func drawReverseTile(img *image.RGBA, ox, oy int) {
+	drawAutoTile(img, ox, oy, 0)
+	for x := 3; x < 17; x++ {
+		img.Set(ox+x, oy+6, dark)
+		img.Set(ox+x, oy+13, dark)
+	}
+	for i := 0; i < 4; i++ {
+		// Right arrow head on the upper line, left arrow head on the lower line
+		img.Set(ox+16-i, oy+6-i, dark)
+		img.Set(ox+16-i, oy+6+i, dark)
+		img.Set(ox+3+i, oy+13-i, dark)
+		img.Set(ox+3+i, oy+13+i, dark)
+	}
+}
+
+// drawSpringTile draws a coil under a flat pad
+func drawSpringTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := transparent
+			switch {
+			case y < 4:
+				c = light // Pad
+			case y >= tileSize-3:
+				c = edge // Base
+			case (x+y*2)%8 < 2 && x > 3 && x < tileSize-4:
+				c = shade // Coil
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawConveyorTile draws a belt with an arrow pointing right
+func drawConveyorTile(img *image.RGBA, ox, oy int) {
+	drawAutoTile(img, ox, oy, 0)
+	for x := 0; x < tileSize; x += 4 {
+		img.Set(ox+x, oy+2, dark) // Belt treads
+		img.Set(ox+x, oy+tileSize-3, dark)
+	}
+	for x := 5; x < 15; x++ {
+		img.Set(ox+x, oy+10, dark)
+	}
+	for i := 0; i < 5; i++ {
+		img.Set(ox+14-i, oy+10-i, dark)
+		img.Set(ox+14-i, oy+10+i, dark)
+	}
+}
+
+// drawStarTile draws a five-pointed collectible star
+func drawStarTile(img *image.RGBA, ox, oy int) {
+	// Star outline as polygon vertices around the tile centre
+	var xs, ys [10]float64
+	for i := range xs {
+		radius := 8.5
+		if i%2 == 1 {
+			radius = 3.8
+		}
+		angle := -math.Pi/2 + float64(i)*math.Pi/5
+		xs[i] = 10 + radius*math.Cos(angle)
+		ys[i] = 10 + radius*math.Sin(angle)
+	}
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			// Even-odd point-in-polygon test
+			inside := false
+			for i, j := 0, len(xs)-1; i < len(xs); j, i = i, i+1 {
+				if (ys[i] > py) != (ys[j] > py) && px < (xs[j]-xs[i])*(py-ys[i])/(ys[j]-ys[i])+xs[i] {
+					inside = !inside
+				}
+			}
+			c := transparent
+			if inside {
+				c = light
+				if py > 12 {
+					c = base // Slight shading on the lower half
+				}
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawWalkerTile draws a spiky-topped crawler facing right
+func drawWalkerTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := transparent
+			dx, dy := float64(x)-9.5, float64(y)-12.5
+			switch {
+			case dx*dx/90+dy*dy/42 <= 1:
+				c = base // Dome-shaped body
+				if y > 16 {
+					c = shade
+				}
+			case y >= 2 && y < 6 && x > 3 && x < 16 && (x+y)%4 == 0:
+				c = edge // Spines
+			case y >= tileSize-2 && (x == 4 || x == 5 || x == 14 || x == 15):
+				c = edge // Feet
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+	// Eye looking in the walking direction
+	for y := 9; y < 13; y++ {
+		for x := 12; x < 16; x++ {
+			c := light
+			if x >= 14 && y >= 10 && y < 12 {
+				c = dark
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawBlockTile draws a heavy block with a cross brace and spiked underside
+func drawBlockTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := base
+			switch {
+			case y >= 15:
+				// Teeth along the bottom edge
+				c = transparent
+				if x%5 >= y-15 && x%5 <= 4-(y-15) {
+					c = edge
+				}
+			case x < 2 || y < 2 || x >= tileSize-2 || y >= 13:
+				c = edge
+			case x-y == 0 || x+y == 14 || x-y == 5 || x+y == 19:
+				c = shade // Cross brace
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawLaserTile draws a ceiling-mounted emitter with its lens facing down
+func drawLaserTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := transparent
+			switch {
+			case y < 4:
+				c = edge // Mount
+			case y < 14 && x >= 3 && x < 17:
+				c = base // Housing
+				if x == 3 || x == 16 || y == 13 {
+					c = shade
+				}
+			case y < 18 && x >= 7 && x < 13:
+				c = light // Lens
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawTurretTile draws a cannon on a base with its barrel facing right
+func drawTurretTile(img *image.RGBA, ox, oy int) {
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			c := transparent
+			dx, dy := float64(x)-8.5, float64(y)-10.5
+			switch {
+			case y >= 16:
+				c = edge // Base
+			case x >= 10 && y >= 8 && y < 13:
+				c = shade // Barrel
+				if x == tileSize-1 {
+					c = dark // Muzzle
+				}
+			case dx*dx+dy*dy <= 36:
+				c = base // Rotating body
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+}
+
+// drawUnitFrame draws a round character facing right.
+// Frames: 0-1 idle, 2-5 run, 6 jump, 7 fall
+func drawUnitFrame(img *image.RGBA, ox, oy, frame int) {
+	bodyY := 9.0
+	eyeY := 7.0
+	leftFootX, rightFootX := 6, 13
+	footY := 18
+
+	switch {
+	case frame <= 1:
+		// Idle: gentle breathing bob
+		bodyY += float64(frame) * 0.5
+	case frame <= 5:
+		// Run: alternate feet
+		step := []int{-2, 0, 2, 0}[frame-2]
+		leftFootX += step
+		rightFootX -= step
+		if step == 0 {
+			bodyY -= 0.5
+		}
+	case frame == 6:
+		// Jump: tuck feet, look up
+		footY = 16
+		eyeY = 6
+		bodyY -= 1
+	case frame == 7:
+		// Fall: spread feet, look down
+		leftFootX -= 2
+		rightFootX += 2
+		eyeY = 8
+	}
+
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			dx := float64(x) + 0.5 - 10
+			dy := float64(y) + 0.5 - bodyY
+			d := math.Sqrt(dx*dx + dy*dy)
+			c := transparent
+			if d <= 8 {
+				c = light
+				if d > 6.8 {
+					c = shade
+				}
+			}
+			img.Set(ox+x, oy+y, c)
+		}
+	}
+
+	// Feet
+	for _, fx := range []int{leftFootX, rightFootX} {
+		for x := fx - 1; x <= fx+1; x++ {
+			for y := footY; y < footY+2 && y < tileSize; y++ {
+				img.Set(ox+x, oy+y, edge)
+			}
+		}
+	}
+
+	// Eye facing right
+	for y := int(eyeY); y < int(eyeY)+3; y++ {
+		for x := 13; x < 15; x++ {
+			img.Set(ox+x, oy+y, dark)
+		}
+	}
+}
@@ -1,24 +1,43 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"go/format"
+	"image"
+	"image/color"
+	"image/png"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
 	"text/template"
+
+	"github.com/pankona/egj2025/internal/stagefile"
 )
 
 // StageData represents the parsed stage data from ASCII art
 type StageData struct {
 	StageNumber        int
+	Info               *stagefile.Metadata // Front matter of the stage file (nil = none)
 	Platforms          []PlatformData
 	GoalPlatforms      []PlatformData
 	SpeedUpPlatforms   []PlatformData
 	SpeedDownPlatforms []PlatformData
+	OneWayPlatforms    []PlatformData
+	ReversePlatforms   []PlatformData
+	SpringPlatforms    []PlatformData
+	ConveyorPlatforms  []ConveyorData
+	UnitGoalPlatforms  []UnitPlatformData
+	UnitPlatforms      []UnitPlatformData
+	UnitSpikes         []UnitSpikeData
 	Spikes             []SpikeData
+	Checkpoints        []CheckpointData
+	Collectibles       []SpikeData // Stars share the single-cell layout of spikes
+	Hazards            []HazardData
 	BlueStartX         int
 	BlueStartY         int
 	RedStartX          int
@@ -39,170 +58,229 @@ type PlatformData struct {
 
 // SpikeData represents a spike in grid coordinates
 type SpikeData struct {
-	X int
-	Y int
+	X         int
+	Y         int
+	Direction string // SpikeDirection constant name for non-upward spikes ("" = floor spike)
+}
+
+// ConveyorData represents a conveyor in grid coordinates
+type ConveyorData struct {
+	PlatformData
+	Direction int // 1 pushes right, -1 pushes left
+}
+
+// UnitPlatformData represents a platform tied to one unit in grid coordinates
+type UnitPlatformData struct {
+	PlatformData
+	Unit string // UnitKind constant name in the game package
+}
+
+// UnitSpikeData represents a spike harmful to one unit in grid coordinates
+type UnitSpikeData struct {
+	SpikeData
+	Unit string // UnitKind constant name in the game package
+}
+
+// CheckpointData represents a checkpoint flag in grid coordinates
+type CheckpointData struct {
+	X    int
+	Y    int
+	Unit string // UnitKind constant name in the game package
 }
 
-// parseASCIIArt parses ASCII art file and returns stage data
-func parseASCIIArt(filename string) (*StageData, error) {
-	file, err := os.Open(filename)
+// HazardData represents an enemy or hazard in grid coordinates
+type HazardData struct {
+	X         int
+	Y         int
+	Name      string // Description used in the generated comment
+	Func      string // Constructor in the game package
+	Direction int    // Firing direction for turrets (0 = constructor takes none)
+}
+
+// readStage reads a stage from an ASCII art (.txt), JSON (.json) or Tiled (.tmx) file
+func readStage(filename string, opts stagefile.Options) (*stagefile.Layout, error) {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("ファイルを開けませんでした: %v", err)
+		return nil, &stagefile.Error{File: filename, Kind: stagefile.ErrRead, Err: err}
 	}
-	defer file.Close()
 
+	stageNum := 0 // Only JSON files record their stage number
 	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		layout, err := stagefile.DecodeJSON(data)
+		if err != nil {
+			return nil, inFile(err, filename)
+		}
+		stageNum = layout.Metadata.Stage
+		lines = stagefile.FormatFile(layout)
+	case ".tmx":
+		if lines, err = stagefile.DecodeTMX(data); err != nil {
+			return nil, inFile(err, filename)
+		}
+	default:
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("ファイル読み込みエラー: %v", err)
+	// Parse the ASCII art even for JSON, so elements are validated and ordered
+	// the same way whatever the input format. Error positions refer to the
+	// ASCII art (for JSON and TMX input, the stage as stagegen -format txt writes it).
+	opts.File = filename
+	layout, err := stagefile.ParseWith(strings.NewReader(strings.Join(lines, "\n")), opts)
+	if err != nil {
+		return nil, err
 	}
+	layout.Metadata.Stage = stageNum
 
-	if len(lines) == 0 {
-		return nil, fmt.Errorf("空のファイルです")
-	}
+	return layout, nil
+}
 
-	// Extract stage number from filename
-	baseName := filepath.Base(filename)
-	// Extract number from stageN.txt format
-	stageNum := 1
-	if strings.HasPrefix(baseName, "stage") && strings.HasSuffix(baseName, ".txt") {
-		numStr := strings.TrimPrefix(strings.TrimSuffix(baseName, ".txt"), "stage")
-		if num, err := strconv.Atoi(numStr); err == nil {
-			stageNum = num
-		} else if numStr != "" {
-			log.Printf("警告: ファイル名 '%s' からステージ番号を抽出できませんでした。デフォルトの1を使用します。", baseName)
-		}
+// stageNumber returns the stage number of a stage file: the one in its JSON
+// metadata, or else the one in its stageNN file name
+func stageNumber(filename string, metadata stagefile.Metadata) (int, error) {
+	if metadata.Stage != 0 {
+		return metadata.Stage, nil
 	}
-
-	stageData := &StageData{StageNumber: stageNum}
-
-	// Create a 2D grid to track processed cells
-	height := len(lines)
-	width := 0
-	for _, line := range lines {
-		if len(line) > width {
-			width = len(line)
-		}
+	number, err := stagefile.StageNumber(strings.TrimSuffix(filename, filepath.Ext(filename)) + ".txt")
+	var e *stagefile.Error
+	if errors.As(err, &e) {
+		e.File, e.Text = filename, filepath.Base(filename)
 	}
+	return number, err
+}
 
-	processed := make([][]bool, height)
-	for i := range processed {
-		processed[i] = make([]bool, width)
+// inFile sets the file name of the stage file problems in err
+func inFile(err error, filename string) error {
+	var list stagefile.ErrorList
+	if errors.As(err, &list) {
+		list.SetFile(filename)
 	}
+	return err
+}
 
-	// Parse the grid
-	for y, line := range lines {
-		for x, char := range line {
-			if processed[y][x] {
-				continue
-			}
-
-			switch char {
-			case 'O':
-				// Find rectangular platform starting from this position
-				platform := findRectangularPlatform(lines, processed, x, y, 'O')
-				if platform != nil {
-					stageData.Platforms = append(stageData.Platforms, *platform)
-				}
-			case 'G':
-				// Find rectangular goal platform starting from this position
-				platform := findRectangularPlatform(lines, processed, x, y, 'G')
-				if platform != nil {
-					stageData.GoalPlatforms = append(stageData.GoalPlatforms, *platform)
-				}
-			case 'u':
-				// Find rectangular speed-up platform starting from this position
-				platform := findRectangularPlatform(lines, processed, x, y, 'u')
-				if platform != nil {
-					stageData.SpeedUpPlatforms = append(stageData.SpeedUpPlatforms, *platform)
-				}
-			case 'd':
-				// Find rectangular speed-down platform starting from this position
-				platform := findRectangularPlatform(lines, processed, x, y, 'd')
-				if platform != nil {
-					stageData.SpeedDownPlatforms = append(stageData.SpeedDownPlatforms, *platform)
-				}
-			case 'L':
-				stageData.BlueStartX = x
-				stageData.BlueStartY = y
-				stageData.BlueStartPixelX = x * 20
-				stageData.BlueStartPixelY = y * 20
-				processed[y][x] = true
-			case 'R':
-				stageData.RedStartX = x
-				stageData.RedStartY = y
-				stageData.RedStartPixelX = x * 20
-				stageData.RedStartPixelY = y * 20
-				processed[y][x] = true
-			case '^':
-				stageData.Spikes = append(stageData.Spikes, SpikeData{X: x, Y: y})
-				processed[y][x] = true
-			case '.':
-				processed[y][x] = true
-			default:
-				return nil, fmt.Errorf("不明な文字 '%c' が座標 (%d, %d) で見つかりました", char, x, y)
-			}
-		}
+// addProblems appends the stage file problems in err to problems,
+// reporting whether err was made of them
+func addProblems(problems *stagefile.ErrorList, err error) bool {
+	var list stagefile.ErrorList
+	var single *stagefile.Error
+	switch {
+	case errors.As(err, &list):
+		*problems = append(*problems, list...)
+	case errors.As(err, &single):
+		*problems = append(*problems, single)
+	default:
+		return false
 	}
-
-	return stageData, nil
+	return true
 }
 
-// findRectangularPlatform finds a rectangular platform starting from (startX, startY)
-func findRectangularPlatform(lines []string, processed [][]bool, startX, startY int, targetChar rune) *PlatformData {
-	if startY >= len(lines) || startX >= len(lines[startY]) {
-		return nil
+// fatal prints stage file problems like a compiler, one "file:line:column: message"
+// line each, or else err after a heading, and exits
+func fatal(heading string, err error) {
+	var problems stagefile.ErrorList
+	if addProblems(&problems, err) {
+		fmt.Fprintln(os.Stderr, problems.Localize(stagefile.ErrorLanguage))
+		os.Exit(1)
 	}
+	log.Fatalf("%s: %v", heading, err)
+}
 
-	if rune(lines[startY][startX]) != targetChar {
-		return nil
+// localize picks the Japanese or English version of a message, following -lang
+func localize(ja, en string) string {
+	if stagefile.ErrorLanguage == stagefile.English {
+		return en
 	}
+	return ja
+}
 
-	// Find the width by scanning right
-	width := 0
-	for x := startX; x < len(lines[startY]) && rune(lines[startY][x]) == targetChar; x++ {
-		width++
+// newStageData groups a parsed layout into the template's sections
+func newStageData(layout *stagefile.Layout) *StageData {
+	stageData := &StageData{
+		StageNumber:     layout.Metadata.Stage,
+		BlueStartX:      layout.BlueStart.X,
+		BlueStartY:      layout.BlueStart.Y,
+		RedStartX:       layout.RedStart.X,
+		RedStartY:       layout.RedStart.Y,
+		BlueStartPixelX: layout.BlueStart.X * 20,
+		BlueStartPixelY: layout.BlueStart.Y * 20,
+		RedStartPixelX:  layout.RedStart.X * 20,
+		RedStartPixelY:  layout.RedStart.Y * 20,
+	}
+	if layout.Metadata.HasFrontMatter() {
+		stageData.Info = &layout.Metadata
 	}
 
-	// Find the height by scanning down, ensuring all rows have the same width
-	height := 0
-	for y := startY; y < len(lines); y++ {
-		// Check if this row has the required width of target characters
-		if len(lines[y]) < startX+width {
-			break
-		}
-		hasFullWidth := true
-		for x := startX; x < startX+width; x++ {
-			if rune(lines[y][x]) != targetChar {
-				hasFullWidth = false
-				break
+	for _, p := range layout.Platforms {
+		platform := PlatformData{X: p.X, Y: p.Y, Width: p.Width, Height: p.Height}
+		if p.Unit != stagefile.UnitNone {
+			unitPlatform := UnitPlatformData{PlatformData: platform, Unit: unitNames[p.Unit]}
+			if p.Kind == stagefile.PlatformGoal {
+				stageData.UnitGoalPlatforms = append(stageData.UnitGoalPlatforms, unitPlatform)
+			} else {
+				stageData.UnitPlatforms = append(stageData.UnitPlatforms, unitPlatform)
 			}
+			continue
 		}
-		if !hasFullWidth {
-			break
+		switch p.Kind {
+		case stagefile.PlatformSolid:
+			stageData.Platforms = append(stageData.Platforms, platform)
+		case stagefile.PlatformGoal:
+			stageData.GoalPlatforms = append(stageData.GoalPlatforms, platform)
+		case stagefile.PlatformSpeedUp:
+			stageData.SpeedUpPlatforms = append(stageData.SpeedUpPlatforms, platform)
+		case stagefile.PlatformSpeedDown:
+			stageData.SpeedDownPlatforms = append(stageData.SpeedDownPlatforms, platform)
+		case stagefile.PlatformOneWay:
+			stageData.OneWayPlatforms = append(stageData.OneWayPlatforms, platform)
+		case stagefile.PlatformReverse:
+			stageData.ReversePlatforms = append(stageData.ReversePlatforms, platform)
+		case stagefile.PlatformSpring:
+			stageData.SpringPlatforms = append(stageData.SpringPlatforms, platform)
+		case stagefile.PlatformConveyor:
+			stageData.ConveyorPlatforms = append(stageData.ConveyorPlatforms, ConveyorData{PlatformData: platform, Direction: p.Direction})
 		}
-		height++
 	}
 
-	// Mark all cells in this rectangle as processed
-	for y := startY; y < startY+height; y++ {
-		for x := startX; x < startX+width; x++ {
-			if y < len(processed) && x < len(processed[y]) {
-				processed[y][x] = true
-			}
+	for _, s := range layout.Spikes {
+		if s.Unit != stagefile.UnitNone {
+			stageData.UnitSpikes = append(stageData.UnitSpikes, UnitSpikeData{SpikeData: SpikeData{X: s.X, Y: s.Y}, Unit: unitNames[s.Unit]})
+			continue
 		}
+		stageData.Spikes = append(stageData.Spikes, SpikeData{X: s.X, Y: s.Y, Direction: spikeDirectionNames[s.Direction]})
 	}
-
-	return &PlatformData{
-		X:      startX,
-		Y:      startY,
-		Width:  width,
-		Height: height,
+	for _, c := range layout.Checkpoints {
+		stageData.Checkpoints = append(stageData.Checkpoints, CheckpointData{X: c.X, Y: c.Y, Unit: unitNames[c.Unit]})
+	}
+	for _, c := range layout.Collectibles {
+		stageData.Collectibles = append(stageData.Collectibles, SpikeData{X: c.X, Y: c.Y})
+	}
+	for _, h := range layout.Hazards {
+		hazard := hazardTemplates[h.Kind]
+		hazard.X, hazard.Y, hazard.Direction = h.X, h.Y, h.Direction
+		stageData.Hazards = append(stageData.Hazards, hazard)
 	}
+	return stageData
+}
+
+// unitNames maps units to UnitKind constant names in the game package
+var unitNames = map[stagefile.Unit]string{
+	stagefile.UnitBlue: "UnitBlue",
+	stagefile.UnitRed:  "UnitRed",
+}
+
+// spikeDirectionNames maps non-upward spikes to SpikeDirection constant names in the game package
+var spikeDirectionNames = map[stagefile.SpikeDirection]string{
+	stagefile.SpikeDown:  "SpikeDown",
+	stagefile.SpikeLeft:  "SpikeLeft",
+	stagefile.SpikeRight: "SpikeRight",
+}
+
+// hazardTemplates maps hazard kinds to their description and constructor in the game package
+var hazardTemplates = map[stagefile.HazardKind]HazardData{
+	stagefile.HazardWalker:       {Name: "Walker", Func: "CreateGridWalker"},
+	stagefile.HazardFallingBlock: {Name: "Falling block", Func: "CreateGridFallingBlock"},
+	stagefile.HazardLaser:        {Name: "Laser", Func: "CreateGridLaser"},
+	stagefile.HazardTurret:       {Name: "Turret", Func: "CreateGridTurret"},
 }
 
 // generateStageFile generates a Go stage file from stage data
@@ -226,14 +304,64 @@ func LoadStage{{.StageNumber}}() *Stage {
 {{end}}{{range .SpeedDownPlatforms}}
 			// Speed-down platform at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
 			CreateGridSpeedDownPlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}),
+{{end}}{{range .OneWayPlatforms}}
+			// One-way platform at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
+			CreateGridOneWayPlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}),
+{{end}}{{range .ReversePlatforms}}
+			// Reverse tile at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
+			CreateGridReversePlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}),
+{{end}}{{range .SpringPlatforms}}
+			// Spring pad at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
+			CreateGridSpringPlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}),
+{{end}}{{range .ConveyorPlatforms}}
+			// Conveyor at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
+			CreateGridConveyorPlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}, {{.Direction}}),
+{{end}}{{range .UnitGoalPlatforms}}
+			// {{.Unit}} goal at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
+			CreateGridUnitGoalPlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}, {{.Unit}}),
+{{end}}{{range .UnitPlatforms}}
+			// {{.Unit}}-only platform at ({{.X}}, {{.Y}}) size {{.Width}}x{{.Height}}
+			CreateGridUnitPlatform({{.X}}, {{.Y}}, {{.Width}}, {{.Height}}, {{.Unit}}),
 {{end}}
 		},
 		Spikes: []Spike{
-{{range .Spikes}}
+{{range .Spikes}}{{if .Direction}}
+			// {{.Direction}} spike at ({{.X}}, {{.Y}})
+			CreateGridSpikeFacing({{.X}}, {{.Y}}, {{.Direction}}),
+{{else}}
 			// Spike at ({{.X}}, {{.Y}})
 			CreateGridSpike({{.X}}, {{.Y}}),
+{{end}}{{end}}{{range .UnitSpikes}}
+			// {{.Unit}}-only spike at ({{.X}}, {{.Y}})
+			CreateGridUnitSpike({{.X}}, {{.Y}}, {{.Unit}}),
 {{end}}
-		},
+		},{{if .Checkpoints}}
+		Checkpoints: []Checkpoint{
+{{range .Checkpoints}}
+			// Checkpoint at ({{.X}}, {{.Y}})
+			CreateGridCheckpoint({{.X}}, {{.Y}}, {{.Unit}}),
+{{end}}
+		},{{end}}{{if .Collectibles}}
+		Collectibles: []Collectible{
+{{range .Collectibles}}
+			// Star at ({{.X}}, {{.Y}})
+			CreateGridCollectible({{.X}}, {{.Y}}),
+{{end}}
+		},{{end}}{{if .Hazards}}
+		Hazards: []Hazard{
+{{range .Hazards}}
+			// {{.Name}} at ({{.X}}, {{.Y}})
+			{{.Func}}({{.X}}, {{.Y}}{{if .Direction}}, {{.Direction}}{{end}}),
+{{end}}
+		},{{end}}{{with .Info}}
+		Info: StageInfo{
+{{if .Title}}			Title: {{printf "%q" .Title}},
+{{end}}{{if .Author}}			Author: {{printf "%q" .Author}},
+{{end}}{{if .Difficulty}}			Difficulty: {{.Difficulty}},
+{{end}}{{if .ParTime}}			ParTime: {{.ParTime}},
+{{end}}{{if .BGM}}			BGM: {{printf "%q" .BGM}},
+{{end}}{{if .Tutorial}}			Tutorial: {{printf "%q" .Tutorial}},
+{{end}}		},{{end}}
 	}
 }
 
@@ -246,52 +374,566 @@ func GetStage{{.StageNumber}}StartPositions() (blueX, blueY, redX, redY float64)
 
 	t, err := template.New("stage").Parse(tmpl)
 	if err != nil {
-		return fmt.Errorf("テンプレート解析エラー: %v", err)
+		return fmt.Errorf("%s: %w", localize("テンプレート解析エラー", "template parse error"), err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, stageData); err != nil {
+		return fmt.Errorf("%s: %w", localize("テンプレート実行エラー", "template execution error"), err)
+	}
+	return writeGoFile(outputPath, buf.Bytes())
+}
+
+// writeGoFile writes gofmt-formatted Go source
+func writeGoFile(outputPath string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", localize("生成したコードの整形エラー", "cannot format the generated code"), err)
+	}
+	if err := os.WriteFile(outputPath, formatted, 0644); err != nil {
+		return fmt.Errorf("%s: %w", localize("出力ファイル作成エラー", "cannot write the output file"), err)
+	}
+	return nil
+}
+
+// registryFileName is the file listing the generated stages for the game's StageLoader
+const registryFileName = "stage_registry.go"
+
+// RegistryStage is one stage of the generated registry
+type RegistryStage struct {
+	Number int    // Stage number
+	Source string // Stage file the stage was generated from
+}
+
+// generateRegistryFile writes the registry of the stages generated in a batch,
+// which StageLoader uses instead of hand-written switches
+func generateRegistryFile(stages []RegistryStage, outputPath string) error {
+	tmpl := `// Code generated by stagegen; DO NOT EDIT.
+
+package main
+
+// builtinStages lists the stages compiled into the game by stage number
+var builtinStages = map[int]builtinStage{
+{{range .Stages}}	{{.Number}}: {Load: LoadStage{{.Number}}, StartPositions: GetStage{{.Number}}StartPositions}, // {{.Source}}
+{{end}}}
+
+// lastBuiltinStage is the highest built-in stage number
+const lastBuiltinStage = {{.Last}}
+`
+
+	t, err := template.New("registry").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("%s: %w", localize("テンプレート解析エラー", "template parse error"), err)
+	}
+	var buf bytes.Buffer
+	data := struct {
+		Stages []RegistryStage
+		Last   int
+	}{stages, stages[len(stages)-1].Number}
+	if err := t.Execute(&buf, data); err != nil {
+		return fmt.Errorf("%s: %w", localize("テンプレート実行エラー", "template execution error"), err)
+	}
+	return writeGoFile(outputPath, buf.Bytes())
+}
+
+// tilesetImageName is the tileset image written next to exported TMX maps
+const tilesetImageName = "stage_tiles.png"
+
+// glyphColors are the tileset colours of each glyph, from the game's default palette
+var glyphColors = func() map[rune]color.RGBA {
+	c := stagefile.DefaultColors
+	return map[rune]color.RGBA{
+		'O': c.Platform, 'G': c.Goal, 'u': c.SpeedUp, 'd': c.SpeedDown, '-': c.OneWay, 'X': c.Reverse, 's': c.Spring,
+		'}': c.Conveyor, '{': c.Conveyor, '^': c.Spike, 'v': c.Spike, '<': c.Spike, '>': c.Spike,
+		'1': c.Goal, '2': c.Goal, '3': c.BlueUnit, '4': c.RedUnit, '5': c.BlueUnit, '6': c.RedUnit, '*': c.Star,
+		'w': c.Hazard, '#': c.Hazard, '!': c.Hazard, 'T': c.Hazard, 'l': c.BlueUnit, 'r': c.RedUnit, 'L': c.BlueUnit, 'R': c.RedUnit,
+	}
+}()
+
+// writeTilesetImage draws one tile per glyph (in stagefile.Glyphs order) for TMX maps.
+// Spikes are drawn as triangles pointing their way and start positions as outlines
+// so tiles of the same colour stay distinguishable in Tiled.
+func writeTilesetImage(path string) error {
+	const size = stagefile.CellSize
+	img := image.NewRGBA(image.Rect(0, 0, len(stagefile.Glyphs)*size, size))
+	for i, glyph := range stagefile.Glyphs {
+		c := glyphColors[glyph]
+		for y := range size {
+			for x := range size {
+				var inside bool
+				switch glyph {
+				case '^', '5', '6':
+					inside = 2*x >= size-1-y && 2*x <= size-1+y
+				case 'v':
+					inside = 2*x >= y && 2*x <= 2*size-2-y
+				case '<':
+					inside = 2*y >= size-1-x && 2*y <= size-1+x
+				case '>':
+					inside = 2*y >= x && 2*y <= 2*size-2-x
+				case 'L', 'R', 'l', 'r', '*', 'w', 'T', '!':
+					inside = x < 3 || y < 3 || x >= size-3 || y >= size-3 || (x >= size/2-2 && x < size/2+2)
+				default:
+					inside = true
+				}
+				if inside {
+					img.SetRGBA(i*size+x, y, c)
+				}
+			}
+		}
+	}
+
+	return writePNG(path, img)
+}
+
+// Preview images
+const (
+	sheetScale   = 0.5 // Size of contact sheet previews relative to the game screen
+	sheetColumns = 4
+)
+
+// writePNG encodes an image as a PNG file
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeStage writes a stage in the given format ("go", "txt", "json", "tmx" or "png")
+func writeStage(layout *stagefile.Layout, format, outputPath string) error {
+	var data []byte
+	var err error
+	switch format {
+	case "go":
+		return generateStageFile(newStageData(layout), outputPath)
+	case "txt":
+		data = []byte(strings.Join(stagefile.FormatFile(layout), "\n") + "\n")
+	case "json":
+		data, err = stagefile.EncodeJSON(layout)
+	case "tmx":
+		if data, err = stagefile.EncodeTMX(stagefile.FormatFile(layout), tilesetImageName); err == nil {
+			err = writeTilesetImage(filepath.Join(filepath.Dir(outputPath), tilesetImageName))
+		}
+	case "png":
+		return writePNG(outputPath, stagefile.Render(layout, stagefile.DefaultColors, 1))
+	default:
+		return fmt.Errorf(localize("不明な出力形式です: %s (go, txt, json, tmx, png のいずれか)", "unknown output format %s (want one of go, txt, json, tmx, png)"), format)
+	}
+	if err != nil {
+		return err
 	}
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// stageFileExts are the stage file formats stagegen reads, in order of preference
+var stageFileExts = []string{".txt", ".json", ".tmx"}
+
+// dirStageFiles returns the stage files in a directory. A stage saved in
+// several formats (stage01.txt and an exported stage01.json) is read once,
+// from the first format in stageFileExts.
+func dirStageFiles(dir string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, ext := range stageFileExts {
+		matches, err := filepath.Glob(filepath.Join(dir, "stage*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if name := strings.TrimSuffix(match, ext); !seen[name] {
+				seen[name] = true
+				files = append(files, match)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf(localize("%s にステージファイル (stage*.txt, stage*.json, stage*.tmx) がありません", "%s has no stage files (stage*.txt, stage*.json, stage*.tmx)"), dir)
+	}
+	return files, nil
+}
+
+// RectangleStats describes how the platforms of a stage are split into rectangles
+type RectangleStats struct {
+	Rectangles    int // Number of platforms
+	Overlapping   int // Platforms that overlap another platform
+	DoubleCovered int // Cells covered by more than one platform (collided with more than once)
+	WallSeams     int // Places on a wall where one platform ends and the next begins
+}
+
+func (s *RectangleStats) add(other RectangleStats) {
+	s.Rectangles += other.Rectangles
+	s.Overlapping += other.Overlapping
+	s.DoubleCovered += other.DoubleCovered
+	s.WallSeams += other.WallSeams
+}
+
+// rectangleStats measures the platforms of a layout. Wall seams are where
+// units snag: updatePhysics resolves each platform on its own, so a unit
+// pushing against a wall made of stacked platforms can land on the top edge
+// of a lower one. Each seam is a pair of vertically neighbouring cells of the
+// same glyph, exposed on the same side, that no single platform covers.
+// (Seams in floors are harmless, since a unit landing on the next platform is
+// not pushed back by it, and seams between different glyphs are part of the
+// stage, whatever the decomposition.)
+func rectangleStats(layout *stagefile.Layout) RectangleStats {
+	art := stagefile.Format(layout)
+	stats := RectangleStats{Rectangles: len(layout.Platforms)}
+	covered := make([][][]int, layout.Height) // Platforms covering each cell
+	for y := range covered {
+		covered[y] = make([][]int, layout.Width)
+	}
+	overlapping := make([]bool, len(layout.Platforms))
+	for i, p := range layout.Platforms {
+		for y := p.Y; y < p.Y+p.Height; y++ {
+			for x := p.X; x < p.X+p.Width; x++ {
+				for _, other := range covered[y][x] {
+					overlapping[i], overlapping[other] = true, true
+				}
+				covered[y][x] = append(covered[y][x], i)
+			}
+		}
+	}
+	for _, o := range overlapping {
+		if o {
+			stats.Overlapping++
+		}
+	}
+	for _, row := range covered {
+		for _, platforms := range row {
+			if len(platforms) > 1 {
+				stats.DoubleCovered++
+			}
+		}
+	}
+
+	// Cells outside the grid count as solid: units never reach the outer faces
+	solid := func(x, y int) bool {
+		return x < 0 || x >= layout.Width || y < 0 || y >= layout.Height || len(covered[y][x]) > 0
+	}
+	// wallSeam reports whether cell (x, y) and the one below it, both exposed
+	// towards side, are the same glyph split between platforms
+	wallSeam := func(x, y, side int) bool {
+		if y+1 >= layout.Height || len(covered[y][x]) == 0 || len(covered[y+1][x]) == 0 ||
+			solid(x+side, y) || solid(x+side, y+1) || art[y][x] != art[y+1][x] {
+			return false
+		}
+		return !slices.ContainsFunc(covered[y][x], func(i int) bool {
+			return slices.Contains(covered[y+1][x], i)
+		})
+	}
+	for y := range layout.Height {
+		for x := range layout.Width {
+			for _, side := range []int{-1, 1} {
+				if wallSeam(x, y, side) {
+					stats.WallSeams++
+				}
+			}
+		}
+	}
+	return stats
+}
+
+// compareRectangles measures a stage split greedily and into the fewest rectangles
+func compareRectangles(layout *stagefile.Layout) (greedy, minimal RectangleStats, err error) {
+	art := strings.Join(stagefile.Format(layout), "\n")
+	for _, c := range []struct {
+		stats         *RectangleStats
+		decomposition stagefile.Decomposition
+	}{{&greedy, stagefile.DecomposeGreedy}, {&minimal, stagefile.DecomposeMinimal}} {
+		parsed, err := stagefile.ParseWith(strings.NewReader(art), stagefile.Options{Decomposition: c.decomposition})
+		if err != nil {
+			return greedy, minimal, err
+		}
+		*c.stats = rectangleStats(parsed)
+	}
+	return greedy, minimal, nil
+}
+
+// formatRectangleComparison formats greedy and minimal stats side by side
+func formatRectangleComparison(greedy, minimal RectangleStats) string {
+	return fmt.Sprintf(localize("矩形 %d → %d (うち重なる矩形 %d → %d)  二重に覆うマス %d → %d  壁の継ぎ目 %d → %d", "rectangles %d → %d (overlapping %d → %d)  cells covered twice %d → %d  wall seams %d → %d"),
+		greedy.Rectangles, minimal.Rectangles, greedy.Overlapping, minimal.Overlapping, greedy.DoubleCovered, minimal.DoubleCovered, greedy.WallSeams, minimal.WallSeams)
+}
+
+// batchInputs expands the arguments into stage files: a directory stands for
+// the stage files in it, and a pattern such as "stages/*.json" for the
+// files it matches. ok reports whether the arguments ask for batch mode.
+func batchInputs(args []string) (files []string, ok bool, err error) {
+	for _, arg := range args {
+		if info, statErr := os.Stat(arg); statErr == nil && info.IsDir() {
+			matches, err := dirStageFiles(arg)
+			if err != nil {
+				return nil, true, err
+			}
+			files = append(files, matches...)
+			ok = true
+			continue
+		}
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, true, fmt.Errorf(localize("パターンが不正です: %s: %w", "invalid pattern %s: %w"), arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, true, fmt.Errorf(localize("%s に一致するファイルがありません", "no files match %s"), arg)
+			}
+			files = append(files, matches...)
+			ok = true
+			continue
+		}
+		files = append(files, arg)
+	}
+	return files, ok || len(files) > 1, nil
+}
+
+// batchStage is a stage read in batch mode
+type batchStage struct {
+	file   string
+	layout *stagefile.Layout
+}
 
-	file, err := os.Create(outputPath)
+// readBatch reads every stage file, in stage number order. Stages are all
+// read before anything is written, so an error leaves no half-generated set of
+// files. Problems in the stage files are collected from every file into one
+// stagefile.ErrorList.
+func readBatch(files []string, opts stagefile.Options) ([]batchStage, error) {
+	var inputs []batchStage
+	var problems stagefile.ErrorList
+	sources := make(map[int]string) // Stage number -> file, to catch duplicates
+	for _, file := range files {
+		layout, err := readStage(file, opts)
+		if err == nil {
+			layout.Metadata.Stage, err = stageNumber(file, layout.Metadata)
+		}
+		if addProblems(&problems, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		stage := layout.Metadata.Stage
+		if other, ok := sources[stage]; ok {
+			return nil, fmt.Errorf(localize("ステージ番号 %d が %s と %s で重複しています", "stage number %d is used by both %s and %s"), stage, other, file)
+		}
+		sources[stage] = file
+		inputs = append(inputs, batchStage{file, layout})
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(inputs, func(a, b batchStage) int { return a.layout.Metadata.Stage - b.layout.Metadata.Stage })
+	return inputs, nil
+}
+
+// writeContactSheet renders every stage into one image, labelled with stage numbers
+func writeContactSheet(files []string, opts stagefile.Options, outputPath string) error {
+	inputs, err := readBatch(files, opts)
+	if err != nil {
+		return err
+	}
+	previews := make([]image.Image, len(inputs))
+	numbers := make([]int, len(inputs))
+	for i, input := range inputs {
+		previews[i] = stagefile.Render(input.layout, stagefile.DefaultColors, sheetScale)
+		numbers[i] = input.layout.Metadata.Stage
+	}
+	if err := writePNG(outputPath, stagefile.ContactSheet(previews, numbers, sheetColumns)); err != nil {
+		return err
+	}
+	fmt.Printf(localize("コンタクトシートを生成しました: %s (%d ステージ)\n", "wrote the contact sheet %s (%d stages)\n"), outputPath, len(inputs))
+	return nil
+}
+
+// generateBatch converts every stage file into outputDir. For Go output it
+// names files stageN.go and also writes the stage registry.
+func generateBatch(files []string, format, outputDir string, opts stagefile.Options) error {
+	inputs, err := readBatch(files, opts)
 	if err != nil {
-		return fmt.Errorf("出力ファイル作成エラー: %v", err)
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
 	}
-	defer file.Close()
 
-	if err := t.Execute(file, stageData); err != nil {
-		return fmt.Errorf("テンプレート実行エラー: %v", err)
+	var stages []RegistryStage
+	var greedyTotal, minimalTotal RectangleStats
+	fmt.Println(localize("矩形の比較 (貪欲法 → 最小分割):", "rectangles (greedy → min):"))
+	for _, input := range inputs {
+		file, layout := input.file, input.layout
+		outputName := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) + "." + format
+		if format == "go" {
+			outputName = fmt.Sprintf("stage%d.go", layout.Metadata.Stage)
+		}
+		if err := writeStage(layout, format, filepath.Join(outputDir, outputName)); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		stages = append(stages, RegistryStage{Number: layout.Metadata.Stage, Source: filepath.Base(file)})
+
+		greedy, minimal, err := compareRectangles(layout)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		fmt.Printf("%s → %s  %s\n", file, filepath.Join(outputDir, outputName), formatRectangleComparison(greedy, minimal))
+		greedyTotal.add(greedy)
+		minimalTotal.add(minimal)
 	}
+	fmt.Printf("%s  %s\n", localize("合計", "total"), formatRectangleComparison(greedyTotal, minimalTotal))
 
+	if format != "go" {
+		return nil
+	}
+	registryPath := filepath.Join(outputDir, registryFileName)
+	if err := generateRegistryFile(stages, registryPath); err != nil {
+		return err
+	}
+	fmt.Printf(localize("ステージ一覧を生成しました: %s (%d ステージ)\n", "wrote the stage registry %s (%d stages)\n"), registryPath, len(stages))
 	return nil
 }
 
+// flagDescriptions holds the Japanese and English description of each flag
+var flagDescriptions = map[string][2]string{
+	"format": {"出力形式: go, txt, json, tmx, png (既定: .txt の入力は go、.json/.tmx の入力は txt。一括変換では go)",
+		"output format: go, txt, json, tmx, png (default: go for .txt input, txt for .json/.tmx input, go in batch mode)"},
+	"out":   {"一括変換の出力先ディレクトリ", "output directory for batch conversion"},
+	"sheet": {"ステージを変換する代わりに、すべてのステージのプレビューを並べた PNG をこのファイルに書き出す", "instead of converting, write a PNG with previews of all the stages to this file"},
+	"lang":  {"メッセージの言語: ja (日本語), en (English)", "message language: ja (Japanese), en (English)"},
+	"rects": {"足場の矩形への分け方: min (重ならない最小個数), greedy (右・下に伸ばす従来の方法)", "how platforms are split into rectangles: min (fewest non-overlapping), greedy (grow right, then down)"},
+}
+
+// flagDescription returns the description of a flag in the message language
+func flagDescription(name string) string {
+	return localize(flagDescriptions[name][0], flagDescriptions[name][1])
+}
+
+// printUsage writes the usage and the flags of fs in the message language.
+// Flags are described again here, since -lang is only known after parsing.
+func printUsage(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := flagDescriptions[f.Name]; ok {
+			f.Usage = flagDescription(f.Name)
+		}
+	})
+	w, name := fs.Output(), fs.Name()
+	files := localize("<ディレクトリ|パターン|ファイル...>", "<directory|pattern|file...>")
+	fmt.Fprintf(w, localize("使用方法: %s", "usage: %s")+" [-format go|txt|json|tmx|png] [-rects min|greedy] [-lang ja|en] <input.txt|input.json|input.tmx>\n", name)
+	fmt.Fprintf(w, "        %s [-format go|txt|json|tmx|png] [-rects min|greedy] [-lang ja|en] [-out dir] %s  %s\n", name, files, localize("(一括変換)", "(batch conversion)"))
+	fmt.Fprintf(w, "        %s -sheet stages.png %s  %s\n", name, files, localize("(コンタクトシート)", "(contact sheet)"))
+	example := localize("例", "example")
+	fmt.Fprintf(w, "%s: %s stage1.txt\n", example, name)
+	fmt.Fprintf(w, "%s: %s .                      %s\n", example, name, fmt.Sprintf(localize("(stage*.txt をすべて stageN.go に変換し、%s を生成する)", "(converts every stage*.txt to stageN.go and writes %s)"), registryFileName))
+	fmt.Fprintf(w, "%s: %s -format tmx stage1.txt  %s\n", example, name, localize("(Tiled 用にエクスポート)", "(export for Tiled)"))
+	fmt.Fprintf(w, "%s: %s -format png stage1.txt  %s\n", example, name, localize("(プレビュー画像を書き出す)", "(write a preview image)"))
+	fmt.Fprintf(w, "%s: %s stage1.tmx              %s\n", example, name, localize("(Tiled からインポートして stage1.txt を書き出す)", "(import from Tiled and write stage1.txt)"))
+	fs.PrintDefaults()
+}
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "使用方法: %s <input.txt>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "例: %s stage1.txt\n", os.Args[0])
+	format := flag.String("format", "", flagDescription("format"))
+	outputDir := flag.String("out", ".", flagDescription("out"))
+	sheet := flag.String("sheet", "", flagDescription("sheet"))
+	var opts stagefile.Options
+	flag.TextVar(&stagefile.ErrorLanguage, "lang", stagefile.Japanese, flagDescription("lang"))
+	flag.TextVar(&opts.Decomposition, "rects", stagefile.DecomposeMinimal, flagDescription("rects"))
+	flag.Usage = func() { printUsage(flag.CommandLine) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
+	files, batch, err := batchInputs(flag.Args())
+	if err != nil {
+		fatal(localize("入力エラー", "input error"), err)
+	}
+	if *sheet != "" {
+		if err := writeContactSheet(files, opts, *sheet); err != nil {
+			fatal(localize("コンタクトシート生成エラー", "contact sheet error"), err)
+		}
+		return
+	}
+	if batch {
+		if *format == "" {
+			*format = "go"
+		}
+		if err := generateBatch(files, *format, *outputDir, opts); err != nil {
+			fatal(localize("一括変換エラー", "batch conversion error"), err)
+		}
+		return
+	}
+
+	inputFile := files[0]
+	inputExt := filepath.Ext(inputFile)
+	if *format == "" {
+		*format = "go"
+		if inputExt == ".json" || inputExt == ".tmx" {
+			*format = "txt"
+		}
+	}
 
-	// Parse ASCII art
-	stageData, err := parseASCIIArt(inputFile)
+	// Parse the stage
+	layout, err := readStage(inputFile, opts)
 	if err != nil {
-		log.Fatalf("ASCII art解析エラー: %v", err)
+		fatal(localize("ステージ解析エラー", "stage error"), err)
+	}
+	if layout.Metadata.Stage, err = stageNumber(inputFile, layout.Metadata); err != nil {
+		message := err.Error()
+		if e := (*stagefile.Error)(nil); errors.As(err, &e) {
+			message = e.Localize(stagefile.ErrorLanguage)
+		}
+		log.Print(localize("警告: "+message+"。デフォルトの1を使用します。", "warning: "+message+"; using stage 1"))
+		layout.Metadata.Stage = 1
 	}
 
 	// Generate output filename
 	baseName := filepath.Base(inputFile)
-	outputName := strings.TrimSuffix(baseName, ".txt") + ".go"
+	outputName := strings.TrimSuffix(baseName, inputExt) + "." + *format
+	if outputName == baseName {
+		log.Fatalf(localize("入力ファイルと同じ形式には変換できません: %s", "cannot convert %s to its own format"), inputFile)
+	}
 
 	// Generate stage file
-	if err := generateStageFile(stageData, outputName); err != nil {
-		log.Fatalf("ステージファイル生成エラー: %v", err)
-	}
-
-	fmt.Printf("ステージファイルを生成しました: %s\n", outputName)
-	fmt.Printf("ステージ番号: %d\n", stageData.StageNumber)
-	fmt.Printf("プラットフォーム数: %d\n", len(stageData.Platforms))
-	fmt.Printf("ゴールプラットフォーム数: %d\n", len(stageData.GoalPlatforms))
-	fmt.Printf("スピードアッププラットフォーム数: %d\n", len(stageData.SpeedUpPlatforms))
-	fmt.Printf("スピードダウンプラットフォーム数: %d\n", len(stageData.SpeedDownPlatforms))
-	fmt.Printf("青キャラ開始位置: (%d, %d)\n", stageData.BlueStartX, stageData.BlueStartY)
-	fmt.Printf("赤キャラ開始位置: (%d, %d)\n", stageData.RedStartX, stageData.RedStartY)
+	if err := writeStage(layout, *format, outputName); err != nil {
+		fatal(localize("ステージファイル生成エラー", "output error"), err)
+	}
+	if *format != "go" {
+		fmt.Printf(localize("ステージを変換しました: %s → %s\n", "converted %s → %s\n"), inputFile, outputName)
+		return
+	}
+
+	stageData := newStageData(layout)
+	fmt.Printf(localize("ステージファイルを生成しました: %s\n", "wrote %s\n"), outputName)
+	fmt.Printf(localize("ステージ番号: %d\n", "stage number: %d\n"), stageData.StageNumber)
+	if info := stageData.Info; info != nil && info.Title != "" {
+		fmt.Printf(localize("タイトル: %s\n", "title: %s\n"), info.Title)
+	}
+	for _, count := range []struct {
+		ja, en string
+		n      int
+	}{
+		{"プラットフォーム数", "platforms", len(stageData.Platforms)},
+		{"ゴールプラットフォーム数", "goal platforms", len(stageData.GoalPlatforms)},
+		{"スピードアッププラットフォーム数", "speed-up platforms", len(stageData.SpeedUpPlatforms)},
+		{"スピードダウンプラットフォーム数", "speed-down platforms", len(stageData.SpeedDownPlatforms)},
+		{"一方通行プラットフォーム数", "one-way platforms", len(stageData.OneWayPlatforms)},
+		{"反転タイル数", "reverse tiles", len(stageData.ReversePlatforms)},
+		{"ジャンプ台数", "springs", len(stageData.SpringPlatforms)},
+		{"ベルトコンベア数", "conveyors", len(stageData.ConveyorPlatforms)},
+		{"キャラ専用ゴール数", "character goals", len(stageData.UnitGoalPlatforms)},
+		{"キャラ専用プラットフォーム数", "character platforms", len(stageData.UnitPlatforms)},
+		{"キャラ専用トゲ数", "character spikes", len(stageData.UnitSpikes)},
+		{"チェックポイント数", "checkpoints", len(stageData.Checkpoints)},
+		{"スター数", "stars", len(stageData.Collectibles)},
+		{"敵・ギミック数", "hazards", len(stageData.Hazards)},
+	} {
+		fmt.Printf("%s: %d\n", localize(count.ja, count.en), count.n)
+	}
+	fmt.Printf(localize("青キャラ開始位置: (%d, %d)\n", "blue start: (%d, %d)\n"), stageData.BlueStartX, stageData.BlueStartY)
+	fmt.Printf(localize("赤キャラ開始位置: (%d, %d)\n", "red start: (%d, %d)\n"), stageData.RedStartX, stageData.RedStartY)
+	if greedy, minimal, err := compareRectangles(layout); err == nil {
+		fmt.Printf("%s %s\n", localize("矩形の比較 (貪欲法 → 最小分割):", "rectangles (greedy → min):"), formatRectangleComparison(greedy, minimal))
+	}
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pankona/egj2025/internal/stagefile"
+)
+
+func TestStageFormats(t *testing.T) {
+	t.Run("書き出した形式から同じステージを読み込める", func(t *testing.T) {
+		layout, err := readStage("../../stage05.txt", stagefile.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		for _, format := range []string{"txt", "json", "tmx"} {
+			path := filepath.Join(dir, "stage05."+format)
+			if err := writeStage(layout, format, path); err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			got, err := readStage(path, stagefile.Options{})
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			if !reflect.DeepEqual(got, layout) {
+				t.Errorf("%s から読み込んだステージが元と一致しない", format)
+			}
+		}
+	})
+
+	t.Run("不明な出力形式はエラーになる", func(t *testing.T) {
+		layout, err := readStage("../../stage01.txt", stagefile.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeStage(layout, "bmp", filepath.Join(t.TempDir(), "stage01.bmp")); err == nil {
+			t.Error("不明な形式はエラーになるべき")
+		}
+	})
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("生成済みのステージと一覧が再生成で変わらない", func(t *testing.T) {
+		files, err := dirStageFiles("../..")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		if err := generateBatch(files, "go", dir, stagefile.Options{}); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{registryFileName}
+		for index := 0; index <= 10; index++ {
+			names = append(names, fmt.Sprintf("stage%d.go", index))
+		}
+		for _, name := range names {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("../..", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s が一致しない (make generate-stages を実行する)", name)
+			}
+		}
+	})
+
+	t.Run("同じステージの別形式は1回だけ読む", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"stage01.txt", "stage01.json", "stage02.tmx"} {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		files, err := dirStageFiles(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{filepath.Join(dir, "stage01.txt"), filepath.Join(dir, "stage02.tmx")}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("txt を優先して1ステージ1ファイルになるべき: %q", files)
+		}
+	})
+
+	t.Run("ステージ番号の重複はエラーになる", func(t *testing.T) {
+		layout, err := readStage("../../stage01.txt", stagefile.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		layout.Metadata.Stage = 1
+		if err := writeStage(layout, "json", filepath.Join(dir, "intro.json")); err != nil {
+			t.Fatal(err)
+		}
+		files := []string{"../../stage01.txt", filepath.Join(dir, "intro.json")}
+		if _, err := readBatch(files, stagefile.Options{}); err == nil {
+			t.Error("同じステージ番号のファイルが2つあればエラーになるべき")
+		}
+	})
+}
+
+func TestRectangleStats(t *testing.T) {
+	for name, tc := range map[string]struct {
+		lines           []string
+		greedy, minimal RectangleStats
+	}{
+		// Greedy covers the middle cell twice
+		"凹型": {[]string{"L....R", "O.O...", "OOO..."}, RectangleStats{3, 2, 1, 0}, RectangleStats{3, 0, 0, 0}},
+		// Greedy takes the top row first, splitting the left wall under it
+		"コの字": {[]string{"L....R", ".OO...", ".O....", ".OO..."}, RectangleStats{3, 0, 0, 1}, RectangleStats{3, 0, 0, 0}},
+		// Greedy grows the top-right pair down, leaving two pieces on the left
+		"段違い": {[]string{"L....R", "..OO..", "OOOO..", "OOOO..", "OO...."}, RectangleStats{3, 2, 4, 0}, RectangleStats{2, 0, 0, 0}},
+	} {
+		layout, err := stagefile.ParseWith(strings.NewReader(strings.Join(tc.lines, "\n")), stagefile.Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		greedy, minimal, err := compareRectangles(layout)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if greedy != tc.greedy || minimal != tc.minimal {
+			t.Errorf("%s: %s, want %s", name, formatRectangleComparison(greedy, minimal), formatRectangleComparison(tc.greedy, tc.minimal))
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	defer func() { stagefile.ErrorLanguage = stagefile.Japanese }()
+	usage := func(lang stagefile.Language) string {
+		stagefile.ErrorLanguage = lang
+		fs := flag.NewFlagSet("stagegen", flag.ContinueOnError)
+		var out bytes.Buffer
+		fs.SetOutput(&out)
+		for name := range flagDescriptions {
+			fs.String(name, "", flagDescription(name))
+		}
+		printUsage(fs)
+		return out.String()
+	}
+
+	if got := usage(stagefile.English); !strings.Contains(got, "usage: stagegen") || !strings.Contains(got, flagDescriptions["rects"][1]) ||
+		strings.ContainsFunc(got, func(r rune) bool { return r >= 0x3040 }) {
+		t.Errorf("-lang en では使用方法もフラグの説明も英語になるべき:\n%s", got)
+	}
+	if got := usage(stagefile.Japanese); !strings.Contains(got, "使用方法: stagegen") || !strings.Contains(got, flagDescriptions["rects"][0]) {
+		t.Errorf("既定では日本語になるべき:\n%s", got)
+	}
+}
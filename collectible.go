@@ -0,0 +1,75 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// collectibleInset shrinks the pickup area so items are collected only when a unit clearly touches them
+const collectibleInset = 4
+
+// Collectible is an optional star that either unit can pick up.
+// Stars collected in a clear are recorded per stage.
+type Collectible struct {
+	X, Y float64
+}
+
+// CreateGridCollectible creates a collectible using grid coordinates
+func CreateGridCollectible(x, y int) Collectible {
+	return Collectible{
+		X: GridToPixelX(x),
+		Y: GridToPixelY(y),
+	}
+}
+
+// touches reports whether a unit overlaps the collectible's pickup area
+func (c Collectible) touches(u *Unit) bool {
+	return u.X+UnitSize > c.X+collectibleInset && u.X < c.X+CellSize-collectibleInset &&
+		u.Y+UnitSize > c.Y+collectibleInset && u.Y < c.Y+CellSize-collectibleInset
+}
+
+// resetCollectibles restores the items collected at the active checkpoint, or none
+func (g *Game) resetCollectibles() {
+	g.collected = make([]bool, len(g.Stage.Collectibles))
+	if g.Checkpoint != nil {
+		copy(g.collected, g.Checkpoint.Collected)
+	}
+}
+
+// updateCollectibles picks up items touched by either unit
+func (g *Game) updateCollectibles() {
+	if len(g.collected) != len(g.Stage.Collectibles) {
+		g.resetCollectibles()
+	}
+	for i, item := range g.Stage.Collectibles {
+		if g.collected[i] {
+			continue
+		}
+		for _, unit := range []*Unit{g.BlueUnit, g.RedUnit} {
+			if item.touches(unit) {
+				g.collected[i] = true
+				g.Events.Emit(Event{Type: EventCollected, Unit: unit.Kind, X: item.X, Y: item.Y})
+				break
+			}
+		}
+	}
+}
+
+// collectedCount returns the number of items collected in the current attempt
+func (g *Game) collectedCount() int {
+	count := 0
+	for _, collected := range g.collected {
+		if collected {
+			count++
+		}
+	}
+	return count
+}
+
+// drawCollectibles draws the items that have not been collected yet
+func (g *Game) drawCollectibles(screen *ebiten.Image) {
+	renderer := g.renderer()
+	for i, item := range g.Stage.Collectibles {
+		if i < len(g.collected) && g.collected[i] {
+			continue
+		}
+		renderer.DrawCollectible(screen, item, g.Tick)
+	}
+}
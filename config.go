@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/pankona/egj2025/internal/stagefile"
+)
+
+// Config holds the startup options, read from command-line flags on desktop
+// and from URL query parameters (e.g. ?debug=1&stage=3) in the browser
+type Config struct {
+	Debug      bool               // Enable debug mode (stage 0, debug overlay, frame stepping)
+	Stage      int                // Stage to start playing directly (-1 = show the title screen)
+	PackPath   string             // Stage pack (directory or zip file) played instead of the built-in stages
+	StagesDir  string             // Directory of stageNN.txt files loaded instead of the built-in stages
+	StageCode  string             // Shared stage code to play (?stage=<code> on the web)
+	Watch      bool               // Reload stage files in StagesDir when they are saved
+	Mute       bool               // Disable all sound effects and music
+	ReplayFile string             // Replay JSON file to play back
+	RecordFile string             // File the replay of each finished attempt is written to
+	Fullscreen bool               // Start in fullscreen
+	Scale      float64            // Window size multiplier
+	Language   stagefile.Language // Language of stage file error messages
+}
+
+// DefaultConfig returns the options used when nothing is specified
+func DefaultConfig() Config {
+	return Config{Stage: -1, Scale: 1, Language: stagefile.English}
+}
+
+// newConfigFlagSet defines the startup options, storing them in cfg
+func newConfigFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("egj2025", flag.ContinueOnError)
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "enable debug mode")
+	fs.IntVar(&cfg.Stage, "stage", cfg.Stage, "start playing the given stage directly")
+	fs.StringVar(&cfg.PackPath, "pack", cfg.PackPath, "play a stage pack (directory or zip file with a pack.json manifest)")
+	fs.StringVar(&cfg.StagesDir, "stages", cfg.StagesDir, "load stageNN.txt files from a directory instead of the built-in stages")
+	fs.StringVar(&cfg.StageCode, "code", cfg.StageCode, "play the stage of a shared stage code")
+	fs.BoolVar(&cfg.Watch, "watch", cfg.Watch, "reload stage files from -stages when they are saved")
+	fs.BoolVar(&cfg.Mute, "mute", cfg.Mute, "disable sound")
+	fs.StringVar(&cfg.ReplayFile, "replay", cfg.ReplayFile, "play back a replay JSON file")
+	fs.StringVar(&cfg.RecordFile, "record", cfg.RecordFile, "write the replay of each finished attempt to a JSON file")
+	fs.BoolVar(&cfg.Fullscreen, "fullscreen", cfg.Fullscreen, "start in fullscreen")
+	fs.Float64Var(&cfg.Scale, "scale", cfg.Scale, "window size multiplier")
+	fs.TextVar(&cfg.Language, "lang", cfg.Language, "language of stage file errors: en or ja")
+	return fs
+}
+
+// ParseConfigArgs parses command-line arguments on top of base
+func ParseConfigArgs(base Config, args []string, output io.Writer) (Config, error) {
+	cfg := base
+	fs := newConfigFlagSet(&cfg)
+	fs.SetOutput(output)
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return cfg, cfg.validate()
+}
+
+// ParseConfigQuery parses URL query parameters on top of base.
+// Boolean parameters without a value (?mute) are treated as true.
+func ParseConfigQuery(base Config, query url.Values) (Config, error) {
+	cfg := base
+	fs := newConfigFlagSet(&cfg)
+	for name, values := range query {
+		f := fs.Lookup(name)
+		if f == nil || len(values) == 0 {
+			continue // Ignore parameters meant for the page itself
+		}
+		value := values[len(values)-1]
+		if _, err := strconv.Atoi(value); name == "stage" && err != nil && value != "" {
+			name = "code" // ?stage=<code> plays a shared stage
+		}
+		if value == "" && isBoolFlag(f) {
+			value = "true"
+		}
+		if err := fs.Set(name, value); err != nil {
+			return cfg, fmt.Errorf("invalid value %q for parameter %s: %w", value, name, err)
+		}
+	}
+	return cfg, cfg.validate()
+}
+
+// isBoolFlag reports whether a flag is a boolean switch
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// validate rejects options that cannot be applied
+func (cfg Config) validate() error {
+	if cfg.Stage < -1 {
+		return errors.New("stage must not be negative")
+	}
+	sources := 0
+	for _, source := range []string{cfg.PackPath, cfg.StagesDir, cfg.StageCode} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of pack, stages and code can be used")
+	}
+	// Stages from a pack or directory are checked once they are loaded (see CheckStage)
+	if sources == 0 {
+		if err := cfg.CheckStage(lastBuiltinStage); err != nil {
+			return err
+		}
+	}
+	if cfg.Watch && cfg.StagesDir == "" {
+		return errors.New("watch requires a stages directory")
+	}
+	if cfg.Scale <= 0 {
+		return errors.New("scale must be positive")
+	}
+	return nil
+}
+
+// CheckStage rejects a start stage beyond the last stage that can be played
+func (cfg Config) CheckStage(lastStage int) error {
+	if cfg.Stage > lastStage {
+		return fmt.Errorf("stage %d does not exist (the last stage is %d)", cfg.Stage, lastStage)
+	}
+	return nil
+}
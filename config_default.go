@@ -0,0 +1,16 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"os"
+)
+
+// loadConfig reads the startup options from the command line for non-WASM builds.
+// The DEBUG environment variable still enables debug mode unless -debug=false is given.
+func loadConfig() (Config, error) {
+	cfg := DefaultConfig()
+	debugEnv := os.Getenv("DEBUG")
+	cfg.Debug = debugEnv == "true" || debugEnv == "1"
+	return ParseConfigArgs(cfg, os.Args[1:], os.Stderr)
+}
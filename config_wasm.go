@@ -0,0 +1,20 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"net/url"
+	"strings"
+	"syscall/js"
+)
+
+// loadConfig reads the startup options from the URL query parameters for WASM builds
+func loadConfig() (Config, error) {
+	// Get window.location.search
+	search := js.Global().Get("window").Get("location").Get("search").String()
+	query, err := url.ParseQuery(strings.TrimPrefix(search, "?"))
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	return ParseConfigQuery(DefaultConfig(), query)
+}
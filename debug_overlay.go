@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Debug overlay layout
+const (
+	debugVelocityScale = 8 // Pixels drawn per pixel-per-tick of velocity
+	debugHUDX          = ScreenWidth - 360
+	debugHUDY          = 10
+	debugHUDLineHeight = 16
+)
+
+// Debug overlay colours
+var (
+	debugPlatformColor = color.RGBA{0, 255, 255, 200}
+	debugSpikeColor    = color.RGBA{255, 0, 255, 255}
+	debugHazardColor   = color.RGBA{255, 128, 0, 255}
+	debugUnitColor     = color.RGBA{255, 255, 255, 255}
+	debugGridColor     = color.RGBA{255, 255, 255, 40}
+	debugVelocityColor = color.RGBA{0, 255, 0, 255}
+	debugHUDBackground = color.RGBA{0, 0, 0, 160}
+)
+
+// toggleDebugOverlay shows or hides the debug overlay
+func (g *Game) toggleDebugOverlay() {
+	g.DebugOverlay = !g.DebugOverlay
+	g.showNotice("Debug overlay: " + onOff(g.DebugOverlay))
+}
+
+// drawDebugOverlay draws the grid, the collision shapes used by the simulation
+// and each unit's velocity and state. It is drawn with the world so it shakes with it.
+func (g *Game) drawDebugOverlay(screen *ebiten.Image) {
+	for x := 0; x <= ScreenWidth; x += CellSize {
+		vector.StrokeLine(screen, float32(x), 0, float32(x), ScreenHeight, 1, debugGridColor, false)
+	}
+	for y := 0; y <= ScreenHeight; y += CellSize {
+		vector.StrokeLine(screen, 0, float32(y), ScreenWidth, float32(y), 1, debugGridColor, false)
+	}
+
+	for _, platform := range g.Stage.Platforms {
+		vector.StrokeRect(screen, float32(platform.X), float32(platform.Y), float32(platform.Width), float32(platform.Height), 1, debugPlatformColor, false)
+	}
+	for _, spike := range g.Stage.Spikes {
+		tri := spike.Triangle()
+		for i := range tri {
+			next := tri[(i+1)%3]
+			vector.StrokeLine(screen, float32(tri[i].X), float32(tri[i].Y), float32(next.X), float32(next.Y), 1, debugSpikeColor, false)
+		}
+	}
+	for _, hazard := range g.hazards {
+		if x, y, w, h, ok := hazardHitbox(hazard, g.Tick); ok {
+			vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 1, debugHazardColor, false)
+		}
+	}
+	for _, p := range g.projectiles {
+		vector.StrokeRect(screen, float32(p.X), float32(p.Y), ProjectileSize, ProjectileSize, 1, debugHazardColor, false)
+	}
+	for _, unit := range []*Unit{g.BlueUnit, g.RedUnit} {
+		vector.StrokeRect(screen, float32(unit.X), float32(unit.Y), UnitSize, UnitSize, 1, debugUnitColor, false)
+
+		// Velocity vector from the unit's centre
+		centerX := float32(unit.X) + UnitSize/2
+		centerY := float32(unit.Y) + UnitSize/2
+		endX := centerX + float32(unit.VX)*debugVelocityScale
+		endY := centerY + float32(unit.VY)*debugVelocityScale
+		vector.StrokeLine(screen, centerX, centerY, endX, endY, 2, debugVelocityColor, false)
+		vector.DrawFilledCircle(screen, endX, endY, 2, debugVelocityColor, false)
+
+		// Short state flags above the unit: G = on ground, S = stopped
+		if g.MarkFont != nil {
+			drawText(screen, unitDebugFlags(unit), g.MarkFont, unit.X, unit.Y-16, debugUnitColor)
+		}
+	}
+}
+
+// unitDebugFlags returns the compact state label drawn above a unit
+func unitDebugFlags(unit *Unit) string {
+	flags := ""
+	if unit.OnGround {
+		flags += "G"
+	}
+	if unit.Stopped {
+		flags += "S"
+	}
+	if unit.SpeedModifier != 1.0 {
+		flags += fmt.Sprintf(" x%.1f", unit.SpeedModifier)
+	}
+	return flags
+}
+
+// drawDebugHUD draws frame rates and detailed unit state in the top-right corner.
+// It is drawn on the screen after the world so it stays steady while the screen shakes.
+func (g *Game) drawDebugHUD(screen *ebiten.Image) {
+	if g.MarkFont == nil {
+		return
+	}
+	lines := []string{
+		fmt.Sprintf("FPS %.1f  TPS %.1f  tick %d", ebiten.ActualFPS(), ebiten.ActualTPS(), g.Tick),
+		unitDebugLine("Blue", g.BlueUnit),
+		unitDebugLine("Red", g.RedUnit),
+	}
+	vector.DrawFilledRect(screen, debugHUDX-6, debugHUDY-4, ScreenWidth-debugHUDX, float32(len(lines)*debugHUDLineHeight+8), debugHUDBackground, false)
+	for i, line := range lines {
+		drawText(screen, line, g.MarkFont, debugHUDX, float64(debugHUDY+i*debugHUDLineHeight), debugUnitColor)
+	}
+}
+
+// unitDebugLine formats a unit's position, velocity and flags for the debug HUD
+func unitDebugLine(name string, unit *Unit) string {
+	state := "air"
+	if unit.OnGround {
+		state = "ground"
+	}
+	if unit.Stopped {
+		state += ",stopped"
+	}
+	return fmt.Sprintf("%-4s (%.0f,%.0f) v(%+.2f,%+.2f) %s x%.2f",
+		name, unit.X, unit.Y, unit.VX, unit.VY, state, unit.SpeedModifier)
+}
@@ -0,0 +1,144 @@
+package main
+
+// UnitKind identifies which of the two player units something belongs to
+type UnitKind int
+
+const (
+	UnitNone UnitKind = iota // Not tied to a specific unit
+	UnitBlue                 // Blue unit (left hand, F key)
+	UnitRed                  // Red unit (right hand, J key)
+)
+
+// EventType identifies the kind of gameplay event emitted by the simulation
+type EventType int
+
+const (
+	EventJumped              EventType = iota // A unit left the ground by jumping
+	EventLanded                               // A unit touched the ground after being airborne
+	EventHitWall                              // A unit bounced off a wall or platform side
+	EventEnteredSpeedZone                     // A unit started standing on a speed-up/speed-down platform
+	EventReachedGoal                          // A unit stopped inside a goal platform
+	EventDied                                 // A unit fell off the screen or touched a spike
+	EventStageCleared                         // Both units reached the goal
+	EventAttemptStarted                       // A stage attempt started from the spawn positions
+	EventBounced                              // A unit was launched by a spring pad or a spike (no-spike-death assist)
+	EventCheckpointActivated                  // A checkpoint pair became the new respawn point (one event per flag)
+	EventReversed                             // A unit's direction was flipped by a reverse tile
+	EventCollected                            // A unit picked up a collectible star
+	EventResumed                              // Play resumed from a safe point after a death (infinite checkpoint assist)
+	EventRespawned                            // Both units restarted at the active checkpoint after a death; the attempt goes on
+)
+
+// String returns a readable name for the event type (used in logs and replays)
+func (t EventType) String() string {
+	switch t {
+	case EventJumped:
+		return "Jumped"
+	case EventLanded:
+		return "Landed"
+	case EventHitWall:
+		return "HitWall"
+	case EventEnteredSpeedZone:
+		return "EnteredSpeedZone"
+	case EventReachedGoal:
+		return "ReachedGoal"
+	case EventDied:
+		return "Died"
+	case EventStageCleared:
+		return "StageCleared"
+	case EventAttemptStarted:
+		return "AttemptStarted"
+	case EventBounced:
+		return "Bounced"
+	case EventCheckpointActivated:
+		return "CheckpointActivated"
+	case EventReversed:
+		return "Reversed"
+	case EventCollected:
+		return "Collected"
+	case EventResumed:
+		return "Resumed"
+	case EventRespawned:
+		return "Respawned"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event is a single gameplay occurrence emitted by the simulation
+type Event struct {
+	Type          EventType
+	Stage         int      // Stage index the event happened in
+	Tick          int      // Simulation tick of the current attempt when the event happened
+	Unit          UnitKind // Unit that caused the event (UnitNone for stage-wide events)
+	X, Y          float64  // Position of the unit when the event happened
+	SpeedModifier float64  // Speed modifier of the platform (EventEnteredSpeedZone only)
+	Assisted      bool     // Whether assist options were used in the attempt (EventStageCleared only)
+	Collected     int      // Stars collected in the attempt (EventStageCleared only)
+	Collectibles  int      // Stars available in the stage (EventStageCleared only)
+}
+
+// EventHandler reacts to a gameplay event
+type EventHandler func(Event)
+
+// EventBus delivers simulation events to independent subscribers
+// (sound, particles, statistics, replay recorders) so the physics never
+// needs to know about them.
+type EventBus struct {
+	handlers map[EventType][]EventHandler
+	all      []EventHandler
+	stage    int
+	tick     int
+}
+
+// NewEventBus creates an empty event bus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		handlers: make(map[EventType][]EventHandler),
+	}
+}
+
+// Subscribe registers a handler for a single event type
+func (b *EventBus) Subscribe(eventType EventType, handler EventHandler) {
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// SubscribeAll registers a handler that receives every event
+func (b *EventBus) SubscribeAll(handler EventHandler) {
+	b.all = append(b.all, handler)
+}
+
+// SetContext sets the stage and tick stamped onto events emitted from now on
+func (b *EventBus) SetContext(stage, tick int) {
+	if b == nil {
+		return
+	}
+	b.stage = stage
+	b.tick = tick
+}
+
+// Emit delivers an event to all matching subscribers.
+// A nil bus silently drops events so the simulation can run headless.
+func (b *EventBus) Emit(event Event) {
+	if b == nil {
+		return
+	}
+	event.Stage = b.stage
+	event.Tick = b.tick
+	for _, handler := range b.handlers[event.Type] {
+		handler(event)
+	}
+	for _, handler := range b.all {
+		handler(event)
+	}
+}
+
+// emitUnitEvent emits an event located at the unit's current position
+func (u *Unit) emitUnitEvent(eventType EventType) {
+	u.Events.Emit(Event{
+		Type: eventType,
+		Unit: u.Kind,
+		X:    u.X,
+		Y:    u.Y,
+	})
+}
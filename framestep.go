@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Frame-step debugging (debug mode only)
+const (
+	FrameStepPauseKey  = ebiten.KeyF10
+	FrameStepKey       = ebiten.KeyPeriod
+	FrameRewindKey     = ebiten.KeyComma
+	frameHistorySize   = 600 // Ticks that can be rewound (10 seconds at 60 TPS)
+	frameKeyRepeatWait = 20  // Frames a step key must be held before it repeats
+	frameKeyRepeatRate = 3   // Frames between repeated steps while held
+)
+
+// GameSnapshot is a copy of the simulation state of an attempt.
+// Restoring it puts the game back at exactly that tick.
+type GameSnapshot struct {
+	Tick               int
+	State              GameState
+	Stage              *Stage
+	Blue, Red          Unit
+	Checkpoint         *RespawnPoint
+	checkpointProgress checkpointProgress
+	collected          []bool
+	hazards            []Hazard
+	projectiles        []Projectile
+	attemptAssisted    bool
+	safePoints         []safePoint
+	stats              *statsSnapshot  // nil if the game has no stats recorder
+	replay             *replaySnapshot // nil if the game has no replay recorder
+}
+
+// Snapshot copies the current simulation state, along with the state of the
+// event subscribers that count what happens in the attempt
+func (g *Game) Snapshot() GameSnapshot {
+	snapshot := GameSnapshot{
+		Tick:               g.Tick,
+		State:              g.State,
+		Stage:              g.Stage,
+		Blue:               *g.BlueUnit,
+		Red:                *g.RedUnit,
+		Checkpoint:         g.Checkpoint,
+		checkpointProgress: g.checkpointProgress,
+		collected:          append([]bool(nil), g.collected...),
+		hazards:            append([]Hazard(nil), g.hazards...),
+		projectiles:        append([]Projectile(nil), g.projectiles...),
+		attemptAssisted:    g.attemptAssisted,
+		safePoints:         append([]safePoint(nil), g.safePoints...),
+	}
+	if g.Stats != nil {
+		stats := g.Stats.snapshot(g.StageLoader.CurrentStageIndex)
+		snapshot.stats = &stats
+	}
+	if g.Replay != nil {
+		replay := g.Replay.snapshot()
+		snapshot.replay = &replay
+	}
+	return snapshot
+}
+
+// Restore puts the simulation back into a snapshot's state
+func (g *Game) Restore(snapshot GameSnapshot) {
+	g.Tick = snapshot.Tick
+	g.State = snapshot.State
+	g.Stage = snapshot.Stage
+	restoreUnit(g.BlueUnit, snapshot.Blue)
+	restoreUnit(g.RedUnit, snapshot.Red)
+	g.Checkpoint = snapshot.Checkpoint
+	g.checkpointProgress = snapshot.checkpointProgress
+	g.collected = append(g.collected[:0], snapshot.collected...)
+	g.hazards = append(g.hazards[:0], snapshot.hazards...)
+	g.projectiles = append(g.projectiles[:0], snapshot.projectiles...)
+	g.hazardStage = snapshot.Stage
+	g.attemptAssisted = snapshot.attemptAssisted
+	g.safePoints = append(g.safePoints[:0], snapshot.safePoints...)
+	if snapshot.stats != nil {
+		g.Stats.restore(*snapshot.stats)
+	}
+	if snapshot.replay != nil {
+		g.Replay.restore(*snapshot.replay)
+	}
+	if g.Particles != nil {
+		g.Particles.Clear() // Effects of the rewound ticks are spawned again when stepping
+	}
+	g.tickAccumulator = 0
+	g.Events.SetContext(g.StageLoader.CurrentStageIndex, g.Tick)
+}
+
+// snapshotRing keeps the most recent snapshots, dropping the oldest when full
+type snapshotRing struct {
+	items []GameSnapshot
+	start int // Index of the oldest snapshot
+	count int
+}
+
+// push adds a snapshot, overwriting the oldest one when the ring is full
+func (r *snapshotRing) push(snapshot GameSnapshot) {
+	if r.items == nil {
+		r.items = make([]GameSnapshot, frameHistorySize)
+	}
+	r.items[(r.start+r.count)%len(r.items)] = snapshot
+	if r.count < len(r.items) {
+		r.count++
+	} else {
+		r.start = (r.start + 1) % len(r.items)
+	}
+}
+
+// pop removes and returns the newest snapshot
+func (r *snapshotRing) pop() (GameSnapshot, bool) {
+	if r.count == 0 {
+		return GameSnapshot{}, false
+	}
+	r.count--
+	i := (r.start + r.count) % len(r.items)
+	snapshot := r.items[i]
+	r.items[i] = GameSnapshot{}
+	return snapshot, true
+}
+
+// reset drops all snapshots, keeping the buffer for reuse
+func (r *snapshotRing) reset() {
+	clear(r.items)
+	r.start, r.count = 0, 0
+}
+
+// frameStepper holds the frame-step debugging state
+type frameStepper struct {
+	paused  bool
+	history snapshotRing // Snapshots taken before each tick of the current attempt
+}
+
+// advanceSimulation runs one simulation tick, remembering the previous state for rewinding in debug mode
+func (g *Game) advanceSimulation(blueJump, redJump bool) {
+	if DebugMode {
+		g.frameStep.history.push(g.Snapshot())
+	}
+	g.stepSimulation(blueJump, redJump)
+}
+
+// updateFrameStep handles the frame-step keys (debug mode only)
+func (g *Game) updateFrameStep() {
+	if !DebugMode {
+		return
+	}
+	g.handleFrameStepKeys(
+		inpututil.IsKeyJustPressed(FrameStepPauseKey),
+		repeatingKeyPressed(FrameStepKey),
+		repeatingKeyPressed(FrameRewindKey),
+	)
+}
+
+// handleFrameStepKeys pauses, steps or rewinds the simulation
+func (g *Game) handleFrameStepKeys(togglePause, step, rewind bool) {
+	if togglePause {
+		g.frameStep.paused = !g.frameStep.paused
+		g.showNotice("Frame step: " + onOff(g.frameStep.paused))
+	}
+	if !g.frameStep.paused || !g.inAttempt() {
+		return
+	}
+	if rewind {
+		if snapshot, ok := g.frameStep.history.pop(); ok {
+			g.Restore(snapshot)
+		}
+	}
+	if step && g.State == StatePlaying {
+		g.advanceSimulation(g.pendingBlueJump, g.pendingRedJump)
+		g.pendingBlueJump = false
+		g.pendingRedJump = false
+	}
+}
+
+// inAttempt reports whether the game shows an attempt that can be stepped or rewound
+func (g *Game) inAttempt() bool {
+	return g.State == StatePlaying || g.State == StateGameOver || g.State == StateCleared
+}
+
+// repeatingKeyPressed reports a key press, repeating while the key is held
+func repeatingKeyPressed(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d >= frameKeyRepeatWait && (d-frameKeyRepeatWait)%frameKeyRepeatRate == 0)
+}
+
+// drawFrameStepStatus shows the current frame while the simulation is paused
+func (g *Game) drawFrameStepStatus(screen *ebiten.Image) {
+	if !g.frameStep.paused || g.MarkFont == nil {
+		return
+	}
+	status := fmt.Sprintf("PAUSED  frame %d  (%d to rewind)   '.' step  ',' rewind  F10 resume", g.Tick, g.frameStep.history.count)
+	drawText(screen, status, g.MarkFont, StageTextX, ScreenHeight-24, debugUnitColor)
+}
@@ -0,0 +1,273 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Hazard behaviour constants (all timings in simulation ticks)
+const (
+	WalkerSpeed = 0.8 // Patrol speed of walkers
+
+	FallingBlockDelay = 20 // Ticks a triggered block shakes before it falls
+
+	LaserOnTicks   = 90 // Ticks the beam stays on
+	LaserOffTicks  = 90 // Ticks the beam stays off
+	LaserWarnTicks = 30 // Ticks before switching on during which the emitter flashes
+
+	TurretInterval  = 120 // Ticks between shots
+	ProjectileSpeed = 3.0
+	ProjectileSize  = 6
+)
+
+// HazardKind identifies the behaviour of a hazard
+type HazardKind int
+
+const (
+	HazardWalker       HazardKind = iota // Patrols a platform and turns at edges and walls
+	HazardFallingBlock                   // Falls when a unit passes underneath
+	HazardLaser                          // Vertical beam that toggles on and off
+	HazardTurret                         // Fires projectiles horizontally at a fixed interval
+)
+
+// Hazard is a moving or timed obstacle that ends the attempt on contact.
+// Stage.Hazards holds the initial state; the game simulates a copy per attempt.
+type Hazard struct {
+	Kind      HazardKind
+	X, Y      float64 // Top-left position of the hazard body
+	VY        float64 // Vertical velocity (falling blocks and walkers)
+	Direction int     // Walking or firing direction (1 = right, -1 = left)
+
+	Timer     int     // Behaviour timer (trigger delay, shot countdown)
+	Triggered bool    // Falling block: a unit passed underneath
+	Landed    bool    // Falling block: came to rest (harmless afterwards)
+	BeamEnd   float64 // Laser: Y where the beam is stopped by a platform
+}
+
+// Projectile is a shot fired by a turret
+type Projectile struct {
+	X, Y float64 // Top-left position
+	VX   float64
+}
+
+// CreateGridWalker creates a walker standing in the given cell, walking right
+func CreateGridWalker(x, y int) Hazard {
+	return Hazard{Kind: HazardWalker, X: GridToPixelX(x), Y: GridToPixelY(y), Direction: 1}
+}
+
+// CreateGridFallingBlock creates a block hanging in the given cell
+func CreateGridFallingBlock(x, y int) Hazard {
+	return Hazard{Kind: HazardFallingBlock, X: GridToPixelX(x), Y: GridToPixelY(y)}
+}
+
+// CreateGridLaser creates a laser emitter firing downward from the given cell
+func CreateGridLaser(x, y int) Hazard {
+	return Hazard{Kind: HazardLaser, X: GridToPixelX(x), Y: GridToPixelY(y)}
+}
+
+// CreateGridTurret creates a turret firing in the given direction (1 = right, -1 = left)
+func CreateGridTurret(x, y, direction int) Hazard {
+	return Hazard{Kind: HazardTurret, X: GridToPixelX(x), Y: GridToPixelY(y), Direction: direction, Timer: TurretInterval}
+}
+
+// LaserOn reports whether a laser's beam is active at the given tick
+func LaserOn(tick int) bool {
+	return tick%(LaserOnTicks+LaserOffTicks) < LaserOnTicks
+}
+
+// laserWarning reports whether a laser is about to switch on at the given tick
+func laserWarning(tick int) bool {
+	phase := tick % (LaserOnTicks + LaserOffTicks)
+	return phase >= LaserOnTicks+LaserOffTicks-LaserWarnTicks
+}
+
+// solidAt returns the platform containing a point that blocks hazards, or nil.
+// Unit-only platforms are ignored so hazards behave the same for both units.
+func solidAt(stage *Stage, x, y float64) *Platform {
+	for i := range stage.Platforms {
+		platform := &stage.Platforms[i]
+		if platform.Only != UnitNone {
+			continue
+		}
+		if x >= platform.X && x < platform.X+platform.Width && y >= platform.Y && y < platform.Y+platform.Height {
+			return platform
+		}
+	}
+	return nil
+}
+
+// resetHazards copies the stage's hazards into their initial state for a new attempt
+func (g *Game) resetHazards() {
+	g.hazards = append(g.hazards[:0], g.Stage.Hazards...)
+	g.projectiles = g.projectiles[:0]
+	for i := range g.hazards {
+		hazard := &g.hazards[i]
+		if hazard.Kind == HazardLaser {
+			// The beam reaches down to the first platform below the emitter
+			hazard.BeamEnd = float64(ScreenHeight)
+			for y := hazard.Y + CellSize; y < ScreenHeight; y += CellSize {
+				if platform := solidAt(g.Stage, hazard.X+CellSize/2, y); platform != nil {
+					hazard.BeamEnd = platform.Y
+					break
+				}
+			}
+		}
+	}
+	g.hazardStage = g.Stage
+}
+
+// updateHazards advances every hazard and projectile by one tick
+func (g *Game) updateHazards() {
+	if g.hazardStage != g.Stage {
+		g.resetHazards()
+	}
+	for i := range g.hazards {
+		hazard := &g.hazards[i]
+		switch hazard.Kind {
+		case HazardWalker:
+			g.updateWalker(hazard)
+		case HazardFallingBlock:
+			g.updateFallingBlock(hazard)
+		case HazardTurret:
+			hazard.Timer--
+			if hazard.Timer <= 0 {
+				hazard.Timer = TurretInterval
+				x := hazard.X + CellSize
+				if hazard.Direction < 0 {
+					x = hazard.X - ProjectileSize
+				}
+				g.projectiles = append(g.projectiles, Projectile{
+					X:  x,
+					Y:  hazard.Y + (CellSize-ProjectileSize)/2,
+					VX: ProjectileSpeed * float64(hazard.Direction),
+				})
+			}
+		}
+	}
+
+	// Move projectiles and drop the ones that hit a wall or left the screen
+	projectiles := g.projectiles[:0]
+	for _, p := range g.projectiles {
+		p.X += p.VX
+		if p.X+ProjectileSize < 0 || p.X > ScreenWidth || solidAt(g.Stage, p.X+ProjectileSize/2, p.Y+ProjectileSize/2) != nil {
+			continue
+		}
+		projectiles = append(projectiles, p)
+	}
+	g.projectiles = projectiles
+}
+
+// updateWalker moves a walker along its platform, turning at edges and walls
+func (g *Game) updateWalker(hazard *Hazard) {
+	// Fall until standing on something
+	if solidAt(g.Stage, hazard.X+CellSize/2, hazard.Y+CellSize) == nil {
+		g.dropHazard(hazard)
+		return
+	}
+
+	aheadX := hazard.X + CellSize
+	if hazard.Direction < 0 {
+		aheadX = hazard.X - 1
+	}
+	blocked := aheadX < 0 || aheadX >= ScreenWidth || solidAt(g.Stage, aheadX, hazard.Y+CellSize/2) != nil
+	noGround := solidAt(g.Stage, aheadX, hazard.Y+CellSize) == nil
+	if blocked || noGround {
+		hazard.Direction = -hazard.Direction
+		return
+	}
+	hazard.X += WalkerSpeed * float64(hazard.Direction)
+}
+
+// updateFallingBlock triggers a block when a unit passes underneath and lets it fall
+func (g *Game) updateFallingBlock(hazard *Hazard) {
+	if hazard.Landed {
+		return
+	}
+	if !hazard.Triggered {
+		for _, unit := range []*Unit{g.BlueUnit, g.RedUnit} {
+			if g.underFallingBlock(hazard, unit) {
+				hazard.Triggered = true
+				hazard.Timer = FallingBlockDelay
+				break
+			}
+		}
+		return
+	}
+	if hazard.Timer > 0 {
+		hazard.Timer--
+		return
+	}
+	hazard.Landed = g.dropHazard(hazard)
+}
+
+// underFallingBlock reports whether a unit is in the block's column below it,
+// with no platform in between that the block would land on first
+func (g *Game) underFallingBlock(hazard *Hazard, unit *Unit) bool {
+	if unit.X+UnitSize <= hazard.X || unit.X >= hazard.X+CellSize || unit.Y < hazard.Y+CellSize {
+		return false
+	}
+	for y := hazard.Y + CellSize; y <= unit.Y; y += CellSize {
+		if solidAt(g.Stage, hazard.X+CellSize/2, y) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// dropHazard applies gravity to a hazard and reports whether it landed on a platform
+func (g *Game) dropHazard(hazard *Hazard) bool {
+	hazard.VY += GRAVITY
+	hazard.Y += hazard.VY
+	if platform := solidAt(g.Stage, hazard.X+CellSize/2, hazard.Y+CellSize); platform != nil {
+		hazard.Y = platform.Y - CellSize
+		hazard.VY = 0
+		return true
+	}
+	return false
+}
+
+// hazardHitbox returns the harmful area of a hazard at the given tick (ok = false if harmless)
+func hazardHitbox(hazard Hazard, tick int) (x, y, w, h float64, ok bool) {
+	switch hazard.Kind {
+	case HazardFallingBlock:
+		if hazard.Landed {
+			return 0, 0, 0, 0, false
+		}
+	case HazardLaser:
+		if !LaserOn(tick) {
+			return 0, 0, 0, 0, false
+		}
+		// Only the beam below the emitter is harmful
+		return hazard.X + 7, hazard.Y + CellSize, 6, hazard.BeamEnd - hazard.Y - CellSize, true
+	}
+	return hazard.X, hazard.Y, CellSize, CellSize, true
+}
+
+// hitByHazard reports whether a unit touches a harmful hazard or projectile
+func (g *Game) hitByHazard(unit *Unit) bool {
+	overlaps := func(x, y, w, h float64) bool {
+		return unit.X+UnitSize > x && unit.X < x+w && unit.Y+UnitSize > y && unit.Y < y+h
+	}
+	for _, hazard := range g.hazards {
+		if x, y, w, h, ok := hazardHitbox(hazard, g.Tick); ok && overlaps(x, y, w, h) {
+			return true
+		}
+	}
+	for _, p := range g.projectiles {
+		if overlaps(p.X, p.Y, ProjectileSize, ProjectileSize) {
+			return true
+		}
+	}
+	return false
+}
+
+// drawHazards draws hazards and projectiles
+func (g *Game) drawHazards(screen *ebiten.Image) {
+	if g.hazardStage != g.Stage {
+		g.resetHazards()
+	}
+	renderer := g.renderer()
+	for _, hazard := range g.hazards {
+		renderer.DrawHazard(screen, hazard, g.Tick)
+	}
+	for _, p := range g.projectiles {
+		renderer.DrawProjectile(screen, p)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Hot reload of stage files (desktop development mode, -watch)
+const (
+	hotReloadInterval = 30 // Frames between checks for changed stage files
+	reloadBannerY     = ScreenHeight - 60
+)
+
+// reloadBannerColor is the background of the stage file error banner
+var reloadBannerColor = color.RGBA{160, 20, 20, 230}
+
+// StageWatcher polls a stage directory and reloads stage files when they are saved
+type StageWatcher struct {
+	Source   *DirStageSource
+	Err      error // Latest parse error, shown as a banner until the file is fixed
+	errStage int   // Stage index Err belongs to
+	modTimes map[string]time.Time
+	timer    int
+}
+
+// NewStageWatcher loads every stage in source once, remembering the file
+// modification times and the first parse error
+func NewStageWatcher(source *DirStageSource) *StageWatcher {
+	w := &StageWatcher{Source: source}
+	w.modTimes = w.currentModTimes()
+	for _, index := range w.stageIndices() {
+		if err := source.Reload(index); err != nil && w.Err == nil {
+			w.Err, w.errStage = err, index
+		}
+	}
+	return w
+}
+
+// currentModTimes returns the modification time of every stage file
+func (w *StageWatcher) currentModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, path := range w.Source.files {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// stageIndices returns the stage indices in the source in ascending order
+func (w *StageWatcher) stageIndices() []int {
+	indices := make([]int, 0, len(w.Source.files))
+	for index := range w.Source.files {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+	return indices
+}
+
+// Poll reloads stage files that were added or saved since the last poll and
+// returns the indices of the stages that changed successfully
+func (w *StageWatcher) Poll() []int {
+	if err := w.Source.scan(); err != nil {
+		w.Err, w.errStage = err, -1
+		return nil
+	}
+	modTimes := w.currentModTimes()
+	var reloaded []int
+	for _, index := range w.stageIndices() {
+		path := w.Source.files[index]
+		if previous, ok := w.modTimes[path]; ok && previous.Equal(modTimes[path]) {
+			continue
+		}
+		if err := w.Source.Reload(index); err != nil {
+			log.Printf("Stage reload failed: %v", err)
+			w.Err, w.errStage = err, index
+			continue
+		}
+		if w.Err != nil && (w.errStage == index || w.errStage == -1) {
+			w.Err = nil
+		}
+		reloaded = append(reloaded, index)
+	}
+	w.modTimes = modTimes
+	return reloaded
+}
+
+// updateHotReload checks for changed stage files and swaps a reloaded
+// current stage into the running game
+func (g *Game) updateHotReload() {
+	w := g.StageWatcher
+	if w == nil {
+		return
+	}
+	w.timer++
+	if w.timer < hotReloadInterval {
+		return
+	}
+	w.timer = 0
+
+	reloaded := w.Poll()
+	g.StageLoader.TotalStages = w.Source.LastStage()
+	if slices.Contains(reloaded, g.StageLoader.CurrentStageIndex) && g.inAttempt() {
+		g.reloadCurrentStage()
+	}
+}
+
+// reloadCurrentStage restarts the current stage from its (new) start positions
+func (g *Game) reloadCurrentStage() {
+	g.clearCheckpoint()
+	g.resetGame()
+	g.showNotice(fmt.Sprintf("Reloaded stage %d", g.StageLoader.CurrentStageIndex))
+}
+
+// drawReloadError shows the latest stage file parse error as a banner
+func (g *Game) drawReloadError(screen *ebiten.Image) {
+	if g.StageWatcher == nil || g.StageWatcher.Err == nil || g.MarkFont == nil {
+		return
+	}
+	vector.DrawFilledRect(screen, 0, reloadBannerY, ScreenWidth, 40, reloadBannerColor, false)
+	drawText(screen, "Stage file error (the last working version is kept):", g.MarkFont, StageTextX, reloadBannerY+4, WhiteColor)
+	drawText(screen, g.StageWatcher.Err.Error(), g.MarkFont, StageTextX, reloadBannerY+20, WhiteColor)
+}
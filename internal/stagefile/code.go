@@ -0,0 +1,117 @@
+package stagefile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+// Stage code format: base64url (no padding) of
+//
+//	version (1 byte) | width (uvarint) | height (uvarint) |
+//	runs of glyph (1 byte) + length (uvarint), row-major | CRC-32 of the preceding bytes (4 bytes)
+//
+// Rows shorter than the widest row are padded with '.' so the grid is rectangular.
+const codeVersion = 1
+
+// maxCodeWidth and maxCodeHeight limit the grid size a code may describe, so
+// a crafted code cannot make the decoder allocate huge grids. They are checked
+// separately because width*height overflows for large enough sizes.
+const (
+	maxCodeWidth  = 64
+	maxCodeHeight = 64
+)
+
+// Errors returned by DecodeGrid
+var (
+	ErrInvalidCode  = errors.New("invalid stage code")
+	ErrCodeChecksum = errors.New("stage code checksum mismatch (the code is incomplete or mistyped)")
+	ErrCodeVersion  = errors.New("unsupported stage code version")
+)
+
+// EncodeGrid encodes the lines of a stage file into a compact URL-safe code
+func EncodeGrid(lines []string) string {
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+
+	var buf []byte
+	buf = append(buf, codeVersion)
+	buf = binary.AppendUvarint(buf, uint64(width))
+	buf = binary.AppendUvarint(buf, uint64(len(lines)))
+
+	var run byte
+	count := 0
+	for _, line := range lines {
+		for x := range width {
+			glyph := byte('.')
+			if x < len(line) {
+				glyph = line[x]
+			}
+			if glyph == run && count > 0 {
+				count++
+				continue
+			}
+			if count > 0 {
+				buf = append(buf, run)
+				buf = binary.AppendUvarint(buf, uint64(count))
+			}
+			run, count = glyph, 1
+		}
+	}
+	if count > 0 {
+		buf = append(buf, run)
+		buf = binary.AppendUvarint(buf, uint64(count))
+	}
+
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// DecodeGrid decodes a code made by EncodeGrid back into the lines of a stage file
+func DecodeGrid(code string) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(code))
+	if err != nil || len(data) < 5 {
+		return nil, ErrInvalidCode
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, ErrCodeChecksum
+	}
+	if body[0] != codeVersion {
+		return nil, ErrCodeVersion
+	}
+
+	r := bytes.NewReader(body[1:])
+	width, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrInvalidCode
+	}
+	height, err := binary.ReadUvarint(r)
+	if err != nil || width == 0 || width > maxCodeWidth || height == 0 || height > maxCodeHeight {
+		return nil, ErrInvalidCode
+	}
+
+	grid := make([]byte, 0, width*height)
+	for r.Len() > 0 {
+		glyph, _ := r.ReadByte()
+		count, err := binary.ReadUvarint(r)
+		if err != nil || uint64(len(grid))+count > width*height {
+			return nil, ErrInvalidCode
+		}
+		grid = append(grid, bytes.Repeat([]byte{glyph}, int(count))...)
+	}
+	if uint64(len(grid)) != width*height {
+		return nil, ErrInvalidCode
+	}
+
+	lines := make([]string, height)
+	for y := range lines {
+		lines[y] = string(grid[uint64(y)*width : uint64(y+1)*width])
+	}
+	return lines, nil
+}
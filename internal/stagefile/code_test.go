@@ -0,0 +1,35 @@
+package stagefile
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeGrid(t *testing.T) {
+	t.Run("エンコードしたグリッドに戻せる", func(t *testing.T) {
+		lines := []string{"..L..", "#####", "..R"}
+		got, err := DecodeGrid(EncodeGrid(lines))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"..L..", "#####", "..R.."}
+		if len(got) != len(want) {
+			t.Fatalf("行数が一致しない: %q", got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%d行目が一致しない: %q", i+1, got[i])
+			}
+		}
+	})
+
+	t.Run("大きすぎるサイズはエラーになる", func(t *testing.T) {
+		// width = 1<<63, height = 2: width*height overflows to 0
+		if _, err := DecodeGrid("AYCAgICAgICAgAECmMOAZQ"); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("ErrInvalidCode になるべき: %v", err)
+		}
+		if _, err := DecodeGrid(EncodeGrid([]string{string(make([]byte, maxCodeWidth+1))})); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("幅の上限を超えるとエラーになるべき: %v", err)
+		}
+	})
+}
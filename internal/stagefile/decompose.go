@@ -0,0 +1,267 @@
+package stagefile
+
+// Decomposition selects how the cells of a rectangular glyph are split into platforms
+type Decomposition int
+
+const (
+	// DecomposeMinimal splits each area into the fewest non-overlapping
+	// rectangles. It is the default, and the generated stageN.go files use it.
+	DecomposeMinimal Decomposition = iota
+	// DecomposeGreedy grows each platform right, then down, from the first cell
+	// not yet covered. Platforms may overlap, and L shapes and staircases become
+	// many small platforms.
+	DecomposeGreedy
+)
+
+var decompositionNames = []string{"min", "greedy"}
+
+func (d Decomposition) MarshalText() ([]byte, error) {
+	return marshalEnum(decompositionNames, int(d))
+}
+
+func (d *Decomposition) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(decompositionNames, text)
+	*d = Decomposition(value)
+	return err
+}
+
+// Options changes how Parse reads a stage
+type Options struct {
+	Decomposition Decomposition
+	File          string // File name used in the positions of errors
+}
+
+// chord is a horizontal or vertical segment between two concave corners of an
+// area, on the grid lines (corner (x, y) is the top-left corner of cell (x, y))
+type chord struct {
+	x0, y0, x1, y1 int
+}
+
+// crosses reports whether a horizontal and a vertical chord share a point
+func (h chord) crosses(v chord) bool {
+	return v.x0 >= h.x0 && v.x0 <= h.x1 && h.y0 >= v.y0 && h.y0 <= v.y1
+}
+
+// minimalRectangles splits the cells of glyph into the fewest rectangles
+// that cover each cell exactly once, in the order of their top-left cells.
+//
+// A rectilinear area needs one cut per concave corner, except that a cut
+// joining two concave corners (a chord) removes both at once. The best set
+// of chords is the largest set of chords that do not cross, which is a
+// maximum independent set of the bipartite horizontal/vertical crossing
+// graph. The remaining concave corners are then cut straight to the nearest
+// edge or cut, and the resulting pieces are all rectangles.
+func minimalRectangles(lines []string, width int, glyph byte) []Platform {
+	height := len(lines)
+	filled := func(x, y int) bool {
+		return y >= 0 && y < height && x >= 0 && x < len(lines[y]) && lines[y][x] == glyph
+	}
+	// Grid line segments from corner (x, y) to (x+1, y) and to (x, y+1) inside the area
+	insideH := func(x, y int) bool { return filled(x, y-1) && filled(x, y) }
+	insideV := func(x, y int) bool { return filled(x-1, y) && filled(x, y) }
+	concave := func(x, y int) bool {
+		count := 0
+		for _, c := range [][2]int{{x - 1, y - 1}, {x, y - 1}, {x - 1, y}, {x, y}} {
+			if filled(c[0], c[1]) {
+				count++
+			}
+		}
+		return count == 3
+	}
+
+	// Cut segments: cutH[y][x] is the segment right of corner (x, y), cutV[y][x] the one below it
+	cutH := make([][]bool, height+1)
+	for y := range cutH {
+		cutH[y] = make([]bool, width)
+	}
+	cutV := make([][]bool, height)
+	for y := range cutV {
+		cutV[y] = make([]bool, width+1)
+	}
+
+	// Find the chords, walking right and down from each concave corner.
+	// A chord cannot pass another concave corner, since all four cells
+	// around a point inside the area are filled.
+	var corners [][2]int
+	var horizontal, vertical []chord
+	for y := 0; y <= height; y++ {
+		for x := 0; x <= width; x++ {
+			if !concave(x, y) {
+				continue
+			}
+			corners = append(corners, [2]int{x, y})
+			for end := x; end < width && insideH(end, y); {
+				if end++; concave(end, y) {
+					horizontal = append(horizontal, chord{x, y, end, y})
+					break
+				}
+			}
+			for end := y; end < height && insideV(x, end); {
+				if end++; concave(x, end) {
+					vertical = append(vertical, chord{x, y, x, end})
+					break
+				}
+			}
+		}
+	}
+
+	// Maximum matching of the crossing graph (Kuhn's algorithm)
+	crossing := make([][]int, len(horizontal))
+	for i, h := range horizontal {
+		for j, v := range vertical {
+			if h.crosses(v) {
+				crossing[i] = append(crossing[i], j)
+			}
+		}
+	}
+	matchH := make([]int, len(horizontal))
+	matchV := make([]int, len(vertical))
+	for i := range matchH {
+		matchH[i] = -1
+	}
+	for j := range matchV {
+		matchV[j] = -1
+	}
+	var visited []bool
+	var augment func(i int) bool
+	augment = func(i int) bool {
+		for _, j := range crossing[i] {
+			if visited[j] {
+				continue
+			}
+			visited[j] = true
+			if matchV[j] < 0 || augment(matchV[j]) {
+				matchH[i], matchV[j] = j, i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range horizontal {
+		visited = make([]bool, len(vertical))
+		augment(i)
+	}
+
+	// König's theorem: the chords reachable from unmatched horizontal chords by
+	// alternating paths give the minimum vertex cover, and its complement the
+	// maximum independent set (reachable horizontal + unreachable vertical chords)
+	reachedH := make([]bool, len(horizontal))
+	reachedV := make([]bool, len(vertical))
+	var reach func(i int)
+	reach = func(i int) {
+		reachedH[i] = true
+		for _, j := range crossing[i] {
+			if !reachedV[j] {
+				reachedV[j] = true
+				if next := matchV[j]; next >= 0 && !reachedH[next] {
+					reach(next)
+				}
+			}
+		}
+	}
+	for i := range horizontal {
+		if matchH[i] < 0 && !reachedH[i] {
+			reach(i)
+		}
+	}
+	for i, h := range horizontal {
+		if reachedH[i] {
+			for x := h.x0; x < h.x1; x++ {
+				cutH[h.y0][x] = true
+			}
+		}
+	}
+	for j, v := range vertical {
+		if !reachedV[j] {
+			for y := v.y0; y < v.y1; y++ {
+				cutV[y][v.x0] = true
+			}
+		}
+	}
+
+	// cut walks from concave corner (x, y) into the area in direction (dx, dy),
+	// up to the nearest edge or cut, and marks the cut if mark is set. It
+	// reports whether the cut ends on a flat side of the area, where the pieces
+	// either side of it leave a seam in the surface.
+	cut := func(x, y, dx, dy int, mark bool) (seam bool) {
+		for {
+			if dy == 0 {
+				segment := min(x, x+dx)
+				if segment < 0 || segment >= width || !insideH(segment, y) || cutH[y][segment] {
+					return !cutH[y][max(0, min(segment, width-1))] && !concave(x, y)
+				}
+				if mark {
+					cutH[y][segment] = true
+				}
+			} else {
+				segment := min(y, y+dy)
+				if segment < 0 || segment >= height || !insideV(x, segment) || cutV[segment][x] {
+					return !cutV[max(0, min(segment, height-1))][x] && !concave(x, y)
+				}
+				if mark {
+					cutV[segment][x] = true
+				}
+			}
+			x, y = x+dx, y+dy
+			if dy == 0 && ((y > 0 && cutV[y-1][x]) || (y < height && cutV[y][x])) {
+				return false
+			}
+			if dx == 0 && ((x > 0 && cutH[y][x-1]) || (x < width && cutH[y][x])) {
+				return false
+			}
+		}
+	}
+
+	// Cut each remaining concave corner straight into the area. Either
+	// direction removes the corner, but a horizontal cut that ends on a wall
+	// leaves a top edge inside the wall that updatePhysics lands units on, while
+	// a seam in a floor is harmless. Cut vertically unless the horizontal cut
+	// ends on another cut or corner.
+	for _, corner := range corners {
+		x, y := corner[0], corner[1]
+		dx, cutRight := 1, x // Direction into the area and segment index of the first step
+		if !insideH(x, y) {
+			dx, cutRight = -1, x-1
+		}
+		dy, cutDown := 1, y
+		if !insideV(x, y) {
+			dy, cutDown = -1, y-1
+		}
+		if cutH[y][cutRight] || cutV[cutDown][x] {
+			continue // Already the end of a chord or of another cut
+		}
+		if cut(x, y, dx, 0, false) {
+			cut(x, y, 0, dy, true)
+		} else {
+			cut(x, y, dx, 0, true)
+		}
+	}
+
+	// Collect the pieces between the cuts, each of which is a rectangle
+	seen := make([][]bool, height)
+	for y := range seen {
+		seen[y] = make([]bool, width)
+	}
+	var rectangles []Platform
+	for y := range height {
+		for x := range width {
+			if seen[y][x] || !filled(x, y) {
+				continue
+			}
+			rect := Platform{X: x, Y: y, Width: 1, Height: 1}
+			for rect.X+rect.Width < width && filled(rect.X+rect.Width, y) && !cutV[y][rect.X+rect.Width] {
+				rect.Width++
+			}
+			for rect.Y+rect.Height < height && filled(x, rect.Y+rect.Height) && !cutH[rect.Y+rect.Height][x] {
+				rect.Height++
+			}
+			for ry := rect.Y; ry < rect.Y+rect.Height; ry++ {
+				for rx := rect.X; rx < rect.X+rect.Width; rx++ {
+					seen[ry][rx] = true
+				}
+			}
+			rectangles = append(rectangles, rect)
+		}
+	}
+	return rectangles
+}
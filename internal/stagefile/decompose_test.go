@@ -0,0 +1,137 @@
+package stagefile
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRectangleDecomposition(t *testing.T) {
+	parse := func(t *testing.T, lines []string, decomposition Decomposition) *Layout {
+		t.Helper()
+		return parseLines(t, lines, Options{Decomposition: decomposition})
+	}
+	// checkPartition reports platforms that overlap, mix glyphs or leave platform cells uncovered
+	checkPartition := func(t *testing.T, name string, lines []string, layout *Layout) {
+		t.Helper()
+		covered := make([][]int, len(lines))
+		for y := range covered {
+			covered[y] = make([]int, len(lines[y]))
+		}
+		for _, p := range layout.Platforms {
+			for y := p.Y; y < p.Y+p.Height; y++ {
+				for x := p.X; x < p.X+p.Width; x++ {
+					if lines[y][x] != lines[p.Y][p.X] {
+						t.Fatalf("%s: 足場 %+v に別の文字 '%c' が含まれる", name, p, lines[y][x])
+					}
+					covered[y][x]++
+				}
+			}
+		}
+		for y, line := range lines {
+			for x := range line {
+				if isPlatform := strings.IndexByte("OGud-Xs}{1234", line[x]) >= 0; isPlatform != (covered[y][x] == 1) {
+					t.Fatalf("%s: (%d, %d) '%c' が足場に含まれる回数が不正 (%d 回)", name, x, y, line[x], covered[y][x])
+				}
+			}
+		}
+	}
+
+	t.Run("最小分割はすべてのステージを重ならずに覆う", func(t *testing.T) {
+		for index := 0; index <= lastStage; index++ {
+			lines := readStageLines(t, index)
+			greedy := parse(t, lines, DecomposeGreedy)
+			minimal := parse(t, lines, DecomposeMinimal)
+			// The grid without the front matter, as read by the greedy parse
+			checkPartition(t, fmt.Sprintf("ステージ%d", index), Format(greedy), minimal)
+			if !reflect.DeepEqual(minimal.Spikes, greedy.Spikes) || minimal.BlueStart != greedy.BlueStart || !reflect.DeepEqual(minimal.Hazards, greedy.Hazards) {
+				t.Errorf("ステージ%d: 足場以外は分割方法で変わらないべき", index)
+			}
+		}
+	})
+
+	t.Run("L字と階段は最小の個数になる", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			lines []string
+			want  int
+		}{
+			"L字":  {[]string{"O...", "O...", "OOOO"}, 2},
+			"階段":  {[]string{"O...", "OO..", "OOO.", "OOOO"}, 4},
+			"穴あき": {[]string{"OOOO", "O..O", "OOOO"}, 4},
+			"十字":  {[]string{".O.", "OOO", ".O."}, 3},
+			"凹型":  {[]string{"O.O", "OOO"}, 3},
+		} {
+			layout := parse(t, tc.lines, DecomposeMinimal)
+			checkPartition(t, name, tc.lines, layout)
+			if len(layout.Platforms) != tc.want {
+				t.Errorf("%s: %d 個に分割された, want %d: %+v", name, len(layout.Platforms), tc.want, layout.Platforms)
+			}
+		}
+	})
+
+	t.Run("ランダムなグリッドで総当たりの最小値と一致する", func(t *testing.T) {
+		// bruteForce returns the fewest rectangles of 'O' covering the grid exactly:
+		// the first uncovered cell must be the top-left corner of some rectangle
+		var bruteForce func(grid [][]byte) int
+		bruteForce = func(grid [][]byte) int {
+			for y := range grid {
+				for x := range grid[y] {
+					if grid[y][x] != 'O' {
+						continue
+					}
+					best := -1
+					maxWidth := len(grid[y]) - x
+					for h := 1; y+h <= len(grid); h++ {
+						for w := 1; w <= maxWidth; w++ {
+							if grid[y+h-1][x+w-1] != 'O' {
+								maxWidth = w - 1
+								break
+							}
+						}
+						if maxWidth == 0 {
+							break
+						}
+						for w := 1; w <= maxWidth; w++ {
+							for ry := y; ry < y+h; ry++ {
+								for rx := x; rx < x+w; rx++ {
+									grid[ry][rx] = 'x'
+								}
+							}
+							if n := 1 + bruteForce(grid); best < 0 || n < best {
+								best = n
+							}
+							for ry := y; ry < y+h; ry++ {
+								for rx := x; rx < x+w; rx++ {
+									grid[ry][rx] = 'O'
+								}
+							}
+						}
+					}
+					return best
+				}
+			}
+			return 0
+		}
+
+		rng := rand.New(rand.NewPCG(1, 2))
+		for i := range 300 {
+			lines := make([]string, 4)
+			grid := make([][]byte, len(lines))
+			for y := range lines {
+				row := make([]byte, 5)
+				for x := range row {
+					row[x] = ".O"[min(rng.IntN(3), 1)]
+				}
+				lines[y], grid[y] = string(row), row
+			}
+			name := fmt.Sprintf("グリッド%d\n%s", i, strings.Join(lines, "\n"))
+			layout := parse(t, lines, DecomposeMinimal)
+			checkPartition(t, name, lines, layout)
+			if want := bruteForce(grid); len(layout.Platforms) != want {
+				t.Fatalf("%s: %d 個に分割された, want %d", name, len(layout.Platforms), want)
+			}
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package stagefile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Language selects the language of error messages
+type Language int
+
+const (
+	Japanese Language = iota
+	English
+)
+
+var languageNames = []string{"ja", "en"}
+
+func (l Language) MarshalText() ([]byte, error) {
+	return marshalEnum(languageNames, int(l))
+}
+
+func (l *Language) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(languageNames, text)
+	*l = Language(value)
+	return err
+}
+
+// ErrorKind identifies a kind of problem in a stage file. Every Error
+// unwraps to its kind, so errors.Is(err, ErrUnknownGlyph) finds unknown
+// glyphs anywhere in a returned ErrorList.
+type ErrorKind int
+
+const (
+	ErrRead           ErrorKind = iota // The file could not be read
+	ErrEmpty                           // The file has no lines
+	ErrNoGrid                          // The file has front matter but no grid
+	ErrUnknownGlyph                    // A grid cell holds a character that is not a glyph
+	ErrUnknownKey                      // A front matter key is not one of the known keys
+	ErrDuplicateKey                    // A front matter key appears twice
+	ErrMissingValue                    // A front matter key has no value
+	ErrInvalidValue                    // A front matter value is out of range, not a number or malformed
+	ErrFileName                        // The file name has no stage number
+	ErrSyntax                          // A JSON or TMX document could not be parsed
+	ErrDocumentFormat                  // A JSON document is not a stage document
+	ErrVersion                         // A JSON document has an unsupported version
+	ErrSize                            // A JSON stage has no cells
+	ErrPlatformBounds                  // A JSON platform reaches outside the stage
+	ErrCellBounds                      // A JSON start, spike or other element is outside the stage
+	ErrDirection                       // A JSON platform has a direction it cannot have
+	ErrUnknownValue                    // A JSON enum value is not one of its names
+	ErrTMXLayers                       // A TMX map does not have exactly one tile layer
+	ErrTMXTileset                      // A TMX map does not have exactly one embedded tileset
+	ErrTMXEncoding                     // A TMX layer is not stored as CSV
+	ErrTMXTileCount                    // A TMX layer has the wrong number of tiles
+	ErrTMXTileID                       // A TMX tile ID is not a number
+	ErrTMXUnknownTile                  // A TMX tile has no glyph
+)
+
+var errorKindNames = []string{
+	"read error", "empty file", "no grid", "unknown glyph", "unknown key",
+	"duplicate key", "missing value", "invalid value", "bad file name",
+	"syntax error", "not a stage document", "unsupported version", "bad stage size",
+	"platform out of bounds", "element out of bounds", "bad direction", "unknown value",
+	"bad TMX layers", "bad TMX tileset", "bad TMX encoding", "bad TMX tile count",
+	"bad TMX tile ID", "unknown TMX tile",
+}
+
+// ErrorLanguage is the language of the messages returned by Error. Programs
+// set it once at startup; Localize formats in any language.
+var ErrorLanguage = Japanese
+
+func (k ErrorKind) Error() string {
+	if k < 0 || int(k) >= len(errorKindNames) {
+		return "stage file error " + strconv.Itoa(int(k))
+	}
+	return errorKindNames[k]
+}
+
+// Error is a problem at a position in a stage file
+type Error struct {
+	File   string // File name (empty if the caller did not give one)
+	Line   int    // 1-based line in the file, counting front matter (0 = whole file)
+	Column int    // 1-based character column (0 = whole line)
+	Kind   ErrorKind
+	Key    string // Front matter key the problem is about
+	Text   string // Offending glyph, key, value or file name
+	Args   []any  // Numbers shown in the message of JSON and TMX problems (see the kinds)
+	Err    error  // Underlying error (ErrRead, ErrSyntax, ErrTMXTileID)
+}
+
+// Position returns the "file:line:column" prefix of the error, leaving out unknown parts
+func (e *Error) Position() string {
+	var parts []string
+	if e.File != "" {
+		parts = append(parts, e.File)
+	}
+	if e.Line > 0 {
+		parts = append(parts, strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			parts = append(parts, strconv.Itoa(e.Column))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// Message describes the problem, without its position
+func (e *Error) Message(lang Language) string {
+	var ja, en string
+	switch e.Kind {
+	case ErrRead:
+		ja, en = fmt.Sprintf("ファイル読み込みエラー: %v", e.Err), fmt.Sprintf("cannot read the file: %v", e.Err)
+	case ErrEmpty:
+		ja, en = "空のファイルです", "the file is empty"
+	case ErrNoGrid:
+		ja, en = "グリッドがありません", "the file has no grid after its front matter"
+	case ErrUnknownGlyph:
+		ja, en = fmt.Sprintf("不明な文字 '%s' です", e.Text), fmt.Sprintf("unknown glyph '%s'", e.Text)
+	case ErrUnknownKey:
+		keys := strings.Join(frontMatterKeys, ", ")
+		ja, en = fmt.Sprintf("不明なキー %q です (%s のいずれか)", e.Text, keys), fmt.Sprintf("unknown key %q (want one of %s)", e.Text, keys)
+	case ErrDuplicateKey:
+		ja, en = fmt.Sprintf("%s が重複しています", e.Key), fmt.Sprintf("duplicate key %s", e.Key)
+	case ErrMissingValue:
+		ja, en = fmt.Sprintf("%s の値がありません", e.Key), fmt.Sprintf("%s has no value", e.Key)
+	case ErrInvalidValue:
+		switch e.Key {
+		case KeyDifficulty:
+			ja = fmt.Sprintf("difficulty は 1 から %d の整数で指定してください: %q", MaxDifficulty, e.Text)
+			en = fmt.Sprintf("difficulty must be a whole number from 1 to %d: %q", MaxDifficulty, e.Text)
+		case KeyTutorial:
+			ja = fmt.Sprintf("tutorial の位置と色は「@x,y #rrggbb 文字列」の形で指定してください: %q", e.Text)
+			en = fmt.Sprintf("tutorial must be text, optionally after \"@x,y\" and \"#rrggbb\": %q", e.Text)
+		default:
+			ja = fmt.Sprintf("%s は正の秒数で指定してください: %q", e.Key, e.Text)
+			en = fmt.Sprintf("%s must be a positive number of seconds: %q", e.Key, e.Text)
+		}
+	case ErrFileName:
+		ja = fmt.Sprintf("ファイル名 '%s' からステージ番号が分かりません (stageNN.txt の形式にしてください)", e.Text)
+		en = fmt.Sprintf("file name '%s' has no stage number (name it stageNN.txt)", e.Text)
+	case ErrSyntax:
+		ja, en = fmt.Sprintf("%sの解析に失敗しました: %v", e.Text, e.Err), fmt.Sprintf("cannot parse the %s: %v", e.Text, e.Err)
+	case ErrDocumentFormat:
+		ja = fmt.Sprintf("format が %q ではありません: %q", DocumentFormat, e.Text)
+		en = fmt.Sprintf("format is not %q: %q", DocumentFormat, e.Text)
+	case ErrVersion:
+		ja, en = fmt.Sprintf("未対応のバージョンです: %v", e.Args...), fmt.Sprintf("unsupported version %v", e.Args...)
+	case ErrSize:
+		ja, en = fmt.Sprintf("ステージの大きさが不正です: %vx%v", e.Args...), fmt.Sprintf("invalid stage size %vx%v", e.Args...)
+	case ErrPlatformBounds:
+		ja = fmt.Sprintf("足場 (%v, %v) size %vx%v がステージの範囲外です", e.Args...)
+		en = fmt.Sprintf("platform (%v, %v) size %vx%v is outside the stage", e.Args...)
+	case ErrCellBounds:
+		ja, en = fmt.Sprintf("要素 (%v, %v) がステージの範囲外です", e.Args...), fmt.Sprintf("element (%v, %v) is outside the stage", e.Args...)
+	case ErrDirection:
+		ja = fmt.Sprintf("足場 (%v, %v) の direction が不正です: %v", e.Args...)
+		en = fmt.Sprintf("platform (%v, %v) has an invalid direction %v", e.Args...)
+	case ErrUnknownValue:
+		ja, en = fmt.Sprintf("不明な値です: %q (%v のいずれか)", e.Args...), fmt.Sprintf("unknown value %q (want one of %v)", e.Args...)
+	case ErrTMXLayers:
+		ja = fmt.Sprintf("TMXにはタイルレイヤーが1つだけ必要です (%v 個あります)", e.Args...)
+		en = fmt.Sprintf("the TMX map needs exactly one tile layer (it has %v)", e.Args...)
+	case ErrTMXTileset:
+		ja, en = "TMXにはマップに埋め込まれたタイルセットが1つだけ必要です", "the TMX map needs exactly one tileset embedded in the map"
+	case ErrTMXEncoding:
+		ja = fmt.Sprintf("TMXのレイヤーはCSV形式で保存してください (encoding=%q)", e.Text)
+		en = fmt.Sprintf("save the TMX layer in CSV format (encoding=%q)", e.Text)
+	case ErrTMXTileCount:
+		ja = fmt.Sprintf("TMXのタイル数が %vx%v と一致しません: %v", e.Args...)
+		en = fmt.Sprintf("the TMX layer is %vx%v but has %v tiles", e.Args...)
+	case ErrTMXTileID:
+		ja = fmt.Sprintf("TMXのタイルID (%v, %v) が不正です: %v", append(e.Args, e.Err)...)
+		en = fmt.Sprintf("invalid TMX tile ID at (%v, %v): %v", append(e.Args, e.Err)...)
+	case ErrTMXUnknownTile:
+		ja = fmt.Sprintf("TMXのタイルID %v (%v, %v) は対応する文字がありません", e.Args...)
+		en = fmt.Sprintf("TMX tile ID %v at (%v, %v) has no glyph", e.Args...)
+	default:
+		ja, en = e.Kind.Error(), e.Kind.Error()
+	}
+	if lang == English {
+		return en
+	}
+	return ja
+}
+
+// Localize formats the error like a compiler: "file:line:column: message"
+func (e *Error) Localize(lang Language) string {
+	if position := e.Position(); position != "" {
+		return position + ": " + e.Message(lang)
+	}
+	return e.Message(lang)
+}
+
+func (e *Error) Error() string {
+	return e.Localize(ErrorLanguage)
+}
+
+// Unwrap returns the error's kind and underlying error, for errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// ErrorList is every problem found in a stage file, in file order
+type ErrorList []*Error
+
+// add records a problem
+func (l *ErrorList) add(e *Error) {
+	*l = append(*l, e)
+}
+
+// SetFile sets the file name of every error in the list
+func (l ErrorList) SetFile(name string) {
+	for _, e := range l {
+		e.File = name
+	}
+}
+
+// Err returns the list as an error, or nil if it is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Localize formats every error on its own line
+func (l ErrorList) Localize(lang Language) string {
+	lines := make([]string, len(l))
+	for i, e := range l {
+		lines[i] = e.Localize(lang)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Error returns the first error and the number of others, so the list fits on one line
+func (l ErrorList) Error() string {
+	switch {
+	case len(l) == 0 && ErrorLanguage == English:
+		return "no errors"
+	case len(l) == 0:
+		return "エラーはありません"
+	case len(l) == 1:
+		return l[0].Error()
+	case ErrorLanguage == English:
+		return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+	}
+	return fmt.Sprintf("%s (他に %d 件のエラー)", l[0], len(l)-1)
+}
+
+// Unwrap returns the errors in the list, for errors.Is and errors.As
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
@@ -0,0 +1,123 @@
+package stagefile
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestErrors(t *testing.T) {
+	file := strings.Join([]string{
+		"title: A",
+		"difficulty: 9",
+		"",
+		"OOOO",
+		"L?.R",
+		"OO@O",
+	}, "\n")
+	_, err := ParseWith(strings.NewReader(file), Options{File: "stage03.txt"})
+
+	t.Run("すべての問題が位置付きで集められる", func(t *testing.T) {
+		var list ErrorList
+		if !errors.As(err, &list) {
+			t.Fatalf("ErrorListが返るべき: %v", err)
+		}
+		want := []string{
+			"stage03.txt:2:13: difficulty は 1 から 5 の整数で指定してください: \"9\"",
+			"stage03.txt:5:2: 不明な文字 '?' です",
+			"stage03.txt:6:3: 不明な文字 '@' です",
+		}
+		if got := strings.Split(list.Localize(Japanese), "\n"); !reflect.DeepEqual(got, want) {
+			t.Errorf("コンパイラ形式で並ぶべき:\n%s", strings.Join(got, "\n"))
+		}
+		if got := list[1].Localize(English); got != "stage03.txt:5:2: unknown glyph '?'" {
+			t.Errorf("英語のメッセージになるべき: %s", got)
+		}
+		if got := err.Error(); got != want[0]+" (他に 2 件のエラー)" {
+			t.Errorf("Errorは1行にまとまるべき: %s", got)
+		}
+	})
+
+	t.Run("errors.IsとAsで種類と位置が分かる", func(t *testing.T) {
+		if !errors.Is(err, ErrUnknownGlyph) || !errors.Is(err, ErrInvalidValue) || errors.Is(err, ErrEmpty) {
+			t.Errorf("含まれる種類だけがIsで見つかるべき: %v", err)
+		}
+		var first *Error
+		if !errors.As(err, &first) || first.Line != 2 || first.Column != 13 || first.Kind != ErrInvalidValue || first.Key != KeyDifficulty {
+			t.Errorf("最初の問題がAsで取り出せるべき: %+v", first)
+		}
+	})
+
+	t.Run("桁はバイトではなく文字で数える", func(t *testing.T) {
+		grid := []string{"OOOO", "Lあ?R"}
+		var list ErrorList
+		if _, err := Parse(strings.NewReader(strings.Join(grid, "\n"))); !errors.As(err, &list) || len(list) != 2 || list[0].Column != 2 || list[1].Column != 3 {
+			t.Errorf("マルチバイト文字の後ろの桁も文字位置になるべき: %v", err)
+		}
+		_, err := EncodeTMX(grid, "tiles.png")
+		if !errors.As(err, &list) || len(list) != 2 || list[0].Column != 2 || list[1].Column != 3 || list[0].Text != "あ" {
+			t.Errorf("TMXでも文字単位で報告されるべき: %v", err)
+		}
+	})
+
+	t.Run("読み込みエラーは元のエラーを包む", func(t *testing.T) {
+		cause := errors.New("disk on fire")
+		_, err := Parse(iotest.ErrReader(cause))
+		if !errors.Is(err, ErrRead) || !errors.Is(err, cause) {
+			t.Errorf("ErrReadと元のエラーの両方が見つかるべき: %v", err)
+		}
+	})
+
+	t.Run("ファイル名の問題も型付きエラーになる", func(t *testing.T) {
+		_, err := StageNumber("levels/intro.txt")
+		var e *Error
+		if !errors.As(err, &e) || e.Kind != ErrFileName || e.Localize(English) != "levels/intro.txt: file name 'intro.txt' has no stage number (name it stageNN.txt)" {
+			t.Errorf("ファイル名のエラーになるべき: %v", err)
+		}
+	})
+
+	t.Run("ErrorLanguageでErrorの言語が変わる", func(t *testing.T) {
+		defer func(lang Language) { ErrorLanguage = lang }(ErrorLanguage)
+		ErrorLanguage = English
+		want := "stage03.txt:2:13: difficulty must be a whole number from 1 to 5: \"9\" (and 2 more errors)"
+		if got := err.Error(); got != want {
+			t.Errorf("英語の1行になるべき: %s", got)
+		}
+	})
+
+	t.Run("JSONとTMXの問題も種類付きで集められる", func(t *testing.T) {
+		data, jsonErr := EncodeJSON(parseLines(t, []string{"OOOO", "L..R", "OOOO"}, Options{}))
+		if jsonErr != nil {
+			t.Fatal(jsonErr)
+		}
+		_, jsonErr = DecodeJSON(bytes.Replace(data, []byte(`"kind": "solid"`), []byte(`"kind": "turbo"`), 1))
+		var e *Error
+		if !errors.As(jsonErr, &e) || e.Kind != ErrUnknownValue || !strings.HasPrefix(e.Localize(English), `unknown value "turbo"`) {
+			t.Errorf("不明な名前はErrUnknownValueになるべき: %v", jsonErr)
+		}
+		if _, jsonErr := DecodeJSON([]byte("{")); !errors.Is(jsonErr, ErrSyntax) {
+			t.Errorf("壊れたJSONはErrSyntaxになるべき: %v", jsonErr)
+		}
+
+		layout := &Layout{Width: 2, Height: 2, RedStart: Cell{5, 0}, Platforms: []Platform{{X: 1, Y: 1, Width: 2, Height: 1}}}
+		var list ErrorList
+		if !errors.As(layout.validate(), &list) || len(list) != 2 || list[0].Kind != ErrPlatformBounds || list[1].Kind != ErrCellBounds {
+			t.Fatalf("範囲外の足場と要素がすべて報告されるべき: %v", list)
+		}
+		if got := list.Localize(English); got != "platform (1, 1) size 2x1 is outside the stage\nelement (5, 0) is outside the stage" {
+			t.Errorf("英語のメッセージになるべき:\n%s", got)
+		}
+
+		_, tmxErr := EncodeTMX([]string{"title: A", "", "OOOO", "L?.R"}, "tiles.png")
+		if !errors.As(tmxErr, &e) || e.Kind != ErrUnknownGlyph || e.Line != 4 || e.Column != 2 {
+			t.Errorf("TMXに書き出せない文字は位置付きで報告されるべき: %v", tmxErr)
+		}
+		_, tmxErr = DecodeTMX([]byte(`<map><tileset firstgid="1"/><layer width="1" height="1"><data encoding="base64">AA==</data></layer></map>`))
+		if !errors.As(tmxErr, &e) || e.Kind != ErrTMXEncoding || e.Localize(English) != `save the TMX layer in CSV format (encoding="base64")` {
+			t.Errorf("CSVでないレイヤーはErrTMXEncodingになるべき: %v", tmxErr)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package stagefile
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// MaxDifficulty is the highest difficulty a stage can declare
+const MaxDifficulty = 5
+
+// Front matter keys, in the order FormatFrontMatter writes them
+const (
+	KeyTitle      = "title"
+	KeyAuthor     = "author"
+	KeyDifficulty = "difficulty"
+	KeyParTime    = "par_time"
+	KeyBGM        = "bgm"
+	KeyTutorial   = "tutorial" // May be repeated, one label each (see TutorialLabel)
+)
+
+var frontMatterKeys = []string{KeyTitle, KeyAuthor, KeyDifficulty, KeyParTime, KeyBGM, KeyTutorial}
+
+// HasFrontMatter reports whether the metadata has anything to write in front matter
+func (m Metadata) HasFrontMatter() bool {
+	m.Stage = 0 // The stage number comes from the file name
+	return m != Metadata{}
+}
+
+// frontMatterKey returns the key of a "key: value" line, or "" if line is
+// not one. Keys are lower case letters and underscores, and ':' is not a
+// glyph, so front matter lines never look like rows of the grid.
+func frontMatterKey(line string) string {
+	key, _, ok := strings.Cut(line, ":")
+	if !ok || key == "" || strings.TrimLeft(key, "abcdefghijklmnopqrstuvwxyz_") != "" {
+		return ""
+	}
+	return key
+}
+
+// parseFrontMatter reads the "key: value" lines at the top of a stage file.
+// It returns the metadata and the number of lines before the grid, including
+// blank lines separating the front matter from the grid, and records every
+// problem in errs.
+func parseFrontMatter(lines []string, errs *ErrorList) (Metadata, int) {
+	var metadata Metadata
+	seen := make(map[string]bool)
+	n := 0
+	for ; n < len(lines); n++ {
+		key := frontMatterKey(lines[n])
+		if key == "" {
+			break
+		}
+		_, rawValue, _ := strings.Cut(lines[n], ":")
+		value := strings.TrimSpace(rawValue)
+		keyError := &Error{Line: n + 1, Column: 1, Key: key, Text: key}
+		valueError := &Error{Line: n + 1, Column: len(lines[n]) - len(strings.TrimLeft(rawValue, " \t")) + 1, Key: key, Text: value}
+		if value == "" {
+			keyError.Kind = ErrMissingValue
+			errs.add(keyError)
+			continue
+		}
+		if seen[key] && key != KeyTutorial {
+			keyError.Kind = ErrDuplicateKey
+			errs.add(keyError)
+			continue
+		}
+		seen[key] = true
+
+		switch key {
+		case KeyTitle:
+			metadata.Title = value
+		case KeyAuthor:
+			metadata.Author = value
+		case KeyDifficulty:
+			difficulty, err := strconv.Atoi(value)
+			if err != nil || difficulty < 1 || difficulty > MaxDifficulty {
+				valueError.Kind = ErrInvalidValue
+				errs.add(valueError)
+			}
+			metadata.Difficulty = difficulty
+		case KeyParTime:
+			parTime, err := strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+			if err != nil || parTime <= 0 {
+				valueError.Kind = ErrInvalidValue
+				errs.add(valueError)
+			}
+			metadata.ParTime = parTime
+		case KeyBGM:
+			metadata.BGM = value
+		case KeyTutorial:
+			if _, ok := ParseTutorialLabel(value); !ok {
+				valueError.Kind = ErrInvalidValue
+				errs.add(valueError)
+			}
+			if metadata.Tutorial != "" {
+				metadata.Tutorial += "\n"
+			}
+			metadata.Tutorial += value
+		default:
+			keyError.Kind = ErrUnknownKey
+			errs.add(keyError)
+		}
+	}
+	if n > 0 {
+		for n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+			n++
+		}
+	}
+	return metadata, n
+}
+
+// TutorialLabel is one line of a stage's tutorial hint. A line starting with
+// "@x,y" is drawn with its top-left corner at that screen position in pixels,
+// optionally followed by a "#rrggbb" colour; other lines are centred below
+// the top of the screen.
+//
+//	tutorial: @50,440 #c8c8ff Press F key
+//	tutorial: Bring both onto the goal platform
+type TutorialLabel struct {
+	Text       string
+	X, Y       int        // Screen position in pixels (Positioned labels only)
+	Positioned bool       // Whether the line starts with a position
+	Color      color.RGBA // Colour of the text (A = 0: the game's default colour)
+}
+
+// ParseTutorialLabel reads one tutorial line (ok = false if its position or
+// colour is malformed or it has no text)
+func ParseTutorialLabel(line string) (label TutorialLabel, ok bool) {
+	rest := line
+	if position, ok := strings.CutPrefix(rest, "@"); ok {
+		position, rest, _ = strings.Cut(position, " ")
+		xs, ys, _ := strings.Cut(position, ",")
+		x, errX := strconv.Atoi(xs)
+		y, errY := strconv.Atoi(ys)
+		if errX != nil || errY != nil || x < 0 || y < 0 {
+			return TutorialLabel{}, false
+		}
+		label.X, label.Y, label.Positioned = x, y, true
+		rest = strings.TrimLeft(rest, " ")
+		if hex, ok := strings.CutPrefix(rest, "#"); ok {
+			hex, rest, _ = strings.Cut(hex, " ")
+			rgb, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || len(hex) != 6 {
+				return TutorialLabel{}, false
+			}
+			label.Color = color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 255}
+			rest = strings.TrimLeft(rest, " ")
+		}
+	}
+	label.Text = rest
+	return label, label.Text != ""
+}
+
+// TutorialLabels splits a tutorial hint into its labels, skipping malformed lines
+func TutorialLabels(tutorial string) []TutorialLabel {
+	var labels []TutorialLabel
+	for _, line := range strings.Split(tutorial, "\n") {
+		if label, ok := ParseTutorialLabel(line); ok {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// FormatFrontMatter writes metadata as the front matter lines of a stage file
+func FormatFrontMatter(metadata Metadata) []string {
+	var lines []string
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, key+": "+value)
+		}
+	}
+	add(KeyTitle, metadata.Title)
+	add(KeyAuthor, metadata.Author)
+	if metadata.Difficulty != 0 {
+		add(KeyDifficulty, strconv.Itoa(metadata.Difficulty))
+	}
+	if metadata.ParTime != 0 {
+		add(KeyParTime, strconv.FormatFloat(metadata.ParTime, 'f', -1, 64))
+	}
+	add(KeyBGM, metadata.BGM)
+	if metadata.Tutorial != "" {
+		for _, line := range strings.Split(metadata.Tutorial, "\n") {
+			add(KeyTutorial, line)
+		}
+	}
+	return lines
+}
+
+// FormatFile draws a layout back as a whole stage file: its front matter,
+// a blank line, and the grid (see Format)
+func FormatFile(layout *Layout) []string {
+	lines := FormatFrontMatter(layout.Metadata)
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, Format(layout)...)
+}
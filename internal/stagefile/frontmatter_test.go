@@ -0,0 +1,121 @@
+package stagefile
+
+import (
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFrontMatter(t *testing.T) {
+	grid := []string{
+		"OOOOO",
+		"L.*.R",
+		"OGGGO",
+	}
+	header := []string{
+		"title: Spring Tour",
+		"author: pankona",
+		"difficulty: 3",
+		"par_time: 12.5",
+		"bgm: spring.mp3",
+		"tutorial: Jump on the pads",
+		"tutorial: to reach the goal",
+	}
+	want := Metadata{
+		Title:      "Spring Tour",
+		Author:     "pankona",
+		Difficulty: 3,
+		ParTime:    12.5,
+		BGM:        "spring.mp3",
+		Tutorial:   "Jump on the pads\nto reach the goal",
+	}
+	file := append(append(append([]string{}, header...), ""), grid...)
+
+	t.Run("ヘッダーが読み込まれ、グリッドは変わらない", func(t *testing.T) {
+		layout := parseLines(t, file, Options{})
+		if layout.Metadata != want {
+			t.Errorf("メタデータが読み込まれるべき: %+v", layout.Metadata)
+		}
+		plain := parseLines(t, grid, Options{})
+		if plain.Metadata.HasFrontMatter() {
+			t.Errorf("ヘッダーのないファイルにメタデータがあってはいけない: %+v", plain.Metadata)
+		}
+		layout.Metadata = Metadata{}
+		if !reflect.DeepEqual(layout, plain) {
+			t.Error("ヘッダーがあってもグリッドは同じに読み込まれるべき")
+		}
+	})
+
+	t.Run("空行なしのヘッダーと秒の単位も読める", func(t *testing.T) {
+		layout := parseLines(t, append([]string{"title: Spring Tour", "par_time: 12.5s"}, grid...), Options{})
+		if layout.Metadata.Title != "Spring Tour" || layout.Metadata.ParTime != 12.5 || layout.Height != len(grid) {
+			t.Errorf("ヘッダーが読み込まれるべき: %+v height=%d", layout.Metadata, layout.Height)
+		}
+	})
+
+	t.Run("テキスト・JSON・TMXで保存される", func(t *testing.T) {
+		layout := parseLines(t, file, Options{})
+		if got := FormatFile(layout); !reflect.DeepEqual(got, file) {
+			t.Errorf("ヘッダーごと書き戻されるべき:\n%s", strings.Join(got, "\n"))
+		}
+		data, err := EncodeJSON(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeJSON(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Metadata != want {
+			t.Errorf("JSONにメタデータが保存されるべき: %+v", decoded.Metadata)
+		}
+		data, err = EncodeTMX(file, "stage_tiles.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, err := DecodeTMX(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(lines, file) {
+			t.Errorf("TMXのプロパティにメタデータが保存されるべき:\n%s", strings.Join(lines, "\n"))
+		}
+	})
+
+	t.Run("ヒントは位置と色を指定できる", func(t *testing.T) {
+		labels := TutorialLabels("@50,440 #c8c8ff Press F key\n@340,460 Goal Platform\nBring both to the goal")
+		want := []TutorialLabel{
+			{Text: "Press F key", X: 50, Y: 440, Positioned: true, Color: color.RGBA{200, 200, 255, 255}},
+			{Text: "Goal Platform", X: 340, Y: 460, Positioned: true},
+			{Text: "Bring both to the goal"},
+		}
+		if !reflect.DeepEqual(labels, want) {
+			t.Errorf("ヒントの位置と色が読み込まれるべき: %+v", labels)
+		}
+	})
+
+	t.Run("不正なヘッダーはエラーになる", func(t *testing.T) {
+		for name, line := range map[string]string{
+			"不明なキー":     "music: spring.mp3",
+			"値がない":      "title:",
+			"難易度が範囲外":   "difficulty: 6",
+			"難易度が数値でない": "difficulty: hard",
+			"目標タイムが負":   "par_time: -1",
+			"ヒントの位置が不正": "tutorial: @50 Press F key",
+			"ヒントの色が不正":  "tutorial: @50,440 #blue Press F key",
+			"ヒントの文字がない": "tutorial: @50,440 #c8c8ff",
+		} {
+			if _, err := Parse(strings.NewReader(strings.Join(append([]string{line}, grid...), "\n"))); err == nil {
+				t.Errorf("%sはエラーになるべき", name)
+			}
+		}
+		duplicate := append([]string{"title: A", "title: B"}, grid...)
+		if _, err := Parse(strings.NewReader(strings.Join(duplicate, "\n"))); err == nil {
+			t.Error("重複したキーはエラーになるべき")
+		}
+		if _, err := Parse(strings.NewReader("title: A\n")); err == nil {
+			t.Error("グリッドのないファイルはエラーになるべき")
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package stagefile
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSON export format
+const (
+	DocumentFormat  = "egj2025-stage"
+	DocumentVersion = 1
+	CellSize        = 20 // Pixels per grid cell in the game
+)
+
+// Metadata describes a stage beyond its geometry. Everything but the stage
+// number can be written as front matter at the top of a stage file:
+//
+//	title: Spring Tour
+//	author: pankona
+//	difficulty: 3
+//	par_time: 12.5
+//	bgm: spring.mp3
+//	tutorial: Jump on the pink pads
+//	tutorial: to reach the high goal
+//
+//	OOOOOOOO...
+type Metadata struct {
+	Stage      int     `json:"stage,omitempty"`      // Stage number (from the stageNN file name)
+	Title      string  `json:"title,omitempty"`      // Shown on the title card when the stage starts
+	Author     string  `json:"author,omitempty"`     // Stage author
+	Difficulty int     `json:"difficulty,omitempty"` // 1 (easy) to MaxDifficulty (0 = not rated)
+	ParTime    float64 `json:"par_time,omitempty"`   // Target clear time in seconds
+	BGM        string  `json:"bgm,omitempty"`        // Background music track name
+	Tutorial   string  `json:"tutorial,omitempty"`   // Hint shown while playing (lines separated by "\n")
+}
+
+// Document is the JSON form of a stage: its metadata and the parsed layout,
+// with every position in grid cells (multiply by cell_size for pixels)
+type Document struct {
+	Format   string   `json:"format"`
+	Version  int      `json:"version"`
+	CellSize int      `json:"cell_size"`
+	Metadata Metadata `json:"metadata"`
+	Layout
+}
+
+// EncodeJSON exports a layout and its metadata as an indented JSON document
+func EncodeJSON(layout *Layout) ([]byte, error) {
+	doc := Document{
+		Format:   DocumentFormat,
+		Version:  DocumentVersion,
+		CellSize: CellSize,
+		Metadata: layout.Metadata,
+		Layout:   *layout,
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// DecodeJSON imports a JSON document made by EncodeJSON (or an external tool)
+func DecodeJSON(data []byte) (*Layout, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		var e *Error
+		if errors.As(err, &e) { // An unknown enum name
+			return nil, ErrorList{e}
+		}
+		return nil, ErrorList{{Kind: ErrSyntax, Text: "JSON", Err: err}}
+	}
+	if doc.Format != DocumentFormat {
+		return nil, ErrorList{{Kind: ErrDocumentFormat, Text: doc.Format}}
+	}
+	if doc.Version != DocumentVersion {
+		return nil, ErrorList{{Kind: ErrVersion, Args: []any{doc.Version}}}
+	}
+	if err := doc.Layout.validate(); err != nil {
+		return nil, err
+	}
+	doc.Layout.Metadata = doc.Metadata
+	return &doc.Layout, nil
+}
+
+// validate checks that an imported layout fits its grid and uses valid
+// directions, reporting every problem as an ErrorList
+func (l *Layout) validate() error {
+	if l.Width <= 0 || l.Height <= 0 {
+		return ErrorList{{Kind: ErrSize, Args: []any{l.Width, l.Height}}}
+	}
+	var errs ErrorList
+	inside := func(x, y, w, h int) bool {
+		return x >= 0 && y >= 0 && w > 0 && h > 0 && x+w <= l.Width && y+h <= l.Height
+	}
+	for _, p := range l.Platforms {
+		if !inside(p.X, p.Y, p.Width, p.Height) {
+			errs.add(&Error{Kind: ErrPlatformBounds, Args: []any{p.X, p.Y, p.Width, p.Height}})
+		}
+		if wantDirection := p.Kind == PlatformConveyor && p.Unit == UnitNone; wantDirection != (p.Direction == 1 || p.Direction == -1) {
+			errs.add(&Error{Kind: ErrDirection, Args: []any{p.X, p.Y, p.Direction}})
+		}
+	}
+	cells := []Cell{l.BlueStart, l.RedStart}
+	cells = append(cells, l.Collectibles...)
+	for _, s := range l.Spikes {
+		cells = append(cells, Cell{s.X, s.Y})
+	}
+	for _, c := range l.Checkpoints {
+		cells = append(cells, Cell{c.X, c.Y})
+	}
+	for _, h := range l.Hazards {
+		cells = append(cells, Cell{h.X, h.Y})
+	}
+	for _, c := range cells {
+		if !inside(c.X, c.Y, 1, 1) {
+			errs.add(&Error{Kind: ErrCellBounds, Args: []any{c.X, c.Y}})
+		}
+	}
+	return errs.Err()
+}
+
+// Names used for enums in JSON
+var (
+	unitJSONNames           = []string{"", "blue", "red"}
+	platformKindJSONNames   = []string{"solid", "goal", "speed_up", "speed_down", "one_way", "reverse", "spring", "conveyor"}
+	spikeDirectionJSONNames = []string{"up", "down", "left", "right"}
+	hazardKindJSONNames     = []string{"walker", "falling_block", "laser", "turret"}
+)
+
+// marshalEnum returns the JSON name of an enum value
+func marshalEnum(names []string, value int) ([]byte, error) {
+	if value < 0 || value >= len(names) {
+		return nil, &Error{Kind: ErrUnknownValue, Args: []any{strconv.Itoa(value), strings.Join(names, ", ")}}
+	}
+	return []byte(names[value]), nil
+}
+
+// unmarshalEnum returns the enum value of a JSON name
+func unmarshalEnum(names []string, text []byte) (int, error) {
+	value := slices.Index(names, string(text))
+	if value < 0 {
+		return 0, &Error{Kind: ErrUnknownValue, Args: []any{string(text), strings.Join(names, ", ")}}
+	}
+	return value, nil
+}
+
+func (u Unit) MarshalText() ([]byte, error) {
+	return marshalEnum(unitJSONNames, int(u))
+}
+
+func (u *Unit) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(unitJSONNames, text)
+	*u = Unit(value)
+	return err
+}
+
+func (k PlatformKind) MarshalText() ([]byte, error) {
+	return marshalEnum(platformKindJSONNames, int(k))
+}
+
+func (k *PlatformKind) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(platformKindJSONNames, text)
+	*k = PlatformKind(value)
+	return err
+}
+
+func (d SpikeDirection) MarshalText() ([]byte, error) {
+	return marshalEnum(spikeDirectionJSONNames, int(d))
+}
+
+func (d *SpikeDirection) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(spikeDirectionJSONNames, text)
+	*d = SpikeDirection(value)
+	return err
+}
+
+func (k HazardKind) MarshalText() ([]byte, error) {
+	return marshalEnum(hazardKindJSONNames, int(k))
+}
+
+func (k *HazardKind) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum(hazardKindJSONNames, text)
+	*k = HazardKind(value)
+	return err
+}
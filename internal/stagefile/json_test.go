@@ -0,0 +1,48 @@
+package stagefile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	t.Run("ASCII→JSON→ASCIIで元に戻る", func(t *testing.T) {
+		for index := 0; index <= lastStage; index++ {
+			lines := readStageLines(t, index)
+			layout := parseLines(t, lines, Options{})
+			layout.Metadata.Stage = index
+			data, err := EncodeJSON(layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := DecodeJSON(data)
+			if err != nil {
+				t.Fatalf("ステージ%d: %v", index, err)
+			}
+			if decoded.Metadata.Stage != index {
+				t.Errorf("ステージ番号が保存されるべき: %d", decoded.Metadata.Stage)
+			}
+			if !reflect.DeepEqual(decoded, layout) {
+				t.Errorf("ステージ%dのレイアウトがJSONから復元されるべき", index)
+			}
+			if got := FormatFile(decoded); !reflect.DeepEqual(got, lines) {
+				t.Errorf("ステージ%dのASCIIが元のファイルと一致しない:\n%s", index, strings.Join(got, "\n"))
+			}
+		}
+	})
+
+	t.Run("不正なファイルはエラーになる", func(t *testing.T) {
+		for name, data := range map[string]string{
+			"壊れたJSON":  `{"format":`,
+			"別の形式":     `{"format":"other","version":1,"width":2,"height":2}`,
+			"新しいバージョン": `{"format":"egj2025-stage","version":99,"width":2,"height":2}`,
+			"範囲外の足場":   `{"format":"egj2025-stage","version":1,"width":2,"height":2,"platforms":[{"x":1,"y":0,"width":2,"height":1,"kind":"solid"}]}`,
+			"不明な種類":    `{"format":"egj2025-stage","version":1,"width":2,"height":2,"platforms":[{"x":0,"y":0,"width":1,"height":1,"kind":"lava"}]}`,
+		} {
+			if _, err := DecodeJSON([]byte(data)); err == nil {
+				t.Errorf("%sはエラーになるべき", name)
+			}
+		}
+	})
+}
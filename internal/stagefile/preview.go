@@ -0,0 +1,301 @@
+package stagefile
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+)
+
+// Colors are the colours a stage is drawn with
+type Colors struct {
+	Ground    color.RGBA // Details of hazards
+	Platform  color.RGBA
+	Goal      color.RGBA
+	Spike     color.RGBA
+	SpeedUp   color.RGBA
+	SpeedDown color.RGBA
+	OneWay    color.RGBA
+	Reverse   color.RGBA
+	Spring    color.RGBA
+	Conveyor  color.RGBA
+	Star      color.RGBA
+	Hazard    color.RGBA
+	BlueUnit  color.RGBA
+	RedUnit   color.RGBA
+}
+
+// DefaultColors is the game's default palette
+var DefaultColors = Colors{
+	Ground:    color.RGBA{100, 100, 100, 255},
+	Platform:  color.RGBA{150, 150, 150, 255},
+	Goal:      color.RGBA{255, 255, 0, 255},
+	Spike:     color.RGBA{255, 0, 0, 255},
+	SpeedUp:   color.RGBA{0, 255, 100, 255},
+	SpeedDown: color.RGBA{255, 100, 0, 255},
+	OneWay:    color.RGBA{190, 140, 90, 255},
+	Reverse:   color.RGBA{180, 100, 255, 255},
+	Spring:    color.RGBA{255, 170, 200, 255},
+	Conveyor:  color.RGBA{90, 110, 140, 255},
+	Star:      color.RGBA{255, 215, 0, 255},
+	Hazard:    color.RGBA{200, 40, 80, 255},
+	BlueUnit:  color.RGBA{0, 100, 255, 255},
+	RedUnit:   color.RGBA{255, 100, 100, 255},
+}
+
+// Preview colours that are not part of the palette
+var (
+	previewBackground = color.RGBA{0, 0, 0, 255}       // The game draws stages on black
+	previewPattern    = color.RGBA{0, 0, 0, 140}       // Hatching of unit-only platforms, as in the game
+	previewMarker     = color.RGBA{255, 255, 255, 255} // Outline of spawn markers
+	sheetBackground   = color.RGBA{20, 30, 50, 255}    // Stage select background
+)
+
+// canvas draws shapes given in game pixels onto an image scaled by scale
+type canvas struct {
+	img   *image.RGBA
+	scale float64
+}
+
+// fill sets every pixel whose centre is inside the shape, within the given game-pixel bounds
+func (c canvas) fill(left, top, right, bottom float64, col color.RGBA, inside func(x, y float64) bool) {
+	x0, y0 := int(math.Floor(left*c.scale)), int(math.Floor(top*c.scale))
+	x1, y1 := int(math.Ceil(right*c.scale)), int(math.Ceil(bottom*c.scale))
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			x, y := (float64(px)+0.5)/c.scale, (float64(py)+0.5)/c.scale
+			if !(image.Point{px, py}.In(c.img.Rect)) || !inside(x, y) {
+				continue
+			}
+			if col.A == 255 {
+				c.img.SetRGBA(px, py, col)
+				continue
+			}
+			// Blend translucent colours over what is already drawn
+			dst := c.img.RGBAAt(px, py)
+			a := uint32(col.A)
+			blend := func(s, d uint8) uint8 { return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255) }
+			c.img.SetRGBA(px, py, color.RGBA{blend(col.R, dst.R), blend(col.G, dst.G), blend(col.B, dst.B), 255})
+		}
+	}
+}
+
+func (c canvas) rect(x, y, w, h float64, col color.RGBA) {
+	c.fill(x, y, x+w, y+h, col, func(float64, float64) bool { return true })
+}
+
+func (c canvas) circle(cx, cy, r float64, col color.RGBA) {
+	c.fill(cx-r, cy-r, cx+r, cy+r, col, func(x, y float64) bool {
+		return (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r
+	})
+}
+
+// triangle fills the triangle with the given corners
+func (c canvas) triangle(p [3][2]float64, col color.RGBA) {
+	side := func(a, b [2]float64, x, y float64) float64 {
+		return (b[0]-a[0])*(y-a[1]) - (b[1]-a[1])*(x-a[0])
+	}
+	left, right := min(p[0][0], p[1][0], p[2][0]), max(p[0][0], p[1][0], p[2][0])
+	top, bottom := min(p[0][1], p[1][1], p[2][1]), max(p[0][1], p[1][1], p[2][1])
+	c.fill(left, top, right, bottom, col, func(x, y float64) bool {
+		d0, d1, d2 := side(p[0], p[1], x, y), side(p[1], p[2], x, y), side(p[2], p[0], x, y)
+		return (d0 >= 0 && d1 >= 0 && d2 >= 0) || (d0 <= 0 && d1 <= 0 && d2 <= 0)
+	})
+}
+
+// line draws a line of the given width between two points
+func (c canvas) line(x0, y0, x1, y1, width float64, col color.RGBA) {
+	dx, dy := x1-x0, y1-y0
+	length2 := dx*dx + dy*dy
+	c.fill(min(x0, x1)-width, min(y0, y1)-width, max(x0, x1)+width, max(y0, y1)+width, col, func(x, y float64) bool {
+		t := 0.0
+		if length2 > 0 {
+			t = max(0, min(1, ((x-x0)*dx+(y-y0)*dy)/length2))
+		}
+		px, py := x0+t*dx-x, y0+t*dy-y
+		return px*px+py*py <= width*width/4
+	})
+}
+
+// unitColor returns the colour of a unit's elements
+func (colors Colors) unitColor(unit Unit) color.RGBA {
+	if unit == UnitRed {
+		return colors.RedUnit
+	}
+	return colors.BlueUnit
+}
+
+// platformColor returns the fill colour of a platform, as the game chooses it
+func (colors Colors) platformColor(p Platform) color.RGBA {
+	switch {
+	case p.Kind == PlatformGoal:
+		return colors.Goal
+	case p.Unit != UnitNone:
+		return colors.unitColor(p.Unit)
+	}
+	return []color.RGBA{
+		PlatformSolid:     colors.Platform,
+		PlatformSpeedUp:   colors.SpeedUp,
+		PlatformSpeedDown: colors.SpeedDown,
+		PlatformOneWay:    colors.OneWay,
+		PlatformReverse:   colors.Reverse,
+		PlatformSpring:    colors.Spring,
+		PlatformConveyor:  colors.Conveyor,
+	}[p.Kind]
+}
+
+// Render draws a stage the way the game shows it before the units start
+// moving, with the units at their spawn positions. scale is the size of the
+// image relative to the game screen (1 = CellSize pixels per cell).
+func Render(layout *Layout, colors Colors, scale float64) *image.RGBA {
+	const cell = float64(CellSize)
+	width := int(math.Ceil(float64(layout.Width) * cell * scale))
+	height := int(math.Ceil(float64(layout.Height) * cell * scale))
+	c := canvas{img: image.NewRGBA(image.Rect(0, 0, width, height)), scale: scale}
+	draw.Draw(c.img, c.img.Rect, image.NewUniform(previewBackground), image.Point{}, draw.Src)
+
+	for _, p := range layout.Platforms {
+		x, y, w, h := float64(p.X)*cell, float64(p.Y)*cell, float64(p.Width)*cell, float64(p.Height)*cell
+		col := colors.platformColor(p)
+		if p.Kind == PlatformOneWay {
+			// A thin plank at the top of each row of cells
+			for row := y; row < y+h; row += cell {
+				c.rect(x, row, w, cell/4, col)
+			}
+			continue
+		}
+		c.rect(x, y, w, h, col)
+		if p.Unit != UnitNone {
+			// Unit-only platforms are hatched and outlined in the unit's colour
+			if p.Kind != PlatformGoal {
+				for hx := x; hx < x+w; hx += cell / 2 {
+					c.line(hx, y+h, hx+cell/2, y, 2, previewPattern)
+				}
+			}
+			unit := colors.unitColor(p.Unit)
+			c.rect(x, y, w, 2, unit)
+			c.rect(x, y+h-2, w, 2, unit)
+			c.rect(x, y, 2, h, unit)
+			c.rect(x+w-2, y, 2, h, unit)
+		}
+	}
+
+	for _, s := range layout.Spikes {
+		x, y := float64(s.X)*cell, float64(s.Y)*cell
+		corners := map[SpikeDirection][3][2]float64{
+			SpikeUp:    {{x, y + cell}, {x + cell, y + cell}, {x + cell/2, y}},
+			SpikeDown:  {{x, y}, {x + cell, y}, {x + cell/2, y + cell}},
+			SpikeLeft:  {{x + cell, y}, {x + cell, y + cell}, {x, y + cell/2}},
+			SpikeRight: {{x, y}, {x, y + cell}, {x + cell, y + cell/2}},
+		}[s.Direction]
+		col := colors.Spike
+		if s.Unit != UnitNone {
+			col = colors.unitColor(s.Unit)
+		}
+		c.triangle(corners, col)
+	}
+
+	for _, cp := range layout.Checkpoints {
+		x, y := float64(cp.X)*cell, float64(cp.Y)*cell
+		c.line(x+5, y+1, x+5, y+cell, 2, colors.Platform)
+		c.triangle([3][2]float64{{x + 6, y + 1}, {x + 18, y + 5}, {x + 6, y + 10}}, colors.unitColor(cp.Unit))
+	}
+
+	for _, star := range layout.Collectibles {
+		cx, cy := (float64(star.X)+0.5)*cell, (float64(star.Y)+0.5)*cell
+		c.triangle([3][2]float64{{cx - 7, cy}, {cx, cy - 8}, {cx + 7, cy}}, colors.Star)
+		c.triangle([3][2]float64{{cx - 7, cy}, {cx, cy + 8}, {cx + 7, cy}}, colors.Star)
+	}
+
+	for _, hz := range layout.Hazards {
+		x, y := float64(hz.X)*cell, float64(hz.Y)*cell
+		switch hz.Kind {
+		case HazardWalker:
+			c.rect(x+2, y+6, cell-4, cell-8, colors.Hazard)
+		case HazardFallingBlock:
+			c.rect(x, y, cell, cell, colors.Hazard)
+			c.line(x+3, y+3, x+cell-3, y+cell-3, 2, colors.Ground)
+			c.line(x+cell-3, y+3, x+3, y+cell-3, 2, colors.Ground)
+		case HazardLaser:
+			c.rect(x+3, y, cell-6, cell-4, colors.Hazard)
+		case HazardTurret:
+			c.circle(x+cell/2, y+cell/2, 7, colors.Hazard)
+			barrelX := x + cell/2
+			if hz.Direction < 0 {
+				barrelX = x
+			}
+			c.rect(barrelX, y+cell/2-2, cell/2, 4, colors.Ground)
+		}
+	}
+
+	// Spawn markers: the units, outlined so they stand out from same-coloured platforms
+	for _, start := range []struct {
+		cell Cell
+		col  color.RGBA
+	}{{layout.BlueStart, colors.BlueUnit}, {layout.RedStart, colors.RedUnit}} {
+		cx, cy := (float64(start.cell.X)+0.5)*cell, (float64(start.cell.Y)+0.5)*cell
+		c.circle(cx, cy, cell/2, previewMarker)
+		c.circle(cx, cy, cell/2-2, start.col)
+	}
+	return c.img
+}
+
+// Contact sheet layout in pixels
+const (
+	sheetGap        = 16 // Space between and around previews
+	sheetLabelScale = 4  // Pixels per dot of the label digits
+)
+
+// ContactSheet arranges stage previews in a grid of the given number of
+// columns, each labelled with its stage number in the top-left corner
+func ContactSheet(previews []image.Image, numbers []int, columns int) *image.RGBA {
+	if len(previews) == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+	columns = max(1, min(columns, len(previews)))
+	rows := (len(previews) + columns - 1) / columns
+	cellW, cellH := 0, 0
+	for _, preview := range previews {
+		cellW = max(cellW, preview.Bounds().Dx())
+		cellH = max(cellH, preview.Bounds().Dy())
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*(cellW+sheetGap)+sheetGap, rows*(cellH+sheetGap)+sheetGap))
+	draw.Draw(sheet, sheet.Rect, image.NewUniform(sheetBackground), image.Point{}, draw.Src)
+	for i, preview := range previews {
+		x := sheetGap + i%columns*(cellW+sheetGap)
+		y := sheetGap + i/columns*(cellH+sheetGap)
+		draw.Draw(sheet, preview.Bounds().Sub(preview.Bounds().Min).Add(image.Pt(x, y)), preview, preview.Bounds().Min, draw.Src)
+		if i < len(numbers) {
+			drawLabel(sheet, x+sheetLabelScale, y+sheetLabelScale, numbers[i])
+		}
+	}
+	return sheet
+}
+
+// labelDigits are 3x5 dot patterns of the digits 0-9, one row of three bits per entry
+var labelDigits = [10][5]uint8{
+	{7, 5, 5, 5, 7}, {2, 6, 2, 2, 7}, {7, 1, 7, 4, 7}, {7, 1, 7, 1, 7}, {5, 5, 7, 1, 1},
+	{7, 4, 7, 1, 7}, {7, 4, 7, 5, 7}, {7, 1, 1, 1, 1}, {7, 5, 7, 5, 7}, {7, 5, 7, 1, 7},
+}
+
+// drawLabel draws a stage number in white on a dark box at (x, y)
+func drawLabel(img *image.RGBA, x, y, number int) {
+	digits := []byte(strconv.Itoa(max(number, 0)))
+	const dot = sheetLabelScale
+	box := image.Rect(x, y, x+dot*(4*len(digits)+1), y+dot*7)
+	draw.Draw(img, box, image.NewUniform(color.RGBA{0, 0, 0, 255}), image.Point{}, draw.Src)
+	for i, digit := range digits {
+		pattern := labelDigits[digit-'0']
+		for row := range 5 {
+			for col := range 3 {
+				if pattern[row]&(4>>col) == 0 {
+					continue
+				}
+				dx, dy := x+dot*(1+4*i+col), y+dot*(1+row)
+				draw.Draw(img, image.Rect(dx, dy, dx+dot, dy+dot), image.NewUniform(previewMarker), image.Point{}, draw.Src)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package stagefile
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	layout := parseLines(t, readStageLines(t, 1), Options{})
+
+	t.Run("縮小して描ける", func(t *testing.T) {
+		img := Render(layout, DefaultColors, 0.5)
+		if got := img.Bounds().Size(); got.X != layout.Width*CellSize/2 || got.Y != layout.Height*CellSize/2 {
+			t.Errorf("半分の大きさになるべき: %v", got)
+		}
+	})
+
+	t.Run("コンタクトシートは全ステージを格子状に並べる", func(t *testing.T) {
+		var previews []image.Image
+		var numbers []int
+		for index := 0; index <= lastStage; index++ {
+			previews = append(previews, Render(parseLines(t, readStageLines(t, index), Options{}), DefaultColors, 0.5))
+			numbers = append(numbers, index)
+		}
+		sheet := ContactSheet(previews, numbers, 4)
+		// 4 columns and 3 rows of 400x310 previews with 16px gaps
+		if got := sheet.Bounds().Size(); got.X != 4*416+16 || got.Y != 3*326+16 {
+			t.Errorf("コンタクトシートの大きさ: %v", got)
+		}
+		if got := sheet.RGBAAt(16+2*416+200, 16+326+155); got != previews[6].(*image.RGBA).RGBAAt(200, 155) {
+			t.Errorf("7枚目のプレビューが3列目・2行目に置かれるべき: %v", got)
+		}
+	})
+}
@@ -0,0 +1,421 @@
+// Package stagefile parses the ASCII art stage format (stageNN.txt).
+//
+// A stage file may start with front matter, "key: value" lines such as
+// "title: Spring Tour", before the grid (see Metadata).
+//
+// The same parser is used by cmd/stagegen to generate stageN.go files and by
+// the game to load stage files at runtime, so both always agree on the layout.
+// It also converts stages to and from JSON, Tiled maps and share codes, and
+// renders PNG previews with the game's default colours.
+package stagefile
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unit identifies the unit a colour-coded element belongs to
+type Unit int
+
+const (
+	UnitNone Unit = iota // Shared by both units
+	UnitBlue
+	UnitRed
+)
+
+// PlatformKind identifies the gimmick of a rectangular area
+type PlatformKind int
+
+// Platform kinds in the order generated stage files list them
+const (
+	PlatformSolid PlatformKind = iota
+	PlatformGoal
+	PlatformSpeedUp
+	PlatformSpeedDown
+	PlatformOneWay
+	PlatformReverse
+	PlatformSpring
+	PlatformConveyor
+)
+
+// SpikeDirection is the direction a spike's tip points
+type SpikeDirection int
+
+const (
+	SpikeUp SpikeDirection = iota
+	SpikeDown
+	SpikeLeft
+	SpikeRight
+)
+
+// HazardKind identifies an enemy or hazard
+type HazardKind int
+
+const (
+	HazardWalker HazardKind = iota
+	HazardFallingBlock
+	HazardLaser
+	HazardTurret
+)
+
+// Cell is a position in grid coordinates
+type Cell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Platform is a rectangular area of one glyph in grid coordinates
+type Platform struct {
+	X         int          `json:"x"`
+	Y         int          `json:"y"`
+	Width     int          `json:"width"`
+	Height    int          `json:"height"`
+	Kind      PlatformKind `json:"kind"`
+	Unit      Unit         `json:"unit,omitempty"`      // Unit-only goals and platforms ('1'-'4')
+	Direction int          `json:"direction,omitempty"` // Conveyor push direction (1 = right, -1 = left)
+}
+
+// Spike is a single spike in grid coordinates
+type Spike struct {
+	X         int            `json:"x"`
+	Y         int            `json:"y"`
+	Direction SpikeDirection `json:"direction"`
+	Unit      Unit           `json:"unit,omitempty"` // Unit-only spikes ('5', '6')
+}
+
+// Checkpoint is a checkpoint flag in grid coordinates
+type Checkpoint struct {
+	X    int  `json:"x"`
+	Y    int  `json:"y"`
+	Unit Unit `json:"unit"`
+}
+
+// Hazard is an enemy or hazard in grid coordinates
+type Hazard struct {
+	X         int        `json:"x"`
+	Y         int        `json:"y"`
+	Kind      HazardKind `json:"kind"`
+	Direction int        `json:"direction,omitempty"` // Turret firing direction (1 = right, -1 = left)
+}
+
+// Layout is a parsed stage file.
+// Platforms and spikes are in the order generated stage files list them:
+// grouped by kind (shared elements before unit-only ones), then top-left first.
+type Layout struct {
+	Width        int          `json:"width"`  // Size of the ASCII art in cells
+	Height       int          `json:"height"` // Size of the ASCII art in cells
+	Platforms    []Platform   `json:"platforms"`
+	Spikes       []Spike      `json:"spikes"`
+	Checkpoints  []Checkpoint `json:"checkpoints"`
+	Collectibles []Cell       `json:"collectibles"`
+	Hazards      []Hazard     `json:"hazards"`
+	BlueStart    Cell         `json:"blue_start"`
+	RedStart     Cell         `json:"red_start"`
+	Metadata     Metadata     `json:"-"` // Front matter (in JSON, the document's metadata)
+}
+
+// platformGlyphs maps rectangular glyphs to their platform kind and unit
+var platformGlyphs = map[rune]Platform{
+	'O': {Kind: PlatformSolid},
+	'G': {Kind: PlatformGoal},
+	'u': {Kind: PlatformSpeedUp},
+	'd': {Kind: PlatformSpeedDown},
+	'-': {Kind: PlatformOneWay},
+	'X': {Kind: PlatformReverse},
+	's': {Kind: PlatformSpring},
+	'}': {Kind: PlatformConveyor, Direction: 1},
+	'{': {Kind: PlatformConveyor, Direction: -1},
+	'1': {Kind: PlatformGoal, Unit: UnitBlue},
+	'2': {Kind: PlatformGoal, Unit: UnitRed},
+	'3': {Kind: PlatformSolid, Unit: UnitBlue},
+	'4': {Kind: PlatformSolid, Unit: UnitRed},
+}
+
+// spikeGlyphs maps spike glyphs to their direction and unit
+var spikeGlyphs = map[rune]Spike{
+	'^': {Direction: SpikeUp},
+	'v': {Direction: SpikeDown},
+	'<': {Direction: SpikeLeft},
+	'>': {Direction: SpikeRight},
+	'5': {Unit: UnitBlue},
+	'6': {Unit: UnitRed},
+}
+
+// hazardGlyphs maps hazard glyphs to their kind
+var hazardGlyphs = map[rune]HazardKind{
+	'w': HazardWalker,
+	'#': HazardFallingBlock,
+	'!': HazardLaser,
+	'T': HazardTurret,
+}
+
+// Parse reads a stage in the ASCII art format
+func Parse(r io.Reader) (*Layout, error) {
+	return ParseWith(r, Options{})
+}
+
+// ParseWith reads a stage in the ASCII art format with the given options.
+// It reports every problem in the file at once, as an ErrorList.
+func ParseWith(r io.Reader, opts Options) (*Layout, error) {
+	var errs ErrorList
+	fail := func(e *Error) (*Layout, error) {
+		errs.add(e)
+		errs.SetFile(opts.File)
+		return nil, errs
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(&Error{Kind: ErrRead, Err: err})
+	}
+	if len(lines) == 0 {
+		return fail(&Error{Kind: ErrEmpty})
+	}
+	metadata, headerLines := parseFrontMatter(lines, &errs)
+	if lines = lines[headerLines:]; len(lines) == 0 {
+		return fail(&Error{Line: headerLines + 1, Kind: ErrNoGrid})
+	}
+
+	layout := &Layout{Height: len(lines), Metadata: metadata}
+	for _, line := range lines {
+		layout.Width = max(layout.Width, len(line))
+	}
+
+	// Track processed cells so each rectangle is only emitted once
+	processed := make([][]bool, layout.Height)
+	for i := range processed {
+		processed[i] = make([]bool, layout.Width)
+	}
+
+	for y, line := range lines {
+		for x, char := range line {
+			if processed[y][x] {
+				continue
+			}
+
+			if glyph, ok := platformGlyphs[char]; ok {
+				if opts.Decomposition == DecomposeMinimal {
+					continue // Split after the scan, once every cell of the glyph is known
+				}
+				platform := findRectangle(lines, processed, x, y, char)
+				platform.Kind, platform.Unit, platform.Direction = glyph.Kind, glyph.Unit, glyph.Direction
+				layout.Platforms = append(layout.Platforms, platform)
+				continue
+			}
+			if glyph, ok := spikeGlyphs[char]; ok {
+				layout.Spikes = append(layout.Spikes, Spike{X: x, Y: y, Direction: glyph.Direction, Unit: glyph.Unit})
+				processed[y][x] = true
+				continue
+			}
+			if kind, ok := hazardGlyphs[char]; ok {
+				hazard := Hazard{X: x, Y: y, Kind: kind}
+				if kind == HazardTurret {
+					// Turrets fire towards the centre of the stage
+					hazard.Direction = 1
+					if x >= len(line)/2 {
+						hazard.Direction = -1
+					}
+				}
+				layout.Hazards = append(layout.Hazards, hazard)
+				processed[y][x] = true
+				continue
+			}
+
+			switch char {
+			case 'L':
+				layout.BlueStart = Cell{x, y}
+			case 'R':
+				layout.RedStart = Cell{x, y}
+			case '*':
+				layout.Collectibles = append(layout.Collectibles, Cell{x, y})
+			case 'l':
+				layout.Checkpoints = append(layout.Checkpoints, Checkpoint{X: x, Y: y, Unit: UnitBlue})
+			case 'r':
+				layout.Checkpoints = append(layout.Checkpoints, Checkpoint{X: x, Y: y, Unit: UnitRed})
+			case '.':
+			default:
+				// x is a byte offset; report the column in characters
+				column := utf8.RuneCountInString(line[:x]) + 1
+				errs.add(&Error{Line: headerLines + y + 1, Column: column, Kind: ErrUnknownGlyph, Text: string(char)})
+			}
+			processed[y][x] = true
+		}
+	}
+
+	if len(errs) > 0 {
+		errs.SetFile(opts.File)
+		return nil, errs
+	}
+
+	if opts.Decomposition == DecomposeMinimal {
+		for char, glyph := range platformGlyphs {
+			for _, platform := range minimalRectangles(lines, layout.Width, byte(char)) {
+				platform.Kind, platform.Unit, platform.Direction = glyph.Kind, glyph.Unit, glyph.Direction
+				layout.Platforms = append(layout.Platforms, platform)
+			}
+		}
+		// Top-left first, like the greedy scan
+		slices.SortFunc(layout.Platforms, func(a, b Platform) int {
+			if a.Y != b.Y {
+				return a.Y - b.Y
+			}
+			return a.X - b.X
+		})
+	}
+
+	// Group by kind in the generated order; the sort is stable, so each group stays top-left first
+	slices.SortStableFunc(layout.Platforms, func(a, b Platform) int {
+		return platformGroup(a) - platformGroup(b)
+	})
+	slices.SortStableFunc(layout.Spikes, func(a, b Spike) int {
+		return spikeGroup(a) - spikeGroup(b)
+	})
+	return layout, nil
+}
+
+// platformGroup returns the position of a platform's group in generated stage files:
+// shared kinds first, then unit goals, then unit-only platforms
+func platformGroup(p Platform) int {
+	switch {
+	case p.Unit == UnitNone:
+		return int(p.Kind)
+	case p.Kind == PlatformGoal:
+		return int(PlatformConveyor) + 1
+	default:
+		return int(PlatformConveyor) + 2
+	}
+}
+
+// spikeGroup returns the position of a spike's group in generated stage files (shared spikes first)
+func spikeGroup(s Spike) int {
+	if s.Unit == UnitNone {
+		return 0
+	}
+	return 1
+}
+
+// findRectangle finds the largest rectangle of targetChar starting at (startX, startY)
+// by scanning right for the width, then down while whole rows match, and marks it processed
+func findRectangle(lines []string, processed [][]bool, startX, startY int, targetChar rune) Platform {
+	// Find the width by scanning right
+	width := 0
+	for x := startX; x < len(lines[startY]) && rune(lines[startY][x]) == targetChar; x++ {
+		width++
+	}
+
+	// Find the height by scanning down, ensuring all rows have the same width
+	height := 0
+	for y := startY; y < len(lines); y++ {
+		if len(lines[y]) < startX+width {
+			break
+		}
+		hasFullWidth := true
+		for x := startX; x < startX+width; x++ {
+			if rune(lines[y][x]) != targetChar {
+				hasFullWidth = false
+				break
+			}
+		}
+		if !hasFullWidth {
+			break
+		}
+		height++
+	}
+
+	// Mark all cells in this rectangle as processed
+	for y := startY; y < startY+height; y++ {
+		for x := startX; x < startX+width; x++ {
+			processed[y][x] = true
+		}
+	}
+
+	return Platform{X: startX, Y: startY, Width: width, Height: height}
+}
+
+// Format draws a layout back as the lines of a stage file.
+// Formatting a parsed stage file reproduces it (with rows padded to the same width).
+func Format(layout *Layout) []string {
+	grid := make([][]byte, layout.Height)
+	for y := range grid {
+		grid[y] = []byte(strings.Repeat(".", layout.Width))
+	}
+	set := func(x, y int, glyph rune) {
+		if y >= 0 && y < layout.Height && x >= 0 && x < layout.Width {
+			grid[y][x] = byte(glyph)
+		}
+	}
+
+	for _, p := range layout.Platforms {
+		glyph := platformGlyph(p)
+		for y := p.Y; y < p.Y+p.Height; y++ {
+			for x := p.X; x < p.X+p.Width; x++ {
+				set(x, y, glyph)
+			}
+		}
+	}
+	for _, s := range layout.Spikes {
+		set(s.X, s.Y, spikeGlyph(s))
+	}
+	for _, h := range layout.Hazards {
+		for glyph, kind := range hazardGlyphs {
+			if kind == h.Kind {
+				set(h.X, h.Y, glyph)
+			}
+		}
+	}
+	for _, c := range layout.Checkpoints {
+		set(c.X, c.Y, map[Unit]rune{UnitBlue: 'l', UnitRed: 'r'}[c.Unit])
+	}
+	for _, c := range layout.Collectibles {
+		set(c.X, c.Y, '*')
+	}
+	set(layout.BlueStart.X, layout.BlueStart.Y, 'L')
+	set(layout.RedStart.X, layout.RedStart.Y, 'R')
+
+	lines := make([]string, len(grid))
+	for y, row := range grid {
+		lines[y] = string(row)
+	}
+	return lines
+}
+
+// platformGlyph returns the glyph drawing a platform
+func platformGlyph(p Platform) rune {
+	for glyph, g := range platformGlyphs {
+		if g.Kind == p.Kind && g.Unit == p.Unit && g.Direction == p.Direction {
+			return glyph
+		}
+	}
+	return 'O'
+}
+
+// spikeGlyph returns the glyph drawing a spike
+func spikeGlyph(s Spike) rune {
+	for glyph, g := range spikeGlyphs {
+		if g.Unit == s.Unit && (s.Unit != UnitNone || g.Direction == s.Direction) {
+			return glyph
+		}
+	}
+	return '^'
+}
+
+// StageNumber extracts the stage number from a file name such as "stage01.txt"
+func StageNumber(filename string) (int, error) {
+	baseName := filepath.Base(filename)
+	numStr, ok := strings.CutPrefix(baseName, "stage")
+	numStr, hasExt := strings.CutSuffix(numStr, ".txt")
+	num, err := strconv.Atoi(numStr)
+	if !ok || !hasExt || err != nil {
+		return 0, &Error{File: filename, Kind: ErrFileName, Text: baseName}
+	}
+	return num, nil
+}
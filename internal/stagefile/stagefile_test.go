@@ -0,0 +1,56 @@
+package stagefile
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lastStage is the highest stage file in the repository root
+const lastStage = 10
+
+// readStageLines reads a stage file of the game from the repository root
+func readStageLines(t *testing.T, index int) []string {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("../../stage%02d.txt", index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+// parseLines parses the lines of a stage file with the given options
+func parseLines(t *testing.T, lines []string, opts Options) *Layout {
+	t.Helper()
+	layout, err := ParseWith(strings.NewReader(strings.Join(lines, "\n")), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return layout
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("すべての文字が書き出せる", func(t *testing.T) {
+		// One of each glyph surrounded by empty cells, so every glyph is its own element
+		lines := []string{strings.Repeat(".", 2*len(Glyphs)+1)}
+		row := []byte(lines[0])
+		for i := range len(Glyphs) {
+			row[2*i+1] = Glyphs[i]
+		}
+		lines = append(lines, string(row), lines[0])
+		if got := Format(parseLines(t, lines, Options{})); !reflect.DeepEqual(got, lines) {
+			t.Errorf("すべての文字が元に戻るべき:\n%s", strings.Join(got, "\n"))
+		}
+	})
+
+	t.Run("ステージファイルがそのまま書き戻される", func(t *testing.T) {
+		for index := 0; index <= lastStage; index++ {
+			lines := readStageLines(t, index)
+			if got := FormatFile(parseLines(t, lines, Options{})); !reflect.DeepEqual(got, lines) {
+				t.Errorf("ステージ%dが元のファイルと一致しない:\n%s", index, strings.Join(got, "\n"))
+			}
+		}
+	})
+}
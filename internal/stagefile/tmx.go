@@ -0,0 +1,256 @@
+package stagefile
+
+import (
+	"encoding/xml"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Glyphs lists every glyph except '.' (empty) in tileset order: tile N of the
+// TMX tileset draws Glyphs[N]
+const Glyphs = "OGud-Xs}{^v<>123456*wlr#!TLR"
+
+// TMX (Tiled map) structure, limited to what the stage format needs:
+// one tile layer in CSV encoding and one embedded tileset
+type tmxMap struct {
+	XMLName      xml.Name       `xml:"map"`
+	Version      string         `xml:"version,attr"`
+	Orientation  string         `xml:"orientation,attr"`
+	RenderOrder  string         `xml:"renderorder,attr"`
+	Width        int            `xml:"width,attr"`
+	Height       int            `xml:"height,attr"`
+	TileWidth    int            `xml:"tilewidth,attr"`
+	TileHeight   int            `xml:"tileheight,attr"`
+	Infinite     int            `xml:"infinite,attr"`
+	NextLayerID  int            `xml:"nextlayerid,attr"`
+	NextObjectID int            `xml:"nextobjectid,attr"`
+	Properties   *tmxProperties `xml:"properties"` // Front matter of the stage file (nil = none)
+	Tileset      []tmxTileset   `xml:"tileset"`
+	Layers       []tmxLayer     `xml:"layer"`
+}
+
+type tmxTileset struct {
+	FirstGID   int       `xml:"firstgid,attr"`
+	Name       string    `xml:"name,attr,omitempty"`
+	TileWidth  int       `xml:"tilewidth,attr,omitempty"`
+	TileHeight int       `xml:"tileheight,attr,omitempty"`
+	TileCount  int       `xml:"tilecount,attr,omitempty"`
+	Columns    int       `xml:"columns,attr,omitempty"`
+	Source     string    `xml:"source,attr,omitempty"`
+	Image      *tmxImage `xml:"image"`
+	Tiles      []tmxTile `xml:"tile"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type tmxTile struct {
+	ID         int           `xml:"id,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+type tmxProperties struct {
+	Property []tmxProperty `xml:"property"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type tmxLayer struct {
+	ID     int     `xml:"id,attr"`
+	Name   string  `xml:"name,attr"`
+	Width  int     `xml:"width,attr"`
+	Height int     `xml:"height,attr"`
+	Data   tmxData `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding    string `xml:"encoding,attr,omitempty"`
+	Compression string `xml:"compression,attr,omitempty"`
+	Content     string `xml:",chardata"`
+}
+
+const (
+	tmxGlyphProperty = "glyph"    // Tile property naming the glyph a tile stands for
+	tmxGIDMask       = 0x0FFFFFFF // Strips Tiled's flip and rotation flags from a GID
+)
+
+// EncodeTMX exports the lines of a stage file as a Tiled map. The embedded
+// tileset uses tilesetImage, an image of len(Glyphs) tiles in one row
+// (see Glyphs), and names each tile's glyph in a "glyph" property.
+// Front matter becomes custom properties of the map.
+func EncodeTMX(lines []string, tilesetImage string) ([]byte, error) {
+	var errs ErrorList
+	metadata, headerLines := parseFrontMatter(lines, &errs)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	lines = lines[headerLines:]
+	var properties []tmxProperty
+	for _, line := range FormatFrontMatter(metadata) {
+		key, value, _ := strings.Cut(line, ": ")
+		if last := len(properties) - 1; last >= 0 && properties[last].Name == key {
+			properties[last].Value += "\n" + value // Tutorial lines as one multi-line property
+			continue
+		}
+		properties = append(properties, tmxProperty{Name: key, Value: value})
+	}
+
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+
+	tileset := tmxTileset{
+		FirstGID:   1,
+		Name:       "stage",
+		TileWidth:  CellSize,
+		TileHeight: CellSize,
+		TileCount:  len(Glyphs),
+		Columns:    len(Glyphs),
+		Image:      &tmxImage{Source: tilesetImage, Width: len(Glyphs) * CellSize, Height: CellSize},
+	}
+	for id, glyph := range Glyphs {
+		tileset.Tiles = append(tileset.Tiles, tmxTile{ID: id, Properties: []tmxProperty{{Name: tmxGlyphProperty, Value: string(glyph)}}})
+	}
+
+	var csv strings.Builder
+	csv.WriteString("\n")
+	for y, line := range lines {
+		cells := []rune(line)
+		for x := range width {
+			gid := 0
+			if x < len(cells) {
+				if id := strings.IndexRune(Glyphs, cells[x]); id >= 0 {
+					gid = tileset.FirstGID + id
+				} else if cells[x] != '.' {
+					errs.add(&Error{Line: headerLines + y + 1, Column: x + 1, Kind: ErrUnknownGlyph, Text: string(cells[x])})
+				}
+			}
+			csv.WriteString(strconv.Itoa(gid))
+			if x < width-1 || y < len(lines)-1 {
+				csv.WriteString(",")
+			}
+		}
+		csv.WriteString("\n")
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	m := tmxMap{
+		Version:      "1.10",
+		Orientation:  "orthogonal",
+		RenderOrder:  "right-down",
+		Width:        width,
+		Height:       len(lines),
+		TileWidth:    CellSize,
+		TileHeight:   CellSize,
+		NextLayerID:  2,
+		NextObjectID: 1,
+		Tileset:      []tmxTileset{tileset},
+		Layers:       []tmxLayer{{ID: 1, Name: "stage", Width: width, Height: len(lines), Data: tmxData{Encoding: "csv", Content: csv.String()}}},
+	}
+	if len(properties) > 0 {
+		m.Properties = &tmxProperties{properties}
+	}
+	data, err := xml.MarshalIndent(m, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// DecodeTMX imports a Tiled map back into the lines of a stage file.
+// Tiles are mapped to glyphs by their "glyph" property, falling back to the
+// tile's position in Glyphs, so the tileset may be edited or reordered in Tiled.
+// Map properties named after front matter keys become front matter; other
+// properties are ignored.
+func DecodeTMX(data []byte) ([]string, error) {
+	var m tmxMap
+	if err := xml.Unmarshal(data, &m); err != nil {
+		return nil, ErrorList{{Kind: ErrSyntax, Text: "TMX", Err: err}}
+	}
+	if len(m.Layers) != 1 {
+		return nil, ErrorList{{Kind: ErrTMXLayers, Args: []any{len(m.Layers)}}}
+	}
+	if len(m.Tileset) != 1 || m.Tileset[0].Source != "" {
+		return nil, ErrorList{{Kind: ErrTMXTileset}}
+	}
+	layer := m.Layers[0]
+	if layer.Data.Encoding != "csv" || layer.Data.Compression != "" {
+		return nil, ErrorList{{Kind: ErrTMXEncoding, Text: layer.Data.Encoding}}
+	}
+
+	tileset := m.Tileset[0]
+	glyphs := make(map[int]byte) // GID -> glyph
+	for id := range len(Glyphs) {
+		glyphs[tileset.FirstGID+id] = Glyphs[id]
+	}
+	for _, tile := range tileset.Tiles {
+		for _, property := range tile.Properties {
+			if property.Name == tmxGlyphProperty && len(property.Value) == 1 {
+				glyphs[tileset.FirstGID+tile.ID] = property.Value[0]
+			}
+		}
+	}
+
+	fields := strings.FieldsFunc(layer.Data.Content, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+	})
+	if len(fields) != layer.Width*layer.Height {
+		return nil, ErrorList{{Kind: ErrTMXTileCount, Args: []any{layer.Width, layer.Height, len(fields)}}}
+	}
+	var header []string
+	var properties []tmxProperty
+	if m.Properties != nil {
+		properties = m.Properties.Property
+	}
+	for _, property := range properties {
+		if !slices.Contains(frontMatterKeys, property.Name) {
+			continue
+		}
+		for _, value := range strings.Split(property.Value, "\n") {
+			header = append(header, property.Name+": "+value)
+		}
+	}
+	if len(header) > 0 {
+		header = append(header, "")
+	}
+
+	var errs ErrorList
+	lines := make([]string, layer.Height)
+	for y := range lines {
+		row := make([]byte, layer.Width)
+		for x := range row {
+			gid, err := strconv.ParseUint(fields[y*layer.Width+x], 10, 32)
+			if err != nil {
+				errs.add(&Error{Kind: ErrTMXTileID, Args: []any{x, y}, Err: err})
+				continue
+			}
+			gid &= tmxGIDMask
+			if gid == 0 {
+				row[x] = '.'
+				continue
+			}
+			glyph, ok := glyphs[int(gid)]
+			if !ok {
+				errs.add(&Error{Kind: ErrTMXUnknownTile, Args: []any{gid, x, y}})
+				continue
+			}
+			row[x] = glyph
+		}
+		lines[y] = string(row)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return append(header, lines...), nil
+}
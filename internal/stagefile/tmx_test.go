@@ -0,0 +1,39 @@
+package stagefile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTMX(t *testing.T) {
+	t.Run("ASCII→TMX→ASCIIで元に戻る", func(t *testing.T) {
+		for index := 0; index <= lastStage; index++ {
+			lines := readStageLines(t, index)
+			data, err := EncodeTMX(lines, "stage_tiles.png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeTMX(data)
+			if err != nil {
+				t.Fatalf("ステージ%d: %v", index, err)
+			}
+			if !reflect.DeepEqual(got, lines) {
+				t.Errorf("ステージ%dのASCIIがTMXから復元されるべき:\n%s", index, strings.Join(got, "\n"))
+			}
+		}
+	})
+
+	t.Run("不正なファイルはエラーになる", func(t *testing.T) {
+		for name, data := range map[string]string{
+			"壊れたXML":   `<map`,
+			"タイル数の不一致": `<map><tileset firstgid="1"/><layer width="2" height="2"><data encoding="csv">1,0,0</data></layer></map>`,
+			"未知のタイル":   `<map><tileset firstgid="1"/><layer width="1" height="1"><data encoding="csv">999</data></layer></map>`,
+			"base64":   `<map><tileset firstgid="1"/><layer width="1" height="1"><data encoding="base64">AAAA</data></layer></map>`,
+		} {
+			if _, err := DecodeTMX([]byte(data)); err == nil {
+				t.Errorf("%sはエラーになるべき", name)
+			}
+		}
+	})
+}
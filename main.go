@@ -2,15 +2,20 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"image/color"
 	"log"
+	"os"
+	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/pankona/egj2025/internal/stagefile"
 )
 
 const (
@@ -22,6 +27,10 @@ const (
 	GRAVITY       = 0.35
 	JUMP_STRENGTH = 5.9 // Allows jumping over 2 platforms but not 3
 
+	// Gimmick tile constants
+	SPRING_STRENGTH = 8.6 // Launch velocity of spring pads (clears about 5 cells)
+	CONVEYOR_SPEED  = 0.8 // Horizontal velocity added by conveyors regardless of the unit's direction
+
 	// Unit constants
 	UnitSize = 20
 
@@ -36,12 +45,25 @@ const (
 
 	// Audio constants
 	SampleRate = 44100
+
+	// Settings keys (ignored by "press any key" prompts)
+	RendererKey           = ebiten.KeyF2
+	DebugOverlayKey       = ebiten.KeyF3
+	GameSpeedKey          = ebiten.KeyF5
+	NoSpikeDeathKey       = ebiten.KeyF6
+	InfiniteCheckpointKey = ebiten.KeyF7
+	PaletteKey            = ebiten.KeyF8
+	ReducedMotionKey      = ebiten.KeyF9
 )
 
 var (
 	// UI colors
 	WhiteColor = color.RGBA{255, 255, 255, 255}
 
+	// Unit colors (replaced by the selected palette)
+	BlueUnitColor = stagefile.DefaultColors.BlueUnit
+	RedUnitColor  = stagefile.DefaultColors.RedUnit
+
 	// Debug mode flag (initialized based on platform)
 	DebugMode bool
 )
@@ -55,27 +77,49 @@ const (
 	StateGameOver
 	StateCleared
 	StateAllCleared
+	StateStageSelect // Stage list with collected stars and challenges
 )
 
 type Unit struct {
-	X, Y      float64
-	VX, VY    float64
-	Direction int // 1 for right, -1 for left
-	Color     color.Color
-	OnGround  bool
-	Stopped   bool // Whether the unit has stopped at the goal
+	X, Y          float64
+	VX, VY        float64
+	Direction     int // 1 for right, -1 for left
+	Color         color.Color
+	OnGround      bool
+	Stopped       bool      // Whether the unit has stopped at the goal
+	Kind          UnitKind  // Which unit this is (blue or red)
+	SpeedModifier float64   // Speed modifier applied during the last physics step
+	OnReverse     bool      // Whether the unit stood on a reverse tile last step (it flips once per visit)
+	Events        *EventBus // Destination for simulation events (nil runs headless)
 }
 
 type Platform struct {
 	X, Y, Width, Height float64
 	Color               color.Color
-	IsGoal              bool    // Mark this platform as a goal zone
-	SpeedModifier       float64 // Speed multiplier when standing on this platform (1.0 = normal, >1.0 = faster, <1.0 = slower)
+	IsGoal              bool     // Mark this platform as a goal zone
+	SpeedModifier       float64  // Speed multiplier when standing on this platform (1.0 = normal, >1.0 = faster, <1.0 = slower)
+	OneWay              bool     // Jump-through platform that only collides when a unit falls onto its top edge
+	Reverse             bool     // Flips the direction of units that walk onto it
+	Bounce              float64  // Launch velocity given to units that touch its top (0 = not a spring)
+	Conveyor            float64  // Horizontal velocity added to units standing on it (+ = right, - = left)
+	Only                UnitKind // Unit this platform exists for (UnitNone = both units)
 }
 
 type Spike struct {
-	X, Y  float64
-	Color color.Color
+	X, Y      float64
+	Color     color.Color
+	Only      UnitKind       // Unit this spike is harmful to (UnitNone = both units)
+	Direction SpikeDirection // Direction the tip points (SpikeUp = floor spike)
+}
+
+// AffectsUnit reports whether the platform collides with (or is a goal for) the given unit
+func (p Platform) AffectsUnit(kind UnitKind) bool {
+	return p.Only == UnitNone || p.Only == kind
+}
+
+// AffectsUnit reports whether the spike is harmful to the given unit
+func (s Spike) AffectsUnit(kind UnitKind) bool {
+	return s.Only == UnitNone || s.Only == kind
 }
 
 // GridPosition represents a position in the grid coordinate system
@@ -93,12 +137,21 @@ type GridPlatform struct {
 	Position      GridPosition
 	Size          GridSize
 	IsGoal        bool
-	SpeedModifier float64 // Speed multiplier when standing on this platform
+	SpeedModifier float64  // Speed multiplier when standing on this platform
+	OneWay        bool     // Jump-through platform
+	Reverse       bool     // Direction-reversal tile
+	Bounce        float64  // Spring pad launch velocity
+	Conveyor      float64  // Conveyor velocity
+	Only          UnitKind // Unit the platform exists for (UnitNone = both)
 }
 
 type Stage struct {
-	Platforms []Platform
-	Spikes    []Spike
+	Platforms    []Platform
+	Spikes       []Spike
+	Checkpoints  []Checkpoint
+	Collectibles []Collectible
+	Hazards      []Hazard
+	Info         StageInfo // Title, author and hints from the stage file's front matter
 }
 
 type Game struct {
@@ -107,12 +160,47 @@ type Game struct {
 	Stage           *Stage
 	State           GameState
 	Font            *text.GoTextFace
+	MarkFont        *text.GoTextFace // Small font for letter marks drawn on units
 	StageLoader     *StageLoader
 	SoundManager    *SoundManager
-	BlinkCounter    int           // Counter for blinking text animation
-	BlinkVisible    bool          // Whether blinking text is currently visible
-	TransitionTimer int           // Timer for screen transitions
-	WhitePixel      *ebiten.Image // Reusable 1x1 white pixel for triangle rendering
+	Events          *EventBus       // Gameplay event stream shared by all subscribers
+	Stats           *StatsRecorder  // Per-stage statistics collected from events
+	Replay          *ReplayRecorder // Jump input recorder collected from events
+	Tick            int             // Simulation ticks elapsed in the current attempt
+	Particles       *ParticleSystem // Juice effects spawned from events
+	WorldLayer      *ebiten.Image   // Offscreen layer used while the screen shakes
+	BlinkCounter    int             // Counter for blinking text animation
+	BlinkVisible    bool            // Whether blinking text is currently visible
+	TransitionTimer int             // Timer for screen transitions
+	Notice          string          // Short message shown after changing a setting
+	NoticeTimer     int             // Frames left to show Notice
+	TitleCardTimer  int             // Frames left to show the stage title card
+	Assist          AssistOptions   // Accessibility assist options
+	Checkpoint      *RespawnPoint   // Active checkpoint pair (nil = restart from the stage spawn)
+	SelectedStage   int             // Stage highlighted on the stage select screen
+	stageSelectTop  int             // Stage shown in the top row of the stage select list
+	VectorRenderer  *VectorRenderer // Shape renderer (always available)
+	SpriteRenderer  *SpriteRenderer // Texture atlas renderer (nil if the atlas failed to load)
+	UseSprites      bool            // Whether to draw with sprites instead of shapes
+	StaticLayer     *ebiten.Image   // Cached rendering of the stage's platforms and spikes
+	DebugOverlay    bool            // Whether collision shapes are drawn over the world (F3)
+	Playback        *Replay         // Replay whose jumps replace player input on its stage (nil = manual play)
+	StageWatcher    *StageWatcher   // Reloads edited stage files while running (nil = not watching)
+
+	attemptAssisted     bool               // Whether assist options were used during the current attempt
+	tickAccumulator     float64            // Fractional simulation ticks owed (for slow game speeds)
+	pendingBlueJump     bool               // Jump input waiting for the next simulation tick
+	pendingRedJump      bool               // Jump input waiting for the next simulation tick
+	safePoints          []safePoint        // Recent safe positions for infinite checkpoint mode
+	checkpointProgress  checkpointProgress // Checkpoint flags touched since the last activation
+	collected           []bool             // Collectibles picked up in the current attempt (by index)
+	hazards             []Hazard           // Hazards simulated in the current attempt
+	projectiles         []Projectile       // Turret shots in flight
+	hazardStage         *Stage             // Stage the simulated hazards were copied from
+	frameStep           frameStepper       // Pause, step and rewind state for debugging
+	clipboardPaste      chan clipboardText // Stage code read from the clipboard (see pasteStageCode)
+	staticLayerStage    *Stage             // Stage currently rendered into StaticLayer
+	staticLayerRenderer Renderer           // Renderer used for StaticLayer
 }
 
 // Grid coordinate conversion functions
@@ -152,6 +240,11 @@ func GridPlatformToPlatform(gridPlatform GridPlatform, color color.Color) Platfo
 		Color:         color,
 		IsGoal:        gridPlatform.IsGoal,
 		SpeedModifier: gridPlatform.SpeedModifier,
+		OneWay:        gridPlatform.OneWay,
+		Reverse:       gridPlatform.Reverse,
+		Bounce:        gridPlatform.Bounce,
+		Conveyor:      gridPlatform.Conveyor,
+		Only:          gridPlatform.Only,
 	}
 }
 
@@ -171,13 +264,21 @@ func (u *Unit) checkCollisionWithPlatform(platform Platform) bool {
 }
 
 func (u *Unit) updatePhysics(stage *Stage) {
+	// Remember state from the previous step to detect transitions for events
+	wasOnGround := u.OnGround
+	wasStopped := u.Stopped
+
 	// Apply gravity
 	u.VY += GRAVITY
 
 	// Calculate current speed modifier based on platforms the unit is standing on
 	speedModifier := 1.0
+	var ground *Platform
 	if u.OnGround {
-		for _, platform := range stage.Platforms {
+		for i, platform := range stage.Platforms {
+			if !platform.AffectsUnit(u.Kind) {
+				continue
+			}
 			// Check if unit is standing on this platform
 			unitLeft := u.X
 			unitRight := u.X + UnitSize
@@ -191,14 +292,37 @@ func (u *Unit) updatePhysics(stage *Stage) {
 			if unitRight > platformLeft && unitLeft < platformRight &&
 				unitBottom >= platformTop && unitBottom <= platformTop+5 { // Small tolerance for "on platform"
 				speedModifier = platform.SpeedModifier
+				ground = &stage.Platforms[i]
 				break // Use the first matching platform's speed modifier
 			}
 		}
 	}
+	if speedModifier != 1.0 && speedModifier != u.SpeedModifier {
+		u.Events.Emit(Event{
+			Type:          EventEnteredSpeedZone,
+			Unit:          u.Kind,
+			X:             u.X,
+			Y:             u.Y,
+			SpeedModifier: speedModifier,
+		})
+	}
+	u.SpeedModifier = speedModifier
+
+	// Reverse tiles flip the direction once when a unit steps onto them
+	onReverse := ground != nil && ground.Reverse
+	if onReverse && !u.OnReverse && !u.Stopped {
+		u.Direction = -u.Direction
+		u.emitUnitEvent(EventReversed)
+	}
+	u.OnReverse = onReverse
 
 	// Apply horizontal movement only if not stopped
 	if !u.Stopped {
 		u.VX = SPEED * float64(u.Direction) * speedModifier
+		// Conveyors push units independently of their walking direction
+		if ground != nil {
+			u.VX += ground.Conveyor
+		}
 		// Update horizontal position
 		u.X += u.VX
 	} else {
@@ -209,10 +333,16 @@ func (u *Unit) updatePhysics(stage *Stage) {
 	if !u.Stopped {
 		if u.X <= 0 {
 			u.X = 0
-			u.Direction = 1 // Move right
+			if u.Direction != 1 {
+				u.Direction = 1 // Move right
+				u.emitUnitEvent(EventHitWall)
+			}
 		} else if u.X >= float64(ScreenWidth-UnitSize) {
 			u.X = float64(ScreenWidth - UnitSize)
-			u.Direction = -1 // Move left
+			if u.Direction != -1 {
+				u.Direction = -1 // Move left
+				u.emitUnitEvent(EventHitWall)
+			}
 		}
 	}
 
@@ -221,7 +351,13 @@ func (u *Unit) updatePhysics(stage *Stage) {
 
 	// Platform collision detection
 	u.OnGround = false
+	bounce := 0.0 // Strongest spring pad landed on in this step
 	for _, platform := range stage.Platforms {
+		// Platforms tied to the other unit's colour do not exist for this unit
+		if !platform.AffectsUnit(u.Kind) {
+			continue
+		}
+
 		unitLeft := u.X
 		unitRight := u.X + UnitSize
 		unitTop := u.Y
@@ -237,16 +373,20 @@ func (u *Unit) updatePhysics(stage *Stage) {
 		// Check if unit is vertically overlapping with platform
 		verticalOverlap := unitBottom > platformTop && unitTop < platformBottom
 
+		// One-way platforms only catch units whose bottom was above the top edge before this step
+		fromAbove := !platform.OneWay || unitBottom-u.VY <= platformTop
+
 		// Landing on top of platform (falling down) - skip goal platforms
-		if !platform.IsGoal && horizontalOverlap && u.VY > 0 && unitBottom > platformTop && unitTop < platformTop {
+		if !platform.IsGoal && horizontalOverlap && u.VY > 0 && unitBottom > platformTop && unitTop < platformTop && fromAbove {
 			u.Y = platformTop - UnitSize
 			u.VY = 0
 			u.OnGround = true
+			bounce = max(bounce, platform.Bounce)
 		}
 
-		// Horizontal collision detection - skip goal platforms
-		// Check horizontal collision for all non-goal platforms
-		if !platform.IsGoal && verticalOverlap && !u.Stopped {
+		// Horizontal collision detection - skip goal and one-way platforms
+		// Check horizontal collision for all solid platforms
+		if !platform.IsGoal && !platform.OneWay && verticalOverlap && !u.Stopped {
 			// Only check horizontal collision if unit is not on top of this platform
 			isOnTopOfPlatform := u.OnGround && unitBottom >= platformTop && unitBottom <= platformTop+5
 
@@ -255,16 +395,25 @@ func (u *Unit) updatePhysics(stage *Stage) {
 				if u.Direction > 0 && unitRight > platformLeft && unitLeft < platformLeft {
 					u.X = platformLeft - UnitSize
 					u.Direction = -1 // Reverse direction to left
+					u.emitUnitEvent(EventHitWall)
 				}
 				// Check collision from right side (moving left)
 				if u.Direction < 0 && unitLeft < platformRight && unitRight > platformRight {
 					u.X = platformRight
 					u.Direction = 1 // Reverse direction to right
+					u.emitUnitEvent(EventHitWall)
 				}
 			}
 		}
 	}
 
+	// Spring pads launch the unit once every platform has been resolved
+	if bounce > 0 {
+		u.VY = -bounce
+		u.OnGround = false
+		u.emitUnitEvent(EventBounced)
+	}
+
 	// Prevent falling through bottom of screen
 	if u.Y > float64(ScreenHeight) {
 		u.Y = float64(ScreenHeight - UnitSize)
@@ -272,10 +421,14 @@ func (u *Unit) updatePhysics(stage *Stage) {
 		u.VY = 0
 	}
 
+	if u.OnGround && !wasOnGround {
+		u.emitUnitEvent(EventLanded)
+	}
+
 	// Check if unit is completely inside goal platform area (for stopping and clearing)
 	if u.OnGround {
 		for _, platform := range stage.Platforms {
-			if platform.IsGoal {
+			if platform.IsGoal && platform.AffectsUnit(u.Kind) {
 				unitLeft := u.X
 				unitRight := u.X + UnitSize
 				unitTop := u.Y
@@ -294,59 +447,67 @@ func (u *Unit) updatePhysics(stage *Stage) {
 			}
 		}
 	}
+
+	if u.Stopped && !wasStopped {
+		u.emitUnitEvent(EventReachedGoal)
+	}
 }
 
-func (u *Unit) jump(soundManager *SoundManager) {
+func (u *Unit) jump() {
 	if u.OnGround {
 		u.VY = -JUMP_STRENGTH
 		u.OnGround = false
-		soundManager.PlayJumpSound()
+		u.emitUnitEvent(EventJumped)
 	}
 }
 
 func (g *Game) checkGameOver() bool {
-	// Check if either unit fell off the screen
-	if g.BlueUnit.Y > float64(ScreenHeight) || g.RedUnit.Y > float64(ScreenHeight) {
-		return true
-	}
+	return g.findDeadUnit() != nil
+}
 
-	// Check if either unit touched a spike
-	for _, spike := range g.Stage.Spikes {
-		if g.checkUnitSpikeCollision(g.BlueUnit, spike) || g.checkUnitSpikeCollision(g.RedUnit, spike) {
-			return true
+// findDeadUnit returns the first unit that fell off the screen or touched a spike or hazard, or nil
+func (g *Game) findDeadUnit() *Unit {
+	for _, unit := range []*Unit{g.BlueUnit, g.RedUnit} {
+		// Check if the unit fell off the screen
+		if unit.Y > float64(ScreenHeight) {
+			return unit
+		}
+
+		// Check if the unit touched a spike (harmless in no-spike-death mode) or a hazard
+		for _, spike := range g.Stage.Spikes {
+			if !g.Assist.NoSpikeDeath && g.checkUnitSpikeCollision(unit, spike) {
+				return unit
+			}
+		}
+		if g.hitByHazard(unit) {
+			return unit
 		}
 	}
 
-	return false
+	return nil
 }
 
 func (g *Game) checkUnitSpikeCollision(unit *Unit, spike Spike) bool {
-	unitLeft := unit.X
-	unitRight := unit.X + UnitSize
-	unitTop := unit.Y
-	unitBottom := unit.Y + UnitSize
-
-	spikeLeft := spike.X
-	spikeRight := spike.X + CellSize
-	spikeTop := spike.Y
-	spikeBottom := spike.Y + CellSize
+	// Spikes tied to the other unit's colour are harmless
+	if !spike.AffectsUnit(unit.Kind) {
+		return false
+	}
 
-	// Check if unit and spike overlap
-	return unitRight > spikeLeft && unitLeft < spikeRight &&
-		unitBottom > spikeTop && unitTop < spikeBottom
+	// Only the drawn triangle is harmful, not the empty corners of its cell
+	return rectIntersectsTriangle(unit.X, unit.Y, unit.X+UnitSize, unit.Y+UnitSize, spike.Triangle())
 }
 
 func (g *Game) checkCleared() bool {
-	// Check if both units are on goal platforms
+	// Check if both units are on goal platforms that accept them
 	blueOnGoal := false
 	redOnGoal := false
 
 	for _, platform := range g.Stage.Platforms {
 		if platform.IsGoal {
-			if g.BlueUnit.checkCollisionWithPlatform(platform) && g.BlueUnit.OnGround {
+			if platform.AffectsUnit(UnitBlue) && g.BlueUnit.checkCollisionWithPlatform(platform) && g.BlueUnit.OnGround {
 				blueOnGoal = true
 			}
-			if g.RedUnit.checkCollisionWithPlatform(platform) && g.RedUnit.OnGround {
+			if platform.AffectsUnit(UnitRed) && g.RedUnit.checkCollisionWithPlatform(platform) && g.RedUnit.OnGround {
 				redOnGoal = true
 			}
 		}
@@ -358,35 +519,152 @@ func (g *Game) checkCleared() bool {
 func (g *Game) resetGame() {
 	// Get starting positions from current stage
 	blueX, blueY, redX, redY := g.StageLoader.GetCurrentStageStartPositions()
+	blueDirection, redDirection := 1, -1
+
+	// Restart from the active checkpoint pair if there is one
+	respawn := g.Checkpoint != nil
+	if respawn {
+		blueX, blueY, blueDirection = g.Checkpoint.BlueX, g.Checkpoint.BlueY, g.Checkpoint.BlueDirection
+		redX, redY, redDirection = g.Checkpoint.RedX, g.Checkpoint.RedY, g.Checkpoint.RedDirection
+	}
+	g.checkpointProgress = checkpointProgress{}
 
 	// Reset units to stage-specific starting positions
 	g.BlueUnit.X = blueX
 	g.BlueUnit.Y = blueY
-	g.BlueUnit.VX = SPEED
+	g.BlueUnit.VX = SPEED * float64(blueDirection)
 	g.BlueUnit.VY = 0
-	g.BlueUnit.Direction = 1
+	g.BlueUnit.Direction = blueDirection
 	g.BlueUnit.OnGround = false
 	g.BlueUnit.Stopped = false
+	g.BlueUnit.OnReverse = false
+	g.BlueUnit.SpeedModifier = 1.0
 
 	g.RedUnit.X = redX
 	g.RedUnit.Y = redY
-	g.RedUnit.VX = -SPEED
+	g.RedUnit.VX = SPEED * float64(redDirection)
 	g.RedUnit.VY = 0
-	g.RedUnit.Direction = -1
+	g.RedUnit.Direction = redDirection
 	g.RedUnit.OnGround = false
 	g.RedUnit.Stopped = false
+	g.RedUnit.OnReverse = false
+	g.RedUnit.SpeedModifier = 1.0
 
 	// Reload current stage
 	g.Stage = g.StageLoader.GetCurrentStage()
+	g.resetCollectibles()
+	g.resetHazards()
 	g.State = StatePlaying
+	g.Particles.Clear()
+	if respawn {
+		g.respawnAtCheckpoint()
+	} else {
+		g.beginAttempt()
+	}
+}
+
+// beginAttempt restarts the tick counter and announces a new attempt to subscribers
+func (g *Game) beginAttempt() {
+	g.Tick = 0
+	g.tickAccumulator = 0
+	g.pendingBlueJump = false
+	g.pendingRedJump = false
+	g.safePoints = nil
+	g.frameStep.history.reset()
+	g.attemptAssisted = g.Assist.Active()
+	g.Events.SetContext(g.StageLoader.CurrentStageIndex, g.Tick)
+	g.Events.Emit(Event{Type: EventAttemptStarted})
+}
+
+// respawnAtCheckpoint goes on with the attempt from the active checkpoint,
+// keeping its tick counter so the clear time includes the play before the death
+func (g *Game) respawnAtCheckpoint() {
+	g.tickAccumulator = 0
+	g.pendingBlueJump = false
+	g.pendingRedJump = false
+	g.safePoints = nil
+	g.frameStep.history.reset()
+	g.attemptAssisted = g.attemptAssisted || g.Assist.Active()
+	g.Events.SetContext(g.StageLoader.CurrentStageIndex, g.Tick)
+	g.Events.Emit(Event{Type: EventRespawned})
+}
+
+// stepSimulation advances the gameplay simulation by one tick.
+// It only touches game state and emits events, so it can run headless.
+func (g *Game) stepSimulation(blueJump, redJump bool) {
+	g.Tick++
+	g.Events.SetContext(g.StageLoader.CurrentStageIndex, g.Tick)
+	g.Particles.Update(g.BlueUnit, g.RedUnit)
+
+	// Replays feed the recorded jumps instead of player input
+	if g.Playback != nil && g.Playback.Stage == g.StageLoader.CurrentStageIndex {
+		blueJump, redJump = g.Playback.JumpsAt(g.Tick)
+	}
+
+	if blueJump {
+		g.BlueUnit.jump()
+	}
+	if redJump {
+		g.RedUnit.jump()
+	}
+
+	// Update physics for both units
+	g.BlueUnit.updatePhysics(g.Stage)
+	g.RedUnit.updatePhysics(g.Stage)
+	g.updateHazards()
+
+	if g.Assist.NoSpikeDeath {
+		g.bounceOffSpikes()
+	}
+	g.updateCollectibles()
+
+	// Check game state conditions
+	if deadUnit := g.findDeadUnit(); deadUnit != nil {
+		g.State = StateGameOver
+		deadUnit.emitUnitEvent(EventDied)
+	} else if g.checkCleared() {
+		g.State = StateCleared
+		g.Events.Emit(Event{
+			Type:         EventStageCleared,
+			Assisted:     g.attemptAssisted,
+			Collected:    g.collectedCount(),
+			Collectibles: len(g.Stage.Collectibles),
+		})
+	} else {
+		g.updateCheckpoints()
+		g.recordSafePoint()
+	}
+}
+
+// retry restarts after a game over, from a recent safe point in infinite
+// checkpoint mode or from the stage start otherwise
+func (g *Game) retry() {
+	if g.Assist.InfiniteCheckpoint && g.restoreSafePoint() {
+		g.State = StatePlaying
+		g.attemptAssisted = true
+		g.Particles.Clear()
+		g.Events.SetContext(g.StageLoader.CurrentStageIndex, g.Tick)
+		g.Events.Emit(Event{Type: EventResumed})
+		return
+	}
+	g.resetGame()
 }
 
 func (g *Game) advanceToNextStageOrRestart() {
+	g.clearCheckpoint()
 	if g.StageLoader.NextStage() {
 		// Advanced to next stage, reset game with new stage
 		g.resetGame()
+		g.showTitleCard()
 		// Restart BGM when advancing to next stage
 		g.SoundManager.StartBGM()
+	} else if g.playingSharedStage() {
+		// A shared stage is played on its own; go back to the built-in stages
+		g.leaveSharedStage()
+		g.openStageSelect()
+		g.SoundManager.StopBGM()
+		g.SoundManager.PlayClearSound()
+		g.showNotice("Shared stage cleared")
 	} else {
 		// No more stages, go to all cleared state
 		g.State = StateAllCleared
@@ -403,6 +681,58 @@ func (g *Game) Update() error {
 		g.BlinkCounter = 0
 	}
 
+	// F2 switches between sprite and vector rendering
+	if inpututil.IsKeyJustPressed(RendererKey) {
+		g.UseSprites = !g.UseSprites
+	}
+
+	// F3 toggles the debug overlay
+	if inpututil.IsKeyJustPressed(DebugOverlayKey) {
+		g.toggleDebugOverlay()
+	}
+
+	// F5-F7 toggle assist options
+	g.handleAssistKeys(
+		inpututil.IsKeyJustPressed(GameSpeedKey),
+		inpututil.IsKeyJustPressed(NoSpikeDeathKey),
+		inpututil.IsKeyJustPressed(InfiniteCheckpointKey),
+	)
+
+	// F8 cycles colour palettes (including colour-blind friendly ones)
+	if inpututil.IsKeyJustPressed(PaletteKey) {
+		g.applyPalette(CurrentPaletteIndex + 1)
+	}
+
+	// F9 toggles reduced motion (disables particles and screen shake)
+	if g.Particles != nil && inpututil.IsKeyJustPressed(ReducedMotionKey) {
+		g.Particles.Config.ReducedMotion = !g.Particles.Config.ReducedMotion
+		g.Particles.Clear()
+		if g.Particles.Config.ReducedMotion {
+			g.showNotice("Reduced motion: ON")
+		} else {
+			g.showNotice("Reduced motion: OFF")
+		}
+	}
+
+	// F10, '.' and ',' pause, step and rewind the simulation (debug mode only)
+	g.updateFrameStep()
+	g.updateHotReload()
+	g.handleDroppedStagePack(ebiten.DroppedFiles())
+	g.updateStageCodePaste()
+
+	if g.NoticeTimer > 0 {
+		g.NoticeTimer--
+	}
+	if g.State == StatePlaying && g.TitleCardTimer > 0 {
+		g.TitleCardTimer--
+	}
+
+	// The simulation ticks the effects while playing; they keep animating on
+	// the game over and cleared overlays (but freeze while frame stepping)
+	if g.inAttempt() && g.State != StatePlaying && !g.frameStep.paused {
+		g.Particles.Update(g.BlueUnit, g.RedUnit)
+	}
+
 	// Ensure BGM is playing only during gameplay (NewInfiniteLoop handles the looping automatically)
 	if g.State == StatePlaying && g.SoundManager.bgmPlayer != nil && !g.SoundManager.bgmPlayer.IsPlaying() {
 		g.SoundManager.StartBGM()
@@ -413,7 +743,7 @@ func (g *Game) Update() error {
 		// Handle any key to start game
 		// Check keyboard input - use JustPressedKeys to avoid repeated triggers
 		keys := inpututil.AppendJustPressedKeys(nil)
-		if len(keys) > 0 {
+		if hasStartKey(keys) {
 			g.SoundManager.PlayShotSound()
 			g.State = StateTitleTransition
 			g.TransitionTimer = 60 // 60 frames = 1 second at 60 FPS
@@ -431,21 +761,17 @@ func (g *Game) Update() error {
 		// Count down transition timer
 		g.TransitionTimer--
 		if g.TransitionTimer <= 0 {
-			g.State = StatePlaying
-			g.SoundManager.StartBGM()
+			g.openStageSelect()
 		}
 
+	case StateStageSelect:
+		g.updateStageSelect()
+
 	case StatePlaying:
 		// Handle keyboard input
-		// F key for blue unit jump
-		if inpututil.IsKeyJustPressed(ebiten.KeyF) {
-			g.BlueUnit.jump(g.SoundManager)
-		}
-
-		// J key for red unit jump
-		if inpututil.IsKeyJustPressed(ebiten.KeyJ) {
-			g.RedUnit.jump(g.SoundManager)
-		}
+		// F key for blue unit jump, J key for red unit jump
+		blueJump := inpututil.IsKeyJustPressed(ebiten.KeyF)
+		redJump := inpututil.IsKeyJustPressed(ebiten.KeyJ)
 
 		// Handle touch input for gameplay
 		touchIDs := inpututil.AppendJustPressedTouchIDs(nil)
@@ -453,38 +779,40 @@ func (g *Game) Update() error {
 			x, _ := ebiten.TouchPosition(id)
 			// Left half of screen = F key (blue unit jump)
 			if x < ScreenWidth/2 {
-				g.BlueUnit.jump(g.SoundManager)
+				blueJump = true
 			} else {
 				// Right half of screen = J key (red unit jump)
-				g.RedUnit.jump(g.SoundManager)
+				redJump = true
 			}
 		}
 
-		// Update physics for both units
-		g.BlueUnit.updatePhysics(g.Stage)
-		g.RedUnit.updatePhysics(g.Stage)
+		// Jumps pressed between simulation ticks (slow game speed) wait for the next tick
+		g.pendingBlueJump = g.pendingBlueJump || blueJump
+		g.pendingRedJump = g.pendingRedJump || redJump
 
-		// Check game state conditions
-		if g.checkGameOver() {
-			g.SoundManager.PlayDeadSound()
-			g.State = StateGameOver
-		} else if g.checkCleared() {
-			g.SoundManager.StopBGM()
-			g.SoundManager.PlayClearSound()
-			g.State = StateCleared
+		// While frame stepping, ticks only advance with the step key
+		if g.frameStep.paused {
+			break
+		}
+		g.tickAccumulator += g.Assist.speed()
+		for g.tickAccumulator >= 1 && g.State == StatePlaying {
+			g.tickAccumulator--
+			g.advanceSimulation(g.pendingBlueJump, g.pendingRedJump)
+			g.pendingBlueJump = false
+			g.pendingRedJump = false
 		}
 
 	case StateGameOver:
 		// Handle restart with space key
 		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-			g.resetGame()
+			g.retry()
 			g.SoundManager.StartBGM()
 		}
 
 		// Handle touch input for retry - any touch triggers retry
 		touchIDs := inpututil.AppendJustPressedTouchIDs(nil)
 		if len(touchIDs) > 0 {
-			g.resetGame()
+			g.retry()
 			g.SoundManager.StartBGM()
 		}
 
@@ -501,12 +829,14 @@ func (g *Game) Update() error {
 		}
 
 	case StateAllCleared:
-		// Handle any key to restart from stage 1
+		// Handle a start key to restart from stage 1
 		// Check keyboard input - use JustPressedKeys to avoid repeated triggers
 		keys := inpututil.AppendJustPressedKeys(nil)
-		if len(keys) > 0 {
+		if hasStartKey(keys) {
 			g.StageLoader.ResetToFirstStage()
+			g.clearCheckpoint()
 			g.resetGame()
+			g.showTitleCard()
 			g.SoundManager.StartBGM()
 		}
 
@@ -514,7 +844,9 @@ func (g *Game) Update() error {
 		touchIDs := inpututil.AppendJustPressedTouchIDs(nil)
 		if len(touchIDs) > 0 {
 			g.StageLoader.ResetToFirstStage()
+			g.clearCheckpoint()
 			g.resetGame()
+			g.showTitleCard()
 			g.SoundManager.StartBGM()
 		}
 	}
@@ -543,6 +875,9 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			text.Draw(screen, "Press any key to start", g.Font, startOp)
 		}
 
+	case StateStageSelect:
+		g.drawStageSelect(screen)
+
 	case StateAllCleared:
 		// TODO: Add background image for all cleared screen
 		// Draw semi-transparent background for now
@@ -570,58 +905,30 @@ func (g *Game) Draw(screen *ebiten.Image) {
 
 	default:
 		// Draw gameplay elements (StatePlaying, StateGameOver, StateCleared)
-		// Draw platforms
-		for _, platform := range g.Stage.Platforms {
-			platformColor := platform.Color
-			// Highlight goal platforms
-			if platform.IsGoal {
-				platformColor = color.RGBA{255, 255, 0, 255} // Yellow for goal
+		// The world is drawn to an offscreen layer while the screen shakes,
+		// so HUD and overlays stay steady
+		world := screen
+		shakeX, shakeY := g.Particles.ShakeOffset()
+		if shakeX != 0 || shakeY != 0 {
+			if g.WorldLayer == nil {
+				g.WorldLayer = ebiten.NewImage(ScreenWidth, ScreenHeight)
 			}
-			vector.DrawFilledRect(screen, float32(platform.X), float32(platform.Y), float32(platform.Width), float32(platform.Height), platformColor, false)
-		}
-
-		// Draw blue unit as circle
-		blueCenterX := float32(g.BlueUnit.X) + UnitSize/2
-		blueCenterY := float32(g.BlueUnit.Y) + UnitSize/2
-		vector.DrawFilledCircle(screen, blueCenterX, blueCenterY, UnitSize/2, g.BlueUnit.Color, false)
-
-		// Draw red unit as circle
-		redCenterX := float32(g.RedUnit.X) + UnitSize/2
-		redCenterY := float32(g.RedUnit.Y) + UnitSize/2
-		vector.DrawFilledCircle(screen, redCenterX, redCenterY, UnitSize/2, g.RedUnit.Color, false)
-
-		// Draw spikes as upward triangles (optimized batch rendering)
-		if len(g.Stage.Spikes) > 0 {
-			// Calculate total vertices and indices needed
-			totalSpikes := len(g.Stage.Spikes)
-			vertices := make([]ebiten.Vertex, 0, totalSpikes*3)
-			indices := make([]uint16, 0, totalSpikes*3)
-
-			// Build all spike triangles in batch
-			for i, spike := range g.Stage.Spikes {
-				x := float32(spike.X)
-				y := float32(spike.Y)
-				size := float32(CellSize)
-
-				// Define triangle vertices for upward pointing spike
-				baseIndex := uint16(i * 3)
-				spikeVertices := []ebiten.Vertex{
-					{DstX: x, DstY: y + size, SrcX: 0, SrcY: 0, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: 1},        // Bottom left
-					{DstX: x + size, DstY: y + size, SrcX: 1, SrcY: 0, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: 1}, // Bottom right
-					{DstX: x + size/2, DstY: y, SrcX: 0.5, SrcY: 1, ColorR: 1, ColorG: 0, ColorB: 0, ColorA: 1},    // Top center
-				}
+			g.WorldLayer.Clear()
+			world = g.WorldLayer
+		}
 
-				// Add vertices to batch
-				vertices = append(vertices, spikeVertices...)
+		g.drawWorld(world)
 
-				// Add indices to batch (triangle indices for this spike)
-				spikeIndices := []uint16{baseIndex, baseIndex + 1, baseIndex + 2}
-				indices = append(indices, spikeIndices...)
-			}
+		if world != screen {
+			shakeOp := &ebiten.DrawImageOptions{}
+			shakeOp.GeoM.Translate(shakeX, shakeY)
+			screen.DrawImage(world, shakeOp)
+		}
 
-			// Single DrawTriangles call for all spikes
-			screen.DrawTriangles(vertices, indices, g.WhitePixel, nil)
+		if g.DebugOverlay {
+			g.drawDebugHUD(screen)
 		}
+		g.drawFrameStepStatus(screen)
 
 		// Draw stage number in top-left corner during gameplay
 		if g.State == StatePlaying {
@@ -631,76 +938,17 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			op.ColorScale.ScaleWithColor(WhiteColor)
 			text.Draw(screen, stageText, g.Font, op)
 
-			// Draw stage 1 specific tutorial text
-			if g.StageLoader.CurrentStageIndex == 1 {
-				// Draw instruction text for blue character (left side) - fixed position
-				blueInstructionOp := &text.DrawOptions{}
-				blueInstructionOp.GeoM.Translate(50, 440)                                   // Same height as Goal Platform (460) minus 20 for "Press F key"
-				blueInstructionOp.ColorScale.ScaleWithColor(color.RGBA{200, 200, 255, 255}) // Light blue
-				text.Draw(screen, "Press F key", g.Font, blueInstructionOp)
-
-				blueJumpOp := &text.DrawOptions{}
-				blueJumpOp.GeoM.Translate(60, 460)                                   // Same height as Goal Platform
-				blueJumpOp.ColorScale.ScaleWithColor(color.RGBA{200, 200, 255, 255}) // Light blue
-				text.Draw(screen, "to Jump", g.Font, blueJumpOp)
-
-				// Draw instruction text for red character (right side) - fixed position with more left margin
-				redInstructionOp := &text.DrawOptions{}
-				redInstructionOp.GeoM.Translate(600, 440)                                  // Moved left from 650 to 600 for better spacing
-				redInstructionOp.ColorScale.ScaleWithColor(color.RGBA{255, 200, 200, 255}) // Light red
-				text.Draw(screen, "Press J key", g.Font, redInstructionOp)
-
-				redJumpOp := &text.DrawOptions{}
-				redJumpOp.GeoM.Translate(610, 460)                                  // Same height as Goal Platform, moved left accordingly
-				redJumpOp.ColorScale.ScaleWithColor(color.RGBA{255, 200, 200, 255}) // Light red
-				text.Draw(screen, "to Jump", g.Font, redJumpOp)
-
-				// Draw goal instruction above goal platform - fixed position
-				goalInstructionOp := &text.DrawOptions{}
-				goalInstructionOp.GeoM.Translate(340, 460)                                  // Fixed position above goal area
-				goalInstructionOp.ColorScale.ScaleWithColor(color.RGBA{255, 255, 100, 255}) // Golden color
-				text.Draw(screen, "Goal Platform", g.Font, goalInstructionOp)
+			// Show that assists are active so assisted clears are not mistaken for normal ones
+			if g.Assist.Active() {
+				assistOp := &text.DrawOptions{}
+				assistOp.GeoM.Translate(StageTextX, StageTextY+30)
+				assistOp.ColorScale.ScaleWithColor(color.RGBA{150, 220, 255, 255})
+				text.Draw(screen, "ASSIST", g.Font, assistOp)
 			}
 
-			// Draw stage 2 specific tutorial text
-			if g.StageLoader.CurrentStageIndex == 2 {
-				// Draw instruction text above left spike pit (grid positions 11-12, 30)
-				leftPitOp := &text.DrawOptions{}
-				leftPitOp.GeoM.Translate(160, 460)                                  // Same height as stage 1 text (Y=460)
-				leftPitOp.ColorScale.ScaleWithColor(color.RGBA{255, 100, 100, 255}) // Red warning color
-				text.Draw(screen, "Jump over this", g.Font, leftPitOp)
-
-				// Draw instruction text above right spike pit (grid positions 27-28, 30)
-				rightPitOp := &text.DrawOptions{}
-				rightPitOp.GeoM.Translate(480, 460)                                  // Same height as stage 1 text (Y=460)
-				rightPitOp.ColorScale.ScaleWithColor(color.RGBA{255, 100, 100, 255}) // Red warning color
-				text.Draw(screen, "Jump over this", g.Font, rightPitOp)
-			}
-
-			// Draw stage 3 specific tutorial text
-			if g.StageLoader.CurrentStageIndex == 3 {
-				// Draw instruction text above speed-down platforms (grid positions 6,19 and 23,19)
-				leftSpeedDownOp := &text.DrawOptions{}
-				leftSpeedDownOp.GeoM.Translate(160, 340)                                 // Moved up from 360 to 340
-				leftSpeedDownOp.ColorScale.ScaleWithColor(color.RGBA{255, 150, 50, 255}) // Orange color (speed-down)
-				text.Draw(screen, "Speed Down", g.Font, leftSpeedDownOp)
-
-				rightSpeedDownOp := &text.DrawOptions{}
-				rightSpeedDownOp.GeoM.Translate(480, 340)                                 // Moved up from 360 to 340
-				rightSpeedDownOp.ColorScale.ScaleWithColor(color.RGBA{255, 150, 50, 255}) // Orange color (speed-down)
-				text.Draw(screen, "Speed Down", g.Font, rightSpeedDownOp)
-
-				// Draw instruction text above speed-up platforms (grid positions 3,24 and 26,24)
-				leftSpeedUpOp := &text.DrawOptions{}
-				leftSpeedUpOp.GeoM.Translate(140, 440)                                 // Moved up from 460 to 440
-				leftSpeedUpOp.ColorScale.ScaleWithColor(color.RGBA{50, 255, 150, 255}) // Green color (speed-up)
-				text.Draw(screen, "Speed Up", g.Font, leftSpeedUpOp)
-
-				rightSpeedUpOp := &text.DrawOptions{}
-				rightSpeedUpOp.GeoM.Translate(540, 440)                                 // Moved up from 460 to 440
-				rightSpeedUpOp.ColorScale.ScaleWithColor(color.RGBA{50, 255, 150, 255}) // Green color (speed-up)
-				text.Draw(screen, "Speed Up", g.Font, rightSpeedUpOp)
-			}
+			// Tutorial hint and title card from the stage file's front matter
+			g.drawStageTutorial(screen)
+			g.drawTitleCard(screen)
 		}
 
 		// Draw game state overlay text with background
@@ -731,6 +979,9 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			op1.ColorScale.ScaleWithColor(WhiteColor)
 			text.Draw(screen, "STAGE CLEARED!", g.Font, op1)
 
+			// Stars and challenges of this clear
+			g.drawClearResult(screen, float64(ScreenHeight/2+60))
+
 			// Draw second line
 			op2 := &text.DrawOptions{}
 			if g.StageLoader.CurrentStageIndex < g.StageLoader.TotalStages {
@@ -744,6 +995,110 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			}
 		}
 	}
+
+	// Settings notices and stage file errors are shown on every screen
+	g.drawNotice(screen)
+	g.drawReloadError(screen)
+}
+
+// drawWorld draws the stage, units and particles
+func (g *Game) drawWorld(screen *ebiten.Image) {
+	g.drawStaticLayer(screen)
+	g.drawCheckpoints(screen)
+	g.drawCollectibles(screen)
+	g.drawHazards(screen)
+
+	renderer := g.renderer()
+	renderer.DrawUnit(screen, g.BlueUnit, g.Tick)
+	renderer.DrawUnit(screen, g.RedUnit, g.Tick)
+
+	// Letter marks show which key controls each unit without relying on colour
+	g.drawUnitMark(screen, g.BlueUnit, "F")
+	g.drawUnitMark(screen, g.RedUnit, "J")
+
+	// Draw particles on top of the stage and units
+	g.Particles.Draw(screen)
+
+	if g.DebugOverlay {
+		g.drawDebugOverlay(screen)
+	}
+}
+
+// drawUnitMark draws a letter centered on a unit
+func (g *Game) drawUnitMark(screen *ebiten.Image, unit *Unit, mark string) {
+	if g.MarkFont == nil {
+		return
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(unit.X+UnitSize/2, unit.Y+UnitSize/2)
+	op.PrimaryAlign = text.AlignCenter
+	op.SecondaryAlign = text.AlignCenter
+	op.ColorScale.ScaleWithColor(color.Black)
+	text.Draw(screen, mark, g.MarkFont, op)
+}
+
+// showNotice shows a short message at the top of the screen
+func (g *Game) showNotice(message string) {
+	g.Notice = message
+	g.NoticeTimer = 120 // 2 seconds at 60 FPS
+}
+
+// drawNotice draws the current settings notice, if any
+func (g *Game) drawNotice(screen *ebiten.Image) {
+	if g.NoticeTimer <= 0 || g.Notice == "" {
+		return
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(ScreenWidth-StageTextX, StageTextY)
+	op.PrimaryAlign = text.AlignEnd
+	op.ColorScale.ScaleWithColor(WhiteColor)
+	text.Draw(screen, g.Notice, g.Font, op)
+}
+
+// drawStaticLayer draws the stage geometry from the cached static layer.
+// Platforms and spikes never change during play, so they are rendered to the
+// cache only when the stage or renderer changes instead of every frame.
+func (g *Game) drawStaticLayer(screen *ebiten.Image) {
+	renderer := g.renderer()
+	if g.StaticLayer == nil {
+		g.StaticLayer = ebiten.NewImage(ScreenWidth, ScreenHeight)
+	}
+	if g.staticLayerStage != g.Stage || g.staticLayerRenderer != renderer {
+		g.StaticLayer.Clear()
+		renderer.DrawStage(g.StaticLayer, g.Stage)
+		g.staticLayerStage = g.Stage
+		g.staticLayerRenderer = renderer
+	}
+	screen.DrawImage(g.StaticLayer, nil)
+}
+
+// invalidateStaticLayer forces the static layer to be redrawn on the next frame
+func (g *Game) invalidateStaticLayer() {
+	g.staticLayerStage = nil
+}
+
+// renderer returns the active renderer, falling back to vector shapes
+func (g *Game) renderer() Renderer {
+	if g.UseSprites && g.SpriteRenderer != nil {
+		return g.SpriteRenderer
+	}
+	if g.VectorRenderer == nil {
+		g.VectorRenderer = NewVectorRenderer()
+	}
+	return g.VectorRenderer
+}
+
+// settingsKeys only change options and never start the game
+var settingsKeys = []ebiten.Key{RendererKey, DebugOverlayKey, FrameStepPauseKey, GameSpeedKey, NoSpikeDeathKey, InfiniteCheckpointKey, PaletteKey, ReducedMotionKey}
+
+// hasStartKey reports whether any of the pressed keys should start the game
+func hasStartKey(keys []ebiten.Key) bool {
+	for _, key := range keys {
+		if !slices.Contains(settingsKeys, key) {
+			return true
+		}
+	}
+	return false
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -751,8 +1106,16 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 }
 
 func main() {
-	// Initialize debug mode based on platform
-	initDebugMode()
+	// Read startup options from the command line or URL parameters
+	config, err := loadConfig()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	DebugMode = config.Debug
+	stagefile.ErrorLanguage = config.Language
 
 	// Log debug mode status
 	if DebugMode {
@@ -761,7 +1124,8 @@ func main() {
 		log.Println("Debug mode: DISABLED (starting from stage 1)")
 	}
 
-	ebiten.SetWindowSize(ScreenWidth, ScreenHeight)
+	ebiten.SetWindowSize(int(ScreenWidth*config.Scale), int(ScreenHeight*config.Scale))
+	ebiten.SetFullscreen(config.Fullscreen)
 	ebiten.SetWindowTitle("UNION JUMPERS")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
@@ -774,19 +1138,93 @@ func main() {
 		Source: fontSource,
 		Size:   24,
 	}
+	markFont := &text.GoTextFace{
+		Source: fontSource,
+		Size:   12,
+	}
 
-	// Create stage loader
+	// Create stage loader, loading stage files from a directory if requested
 	stageLoader := NewStageLoader()
+	var stageWatcher *StageWatcher
+	var pack *StagePack
+	if config.PackPath != "" {
+		if pack, err = OpenStagePack(config.PackPath); err != nil {
+			log.Fatalf("Failed to load stage pack %s: %v", config.PackPath, err)
+		}
+		stageLoader.SetSource(pack)
+	}
+	if config.StageCode != "" {
+		if pack, err = SharedStagePack(config.StageCode); err != nil {
+			log.Fatalf("Failed to load stage code: %v", err)
+		}
+		stageLoader.SetSource(pack)
+		config.Stage = 1
+	}
+	if config.StagesDir != "" {
+		source, err := NewDirStageSource(config.StagesDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stageLoader.SetSource(source)
+		if config.Watch {
+			stageWatcher = NewStageWatcher(source)
+		}
+	}
+
+	// Load the replay to play back, which starts directly on its stage
+	var playback *Replay
+	if config.ReplayFile != "" {
+		data, err := os.ReadFile(config.ReplayFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if playback, err = ParseReplay(data); err != nil {
+			log.Fatalf("Failed to parse replay %s: %v", config.ReplayFile, err)
+		}
+		config.Stage = playback.Stage
+	}
+	lastStage := lastBuiltinStage
+	if stageLoader.Source != nil {
+		lastStage = stageLoader.Source.LastStage()
+	}
+	if err := config.CheckStage(lastStage); err != nil {
+		log.Fatal(err)
+	}
+	if config.Stage >= 0 {
+		stageLoader.CurrentStageIndex = config.Stage
+	}
 
 	// Create sound manager
 	soundManager := NewSoundManager()
+	soundManager.Muted = config.Mute
+	if pack != nil && pack.Music != nil {
+		if err := soundManager.SetBGM(pack.Music); err != nil {
+			log.Printf("Keeping the built-in BGM: %v", err)
+		}
+	}
+
+	// Create the gameplay event stream and attach independent subscribers
+	events := NewEventBus()
+	soundManager.SubscribeEvents(events)
+	stats := LoadStatsRecorder()
+	stats.SubscribeEvents(events)
+	replay := NewReplayRecorder()
+	replay.SavePath = config.RecordFile
+	replay.SubscribeEvents(events)
+	particles := NewParticleSystem(DefaultEffectsConfig())
+	particles.SubscribeEvents(events)
 
 	// Get starting positions for the first stage
 	blueX, blueY, redX, redY := stageLoader.GetCurrentStageStartPositions()
 
-	// Create reusable 1x1 white pixel for triangle rendering
-	whitePixel := ebiten.NewImage(1, 1)
-	whitePixel.Fill(color.White)
+	// Create renderers, keeping the vector renderer as a fallback if the atlas cannot be loaded
+	vectorRenderer := NewVectorRenderer()
+	var spriteRenderer *SpriteRenderer
+	if atlas, err := LoadSpriteAtlas(atlasImageBytes); err != nil {
+		log.Printf("Sprites disabled: %v", err)
+	} else {
+		spriteRenderer = NewSpriteRenderer(atlas, vectorRenderer)
+	}
 
 	game := &Game{
 		BlueUnit: &Unit{
@@ -795,9 +1233,11 @@ func main() {
 			VX:        SPEED,
 			VY:        0,
 			Direction: 1,
-			Color:     color.RGBA{0, 100, 255, 255}, // Blue
+			Color:     BlueUnitColor,
 			OnGround:  false,
 			Stopped:   false,
+			Kind:      UnitBlue,
+			Events:    events,
 		},
 		RedUnit: &Unit{
 			X:         redX,
@@ -805,19 +1245,38 @@ func main() {
 			VX:        -SPEED,
 			VY:        0,
 			Direction: -1,
-			Color:     color.RGBA{255, 100, 100, 255}, // Red
+			Color:     RedUnitColor,
 			OnGround:  false,
 			Stopped:   false,
+			Kind:      UnitRed,
+			Events:    events,
 		},
 		Stage:           stageLoader.GetCurrentStage(), // Load first stage
 		State:           StateTitle,                    // Start with title screen
 		Font:            font,
+		MarkFont:        markFont,
 		StageLoader:     stageLoader,
 		SoundManager:    soundManager,
+		Events:          events,
+		Stats:           stats,
+		Replay:          replay,
+		Particles:       particles,
+		Assist:          DefaultAssistOptions(),
 		BlinkCounter:    0,
 		BlinkVisible:    true,
 		TransitionTimer: 0,
-		WhitePixel:      whitePixel,
+		VectorRenderer:  vectorRenderer,
+		SpriteRenderer:  spriteRenderer,
+		UseSprites:      spriteRenderer != nil,
+		DebugOverlay:    DebugMode,
+		Playback:        playback,
+		StageWatcher:    stageWatcher,
+	}
+	game.syncStatsSource()
+	// Skip the title screen when a stage was given
+	if config.Stage >= 0 {
+		game.resetGame()
+		game.showTitleCard()
 	}
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
@@ -1,12 +1,27 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"errors"
+	"fmt"
 	"image/color"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/pankona/egj2025/internal/stagefile"
 )
 
 func createTestFont() *text.GoTextFace {
@@ -255,3 +270,1996 @@ func TestGameStateCleared(t *testing.T) {
 		}
 	})
 }
+
+func TestGameplayEvents(t *testing.T) {
+	stage := &Stage{
+		Platforms: []Platform{
+			// Ground platform
+			{X: 0, Y: 550, Width: 800, Height: 50, Color: color.RGBA{100, 100, 100, 255}, IsGoal: false, SpeedModifier: 1.0},
+			// Goal platform
+			{X: 350, Y: 530, Width: 100, Height: 20, Color: color.RGBA{255, 255, 0, 255}, IsGoal: true, SpeedModifier: 1.0},
+		},
+	}
+
+	newGame := func() (*Game, *[]Event) {
+		events := NewEventBus()
+		var received []Event
+		events.SubscribeAll(func(e Event) {
+			received = append(received, e)
+		})
+		game := &Game{
+			BlueUnit:    &Unit{X: 100, Y: 520, Direction: 1, Kind: UnitBlue, Events: events},
+			RedUnit:     &Unit{X: 600, Y: 520, Direction: -1, Kind: UnitRed, Events: events},
+			Stage:       stage,
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Events:      events,
+		}
+		return game, &received
+	}
+
+	countEvents := func(received []Event, eventType EventType, unit UnitKind) int {
+		count := 0
+		for _, e := range received {
+			if e.Type == eventType && e.Unit == unit {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("着地とジャンプのイベントがサウンドなしで発行される", func(t *testing.T) {
+		game, received := newGame()
+
+		// Let both units fall onto the ground
+		for i := 0; i < 30; i++ {
+			game.stepSimulation(false, false)
+		}
+		if countEvents(*received, EventLanded, UnitBlue) != 1 || countEvents(*received, EventLanded, UnitRed) != 1 {
+			t.Fatalf("両キャラの着地イベントがそれぞれ1回発行されるべき: %v", *received)
+		}
+
+		game.stepSimulation(true, false)
+		if countEvents(*received, EventJumped, UnitBlue) != 1 {
+			t.Error("青キャラのジャンプイベントが発行されるべき")
+		}
+		if countEvents(*received, EventJumped, UnitRed) != 0 {
+			t.Error("赤キャラはジャンプしていないのでイベントは発行されないべき")
+		}
+
+		last := (*received)[len(*received)-1]
+		if last.Stage != 1 || last.Tick != game.Tick {
+			t.Errorf("イベントにステージとティックが記録されるべき: stage=%d tick=%d", last.Stage, last.Tick)
+		}
+	})
+
+	t.Run("両キャラがゴールするとクリアイベントが発行され統計に記録される", func(t *testing.T) {
+		game, received := newGame()
+		stats := NewStatsRecorder()
+		stats.SubscribeEvents(game.Events)
+
+		game.BlueUnit.X = 360
+		game.BlueUnit.Y = 530
+		game.RedUnit.X = 380
+		game.RedUnit.Y = 530
+		game.stepSimulation(false, false)
+
+		if game.State != StateCleared {
+			t.Fatalf("ゲーム状態がStateClearedになるべき: %v", game.State)
+		}
+		if countEvents(*received, EventStageCleared, UnitNone) != 1 {
+			t.Error("ステージクリアイベントが1回発行されるべき")
+		}
+		if countEvents(*received, EventReachedGoal, UnitBlue) != 1 || countEvents(*received, EventReachedGoal, UnitRed) != 1 {
+			t.Error("両キャラのゴール到達イベントが発行されるべき")
+		}
+		if got := stats.StageStats(1).Clears; got != 1 {
+			t.Errorf("統計のクリア回数が1になるべき: %d", got)
+		}
+	})
+}
+
+func TestParticleSystem(t *testing.T) {
+	emitter := EmitterConfig{Count: 5, Speed: 1.0, Spread: math.Pi, Life: 3, Size: 2, Colors: []*color.RGBA{&WhiteColor}}
+
+	t.Run("イベントで粒子を出し寿命が尽きると消える", func(t *testing.T) {
+		ps := NewParticleSystem(DefaultEffectsConfig())
+		bus := NewEventBus()
+		ps.SubscribeEvents(bus)
+		bus.Emit(Event{Type: EventJumped, X: 100, Y: 100})
+		if got, want := len(ps.particles), ps.Config.JumpDust.Count; got != want {
+			t.Fatalf("ジャンプで%d個の粒子が出るべき: %d", want, got)
+		}
+
+		ps.Clear()
+		ps.Emit(emitter, 10, 10)
+		for i := 0; i < emitter.Life-1; i++ {
+			ps.Update()
+		}
+		if len(ps.particles) != emitter.Count {
+			t.Fatalf("寿命が残っている粒子は消えないべき: %d", len(ps.particles))
+		}
+		ps.Update()
+		if len(ps.particles) != 0 {
+			t.Errorf("寿命が尽きた粒子は消えるべき: %d", len(ps.particles))
+		}
+	})
+
+	t.Run("動きを減らす設定では粒子も揺れも出ない", func(t *testing.T) {
+		config := DefaultEffectsConfig()
+		config.ReducedMotion = true
+		ps := NewParticleSystem(config)
+		bus := NewEventBus()
+		ps.SubscribeEvents(bus)
+		bus.Emit(Event{Type: EventDied})
+		if dx, dy := ps.ShakeOffset(); len(ps.particles) != 0 || dx != 0 || dy != 0 {
+			t.Errorf("粒子も揺れも出ないべき: %d particles, shake (%v, %v)", len(ps.particles), dx, dy)
+		}
+	})
+
+	t.Run("画面の揺れは時間とともに弱まり止まる", func(t *testing.T) {
+		ps := NewParticleSystem(DefaultEffectsConfig())
+		bus := NewEventBus()
+		ps.SubscribeEvents(bus)
+		bus.Emit(Event{Type: EventDied})
+		limit := ps.Config.ShakeMagnitude
+		for i := 0; i < ps.Config.ShakeDuration; i++ {
+			dx, dy := ps.ShakeOffset()
+			if math.Abs(dx) > limit || math.Abs(dy) > limit {
+				t.Fatalf("%dティック目の揺れが上限 %v を超える: (%v, %v)", i, limit, dx, dy)
+			}
+			limit = ps.Config.ShakeMagnitude * float64(ps.shakeLeft-1) / float64(ps.Config.ShakeDuration)
+			ps.Update()
+		}
+		if dx, dy := ps.ShakeOffset(); dx != 0 || dy != 0 {
+			t.Errorf("揺れは止まるべき: (%v, %v)", dx, dy)
+		}
+	})
+
+	t.Run("粒子の色は出したときのパレットになる", func(t *testing.T) {
+		defer ApplyPalette(0)
+		ps := NewParticleSystem(DefaultEffectsConfig())
+		palette := ApplyPalette(1)
+		star := ps.Config.StarSparkle
+		star.Colors = star.Colors[:1]
+		ps.Emit(star, 0, 0)
+		if got := ps.particles[0].Color; got != palette.Star {
+			t.Errorf("切り替えた後のパレットの色で出るべき: got %v, want %v", got, palette.Star)
+		}
+	})
+
+	t.Run("粒子はシミュレーションのティックで進む", func(t *testing.T) {
+		game := &Game{
+			BlueUnit:    &Unit{X: 100, Y: 100, Direction: 1, Kind: UnitBlue},
+			RedUnit:     &Unit{X: 600, Y: 100, Direction: -1, Kind: UnitRed},
+			Stage:       &Stage{},
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+			Particles:   NewParticleSystem(DefaultEffectsConfig()),
+		}
+		game.Particles.Emit(emitter, 10, 10)
+		game.stepSimulation(false, false)
+		game.stepSimulation(false, false)
+		if game.Particles.tick != 2 || game.Particles.particles[0].Life != emitter.Life-2 {
+			t.Errorf("ティックごとに1回だけ進むべき: tick=%d life=%d", game.Particles.tick, game.Particles.particles[0].Life)
+		}
+	})
+
+	t.Run("描画回数は粒子の出方に影響しない", func(t *testing.T) {
+		shaken, still := NewParticleSystem(DefaultEffectsConfig()), NewParticleSystem(DefaultEffectsConfig())
+		shaken.shakeLeft = shaken.Config.ShakeDuration
+		first, _ := shaken.ShakeOffset()
+		if again, _ := shaken.ShakeOffset(); again != first {
+			t.Errorf("同じティックの揺れは同じであるべき: %v, %v", first, again)
+		}
+		shaken.Emit(emitter, 0, 0)
+		still.Emit(emitter, 0, 0)
+		if !reflect.DeepEqual(shaken.particles, still.particles) {
+			t.Error("揺れを描いても以降の粒子は変わらないべき")
+		}
+	})
+}
+
+func TestBuildStageTiles(t *testing.T) {
+	stage := &Stage{
+		Platforms: []Platform{
+			CreateGridPlatform(0, 0, 3, 1),
+			CreateGridPlatform(1, 1, 1, 1),
+			CreateGridSpeedUpPlatform(3, 0, 1, 1),
+			// Not grid aligned: drawn as a shape instead of tiles
+			{X: 5, Y: 5, Width: 30, Height: 10, SpeedModifier: 1.0},
+		},
+	}
+
+	tiles := BuildStageTiles(stage)
+	masks := make(map[GridPosition]stageTileInfo)
+	for _, tile := range tiles {
+		masks[GridPosition{X: tile.GridX, Y: tile.GridY}] = stageTileInfo{Kind: tile.Kind, Mask: tile.Mask}
+	}
+
+	t.Run("グリッドに揃ったプラットフォームのみタイルに分割される", func(t *testing.T) {
+		if len(tiles) != 5 {
+			t.Errorf("タイル数が5になるべき: %d", len(tiles))
+		}
+	})
+
+	t.Run("隣接する同種のセルに応じてオートタイルが選ばれる", func(t *testing.T) {
+		cases := []struct {
+			pos  GridPosition
+			want stageTileInfo
+		}{
+			{GridPosition{0, 0}, stageTileInfo{TileSolid, NeighbourEast}},
+			{GridPosition{1, 0}, stageTileInfo{TileSolid, NeighbourEast | NeighbourSouth | NeighbourWest}},
+			{GridPosition{2, 0}, stageTileInfo{TileSolid, NeighbourWest}},
+			{GridPosition{1, 1}, stageTileInfo{TileSolid, NeighbourNorth}},
+			// Speed-up platform does not join the regular platform next to it
+			{GridPosition{3, 0}, stageTileInfo{TileSpeedUp, 0}},
+		}
+		for _, c := range cases {
+			if got := masks[c.pos]; got != c.want {
+				t.Errorf("(%d, %d) のタイルが期待と異なる: got %+v, want %+v", c.pos.X, c.pos.Y, got, c.want)
+			}
+		}
+	})
+}
+
+// stageTileInfo is the part of a StageTile compared in tests
+type stageTileInfo struct {
+	Kind TileKind
+	Mask int
+}
+
+func TestUnitAnimationFrame(t *testing.T) {
+	cases := []struct {
+		name string
+		unit Unit
+		want int
+	}{
+		{"ゴールで停止中は待機アニメーション", Unit{OnGround: true, Stopped: true}, UnitFrameIdle},
+		{"地面を歩行中は走りアニメーション", Unit{OnGround: true}, UnitFrameRun},
+		{"上昇中はジャンプフレーム", Unit{VY: -3}, UnitFrameJump},
+		{"落下中は落下フレーム", Unit{VY: 3}, UnitFrameFall},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := unitAnimationFrame(&c.unit, 0); got != c.want {
+				t.Errorf("フレームが期待と異なる: got %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+// Benchmarks comparing per-frame stage drawing with and without the static layer cache.
+// Run with: GOOS=js GOARCH=wasm go test -bench StageDraw -benchmem
+func BenchmarkStageDrawUncached(b *testing.B) {
+	screen := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	renderer := NewVectorRenderer()
+	stage := LoadStage0()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// Previous behaviour: every platform rect and spike vertex rebuilt each frame
+		renderer.DrawStage(screen, stage)
+	}
+}
+
+func BenchmarkStageDrawCached(b *testing.B) {
+	screen := ebiten.NewImage(ScreenWidth, ScreenHeight)
+	game := &Game{
+		Stage:          LoadStage0(),
+		VectorRenderer: NewVectorRenderer(),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		game.drawStaticLayer(screen)
+	}
+}
+
+func TestApplyPalette(t *testing.T) {
+	defer ApplyPalette(0)
+
+	t.Run("パレットを適用すると以降に作成されるステージの色が変わる", func(t *testing.T) {
+		palette := ApplyPalette(1)
+		if got := CreateGridSpeedUpPlatform(0, 0, 1, 1).Color; got != palette.SpeedUp {
+			t.Errorf("スピードアップ足場の色がパレットの色になるべき: got %v, want %v", got, palette.SpeedUp)
+		}
+		if got := CreateGridSpike(0, 0).Color; got != palette.Spike {
+			t.Errorf("トゲの色がパレットの色になるべき: got %v, want %v", got, palette.Spike)
+		}
+		if BlueUnitColor != palette.BlueUnit || RedUnitColor != palette.RedUnit {
+			t.Error("キャラの色がパレットの色になるべき")
+		}
+	})
+
+	t.Run("パレット番号は範囲外でも循環する", func(t *testing.T) {
+		ApplyPalette(len(Palettes))
+		if CurrentPaletteIndex != 0 {
+			t.Errorf("パレット番号が0に戻るべき: %d", CurrentPaletteIndex)
+		}
+		ApplyPalette(-1)
+		if CurrentPaletteIndex != len(Palettes)-1 {
+			t.Errorf("パレット番号が最後に戻るべき: %d", CurrentPaletteIndex)
+		}
+	})
+
+	t.Run("実行中に切り替えるとステージをそのまま塗り直す", func(t *testing.T) {
+		ApplyPalette(0)
+		loader := &StageLoader{}
+		for index := 0; index <= lastBuiltinStage; index++ {
+			loader.CurrentStageIndex = index
+			game := &Game{
+				BlueUnit:    &Unit{Kind: UnitBlue},
+				RedUnit:     &Unit{Kind: UnitRed},
+				Stage:       loader.GetCurrentStage(),
+				StageLoader: loader,
+			}
+			game.resetHazards()
+			stage, hazardStage := game.Stage, game.hazardStage
+
+			game.applyPalette(2)
+			if game.Stage != stage || game.hazardStage != hazardStage {
+				t.Fatalf("ステージ%dを作り直すとハザードがリセットされる", index)
+			}
+			if want := loader.GetCurrentStage(); !reflect.DeepEqual(game.Stage, want) {
+				t.Errorf("ステージ%dの色が新しいパレットで作ったステージと一致しない", index)
+			}
+			ApplyPalette(0)
+		}
+	})
+
+	t.Run("速度の指定がない地面は減速床の色にならない", func(t *testing.T) {
+		ApplyPalette(0)
+		previous := Palettes[CurrentPaletteIndex]
+		ApplyPalette(1)
+		if got := platformColor(CreateGroundPlatform(), previous); got != GroundColor {
+			t.Errorf("地面の色になるべき: got %v, want %v", got, GroundColor)
+		}
+		ApplyPalette(0)
+	})
+
+	t.Run("ステージパックのステージも新しいパレットの色になる", func(t *testing.T) {
+		ApplyPalette(0)
+		code := StageCode(LoadStage4(), StartPositions{BlueX: GridToPixelX(2), BlueY: GridToPixelY(27), RedX: GridToPixelX(37), RedY: GridToPixelY(27)})
+		pack, err := SharedStagePack(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := pack.LoadStage(1); err != nil {
+			t.Fatal(err)
+		}
+		ApplyPalette(3)
+		got, _, _ := pack.LoadStage(1)
+		want, _, _ := ParseStageCode(code)
+		if !reflect.DeepEqual(got, want) {
+			t.Error("キャッシュしたステージも新しいパレットの色になるべき")
+		}
+	})
+
+	t.Run("デフォルトパレットは従来の色と一致する", func(t *testing.T) {
+		ApplyPalette(0)
+		if SpeedUpColor != (color.RGBA{0, 255, 100, 255}) || SpeedDownColor != (color.RGBA{255, 100, 0, 255}) {
+			t.Error("デフォルトパレットの色が従来の色と異なる")
+		}
+	})
+}
+
+func TestAssistModes(t *testing.T) {
+	newGame := func(stage *Stage) *Game {
+		return &Game{
+			BlueUnit:    &Unit{X: 100, Y: 530, Direction: 1, Kind: UnitBlue, OnGround: true},
+			RedUnit:     &Unit{X: 600, Y: 530, Direction: -1, Kind: UnitRed, OnGround: true},
+			Stage:       stage,
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+		}
+	}
+	ground := Platform{X: 0, Y: 550, Width: 800, Height: 50, SpeedModifier: 1.0}
+
+	t.Run("トゲ無効モードではトゲに触れても跳ね返りゲームオーバーにならない", func(t *testing.T) {
+		stage := &Stage{
+			Platforms: []Platform{ground},
+			Spikes:    []Spike{{X: 120, Y: 530}},
+		}
+		game := newGame(stage)
+		game.Assist.NoSpikeDeath = true
+
+		game.stepSimulation(false, false)
+
+		if game.State != StatePlaying {
+			t.Fatalf("ゲームオーバーになってはいけない: %v", game.State)
+		}
+		if game.BlueUnit.VY >= 0 {
+			t.Errorf("トゲに触れたキャラは上に跳ね返るべき: VY=%v", game.BlueUnit.VY)
+		}
+
+		game.Assist.NoSpikeDeath = false
+		game.BlueUnit.X, game.BlueUnit.Y, game.BlueUnit.VY = 110, 530, 0
+		game.stepSimulation(false, false)
+		if game.State != StateGameOver {
+			t.Error("通常モードではトゲに触れるとゲームオーバーになるべき")
+		}
+	})
+
+	t.Run("ゲーム速度50%ではシミュレーションが2フレームに1回進む", func(t *testing.T) {
+		game := newGame(&Stage{Platforms: []Platform{ground}})
+		game.Assist.GameSpeed = 0.5
+
+		for i := 0; i < 10; i++ {
+			game.tickAccumulator += game.Assist.speed()
+			for game.tickAccumulator >= 1 {
+				game.tickAccumulator--
+				game.stepSimulation(false, false)
+			}
+		}
+		if game.Tick != 5 {
+			t.Errorf("10フレームで5ティック進むべき: %d", game.Tick)
+		}
+	})
+
+	t.Run("無限チェックポイントモードでは死亡前の安全な位置から再開する", func(t *testing.T) {
+		game := newGame(&Stage{Platforms: []Platform{ground}})
+		game.Assist.InfiniteCheckpoint = true
+
+		for i := 0; i < 200; i++ {
+			game.stepSimulation(false, false)
+		}
+		// Put a spike right in front of the blue unit
+		game.Stage.Spikes = []Spike{{X: game.BlueUnit.X + float64(game.BlueUnit.Direction), Y: 530}}
+		game.stepSimulation(false, false)
+		if game.State != StateGameOver {
+			t.Fatalf("トゲに触れたらゲームオーバーになるべき: %v", game.State)
+		}
+		game.Stage.Spikes = nil
+		deathTick := game.Tick
+
+		game.retry()
+
+		if game.State != StatePlaying {
+			t.Fatalf("再開後はプレイ中になるべき: %v", game.State)
+		}
+		if game.Tick > deathTick-safePointMinAge || game.Tick == 0 {
+			t.Errorf("死亡より十分前の安全な位置から再開するべき: tick=%d death=%d", game.Tick, deathTick)
+		}
+		if !game.BlueUnit.OnGround || game.checkGameOver() {
+			t.Error("再開後のキャラは安全に地面の上にいるべき")
+		}
+	})
+
+	t.Run("無限チェックポイントで再開すると敵・スター・リプレイも安全な位置の時点に戻る", func(t *testing.T) {
+		ledge := Platform{X: 300, Y: 120, Width: 200, Height: 20, SpeedModifier: 1.0}
+		game := newGame(&Stage{
+			Platforms:    []Platform{ground, ledge},
+			Hazards:      []Hazard{CreateGridWalker(16, 5)},
+			Collectibles: []Collectible{{X: 400, Y: 300}},
+		})
+		game.Events = NewEventBus()
+		game.BlueUnit.Events = game.Events
+		game.RedUnit.Events = game.Events
+		replay := NewReplayRecorder()
+		replay.SubscribeEvents(game.Events)
+		game.Assist.InfiniteCheckpoint = true
+		game.resetCollectibles()
+		game.resetHazards()
+		game.beginAttempt()
+
+		for i := 0; i < 120; i++ {
+			game.stepSimulation(i == 20, false)
+		}
+		wantHazards := append([]Hazard(nil), game.hazards...)
+		// Pick up the star and jump after the last safe point before the death
+		game.Stage.Collectibles[0].X, game.Stage.Collectibles[0].Y = game.BlueUnit.X, game.BlueUnit.Y
+		for i := 0; i < 100; i++ {
+			game.stepSimulation(i == 30, false)
+		}
+		if game.collectedCount() != 1 {
+			t.Fatal("スターを取っているべき")
+		}
+		game.Stage.Spikes = []Spike{{X: game.BlueUnit.X + float64(game.BlueUnit.Direction), Y: 530}}
+		game.stepSimulation(false, false)
+		if game.State != StateGameOver {
+			t.Fatalf("トゲに触れたらゲームオーバーになるべき: %v", game.State)
+		}
+		game.Stage.Spikes = nil
+
+		game.retry()
+
+		if game.Tick != 120 {
+			t.Fatalf("tick 120 の安全な位置から再開するべき: %d", game.Tick)
+		}
+		if game.collectedCount() != 0 {
+			t.Error("安全な位置より後に取ったスターは取っていないことになるべき")
+		}
+		if !reflect.DeepEqual(game.hazards, wantHazards) {
+			t.Errorf("敵の状態が安全な位置の時点に戻るべき:\ngot  %+v\nwant %+v", game.hazards, wantHazards)
+		}
+		if want := []ReplayInput{{Tick: 21, Unit: UnitBlue}}; !reflect.DeepEqual(replay.current.Inputs, want) {
+			t.Errorf("安全な位置より後のジャンプはリプレイから消えるべき: %+v", replay.current.Inputs)
+		}
+		if !replay.current.Assisted {
+			t.Error("死亡を取り消したリプレイはアシスト扱いになるべき")
+		}
+	})
+
+	t.Run("アシスト使用時のクリアは統計でアシスト扱いになる", func(t *testing.T) {
+		stats := NewStatsRecorder()
+		stats.handleEvent(Event{Type: EventStageCleared, Stage: 3, Tick: 100, Assisted: true})
+
+		s := stats.StageStats(3)
+		if !s.ClearedOnlyWithAssist() {
+			t.Error("アシストのみのクリアとして記録されるべき")
+		}
+		if s.BestClearTicks != 0 {
+			t.Error("アシスト使用時のクリアはベストタイムに記録されないべき")
+		}
+
+		stats.handleEvent(Event{Type: EventStageCleared, Stage: 3, Tick: 200})
+		if s.ClearedOnlyWithAssist() || s.BestClearTicks != 200 {
+			t.Errorf("アシストなしのクリアが記録されるべき: %+v", s)
+		}
+	})
+}
+
+func TestCheckpoints(t *testing.T) {
+	ground := Platform{X: 0, Y: 550, Width: 800, Height: 50, SpeedModifier: 1.0}
+	newGame := func() *Game {
+		return &Game{
+			BlueUnit: &Unit{X: 100, Y: 530, Direction: 1, Kind: UnitBlue, OnGround: true},
+			RedUnit:  &Unit{X: 600, Y: 530, Direction: -1, Kind: UnitRed, OnGround: true},
+			Stage: &Stage{
+				Platforms: []Platform{ground},
+				Checkpoints: []Checkpoint{
+					{X: 120, Y: 530, Unit: UnitBlue},
+					{X: 300, Y: 530, Unit: UnitRed},
+					{X: 580, Y: 530, Unit: UnitRed},
+				},
+			},
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+		}
+	}
+
+	t.Run("片方のキャラだけが旗に触れてもチェックポイントは有効にならない", func(t *testing.T) {
+		game := newGame()
+		game.RedUnit.X = 700
+
+		game.stepSimulation(false, false)
+
+		if game.Checkpoint != nil {
+			t.Errorf("両方のキャラが旗に触れるまで有効にならないべき: %+v", game.Checkpoint)
+		}
+	})
+
+	t.Run("他のキャラの色の旗には触れても反応しない", func(t *testing.T) {
+		game := newGame()
+		game.BlueUnit.X = 300
+
+		if i := game.BlueUnit.touchingCheckpoint(game.Stage); i != -1 {
+			t.Errorf("青キャラは赤の旗に反応しないべき: %d", i)
+		}
+	})
+
+	t.Run("両方のキャラが旗に触れるとリトライ位置になる", func(t *testing.T) {
+		game := newGame()
+		game.Events = NewEventBus()
+		var activated []UnitKind
+		game.Events.Subscribe(EventCheckpointActivated, func(e Event) {
+			activated = append(activated, e.Unit)
+		})
+
+		game.stepSimulation(false, false)
+
+		if game.Checkpoint == nil {
+			t.Fatal("チェックポイントが有効になるべき")
+		}
+		if game.Checkpoint.BlueCheckpoint != 0 || game.Checkpoint.RedCheckpoint != 2 {
+			t.Errorf("触れた旗のペアが有効になるべき: %+v", game.Checkpoint)
+		}
+		if len(activated) != 2 {
+			t.Errorf("旗ごとにイベントが発行されるべき: %v", activated)
+		}
+		if !game.isCheckpointActive(0) || game.isCheckpointActive(1) || !game.isCheckpointActive(2) {
+			t.Error("有効なペアの旗だけが強調表示されるべき")
+		}
+
+		game.BlueUnit.X, game.RedUnit.X = 400, 450
+		game.State = StateGameOver
+		game.resetGame()
+
+		if game.BlueUnit.X != 120 || game.BlueUnit.Y != 530 || game.BlueUnit.Direction != 1 {
+			t.Errorf("青キャラは青の旗から再開するべき: (%v, %v) dir=%d", game.BlueUnit.X, game.BlueUnit.Y, game.BlueUnit.Direction)
+		}
+		if game.RedUnit.X != 580 || game.RedUnit.Y != 530 || game.RedUnit.Direction != -1 || game.RedUnit.VX != -SPEED {
+			t.Errorf("赤キャラは赤の旗から左向きに再開するべき: (%v, %v) dir=%d", game.RedUnit.X, game.RedUnit.Y, game.RedUnit.Direction)
+		}
+
+		game.clearCheckpoint()
+		game.resetGame()
+		blueX, _, redX, _ := game.StageLoader.GetCurrentStageStartPositions()
+		if game.BlueUnit.X != blueX || game.RedUnit.X != redX {
+			t.Error("チェックポイント解除後はステージの初期位置から始まるべき")
+		}
+	})
+
+	t.Run("チェックポイントから再開しても経過時間とジャンプ数は引き継がれる", func(t *testing.T) {
+		game := newGame()
+		game.Events = NewEventBus()
+		stats := NewStatsRecorder()
+		stats.SubscribeEvents(game.Events)
+		replays := NewReplayRecorder()
+		replays.SubscribeEvents(game.Events)
+		game.beginAttempt()
+
+		game.stepSimulation(false, false)
+		game.Events.Emit(Event{Type: EventJumped, Unit: UnitBlue})
+		game.Tick = 100
+		game.State = StateGameOver
+		game.resetGame()
+
+		if game.Tick != 100 {
+			t.Errorf("経過時間はリセットされないべき: %d", game.Tick)
+		}
+		if stats.attemptJumps != 1 || stats.StageStats(1).Attempts != 1 {
+			t.Errorf("同じ挑戦として数えられるべき: jumps=%d attempts=%d", stats.attemptJumps, stats.StageStats(1).Attempts)
+		}
+		if !replays.current.Assisted {
+			t.Error("チェックポイントからの再開を含むリプレイは再現できないものとして扱うべき")
+		}
+
+		game.clearCheckpoint()
+		game.resetGame()
+		if game.Tick != 0 || stats.attemptJumps != 0 || stats.StageStats(1).Attempts != 2 {
+			t.Errorf("最初からのリトライでは新しい挑戦になるべき: tick=%d jumps=%d", game.Tick, stats.attemptJumps)
+		}
+	})
+}
+
+func TestOneWayPlatform(t *testing.T) {
+	oneWay := CreateGridOneWayPlatform(5, 20, 6, 1) // x=100..220, y=400
+
+	t.Run("下からジャンプすると通り抜ける", func(t *testing.T) {
+		unit := &Unit{X: 140, Y: 420, VY: -JUMP_STRENGTH, Direction: 1, SpeedModifier: 1.0}
+		stage := &Stage{Platforms: []Platform{oneWay}}
+
+		for i := 0; i < 30 && unit.VY < 0; i++ {
+			unit.updatePhysics(stage)
+		}
+		if unit.Y+UnitSize > oneWay.Y {
+			t.Errorf("足場の上まで通り抜けるべき: Y=%v", unit.Y)
+		}
+	})
+
+	t.Run("上から落ちると着地する", func(t *testing.T) {
+		unit := &Unit{X: 140, Y: 378, VY: 5, Direction: 1, SpeedModifier: 1.0}
+		stage := &Stage{Platforms: []Platform{oneWay}}
+
+		unit.updatePhysics(stage)
+		if !unit.OnGround || unit.Y != oneWay.Y-UnitSize {
+			t.Errorf("足場の上に着地するべき: Y=%v OnGround=%v", unit.Y, unit.OnGround)
+		}
+		unit.updatePhysics(stage)
+		if !unit.OnGround {
+			t.Error("着地後も足場の上に立ち続けるべき")
+		}
+	})
+
+	t.Run("横からぶつかっても向きが変わらない", func(t *testing.T) {
+		unit := &Unit{X: 85, Y: 395, Direction: 1, SpeedModifier: 1.0}
+		stage := &Stage{Platforms: []Platform{oneWay}}
+
+		unit.updatePhysics(stage)
+		if unit.Direction != 1 {
+			t.Error("一方通行の足場は横から通り抜けられるべき")
+		}
+	})
+}
+
+func TestGimmickTiles(t *testing.T) {
+	// Ground at grid row 27 (y=540); units standing on it have Y=520
+	newUnit := func(x float64, direction int) *Unit {
+		return &Unit{X: x, Y: 520, Direction: direction, OnGround: true, SpeedModifier: 1.0}
+	}
+
+	t.Run("反転タイルに乗ると一度だけ向きが反転する", func(t *testing.T) {
+		stage := &Stage{Platforms: []Platform{
+			CreateGridPlatform(0, 27, 10, 1),
+			CreateGridReversePlatform(10, 27, 2, 1),
+		}}
+		unit := newUnit(200, 1)
+
+		unit.updatePhysics(stage)
+		if unit.Direction != -1 {
+			t.Fatalf("反転タイルの上で向きが反転するべき: %d", unit.Direction)
+		}
+		for i := 0; i < 5; i++ {
+			unit.updatePhysics(stage)
+		}
+		if unit.Direction != -1 {
+			t.Errorf("同じタイルの上では再度反転しないべき: %d", unit.Direction)
+		}
+	})
+
+	t.Run("ジャンプ台は通常のジャンプより強く跳ね上げる", func(t *testing.T) {
+		stage := &Stage{Platforms: []Platform{CreateGridSpringPlatform(5, 27, 3, 1)}}
+		unit := newUnit(120, 1)
+
+		unit.updatePhysics(stage)
+		if unit.OnGround || unit.VY != -SPRING_STRENGTH {
+			t.Errorf("ジャンプ台で跳ね上がるべき: VY=%v OnGround=%v", unit.VY, unit.OnGround)
+		}
+		if SPRING_STRENGTH <= JUMP_STRENGTH {
+			t.Error("ジャンプ台は通常のジャンプより強いべき")
+		}
+	})
+
+	t.Run("ベルトコンベアはキャラの向きに関係なく押し流す", func(t *testing.T) {
+		stage := &Stage{Platforms: []Platform{CreateGridConveyorPlatform(0, 27, 40, 1, -1)}}
+		right := newUnit(300, 1)
+		left := newUnit(500, -1)
+
+		right.updatePhysics(stage)
+		left.updatePhysics(stage)
+
+		if right.X != 300+SPEED-CONVEYOR_SPEED {
+			t.Errorf("右向きのキャラは減速するべき: X=%v", right.X)
+		}
+		if left.X != 500-SPEED-CONVEYOR_SPEED {
+			t.Errorf("左向きのキャラは加速するべき: X=%v", left.X)
+		}
+	})
+}
+
+func TestUnitColourGimmicks(t *testing.T) {
+	t.Run("キャラ専用の足場は同じ色のキャラだけが乗れる", func(t *testing.T) {
+		stage := &Stage{Platforms: []Platform{CreateGridUnitPlatform(5, 20, 6, 1, UnitBlue)}}
+		blue := &Unit{X: 140, Y: 378, VY: 5, Direction: 1, Kind: UnitBlue, SpeedModifier: 1.0}
+		red := &Unit{X: 140, Y: 378, VY: 5, Direction: 1, Kind: UnitRed, SpeedModifier: 1.0}
+
+		blue.updatePhysics(stage)
+		red.updatePhysics(stage)
+
+		if !blue.OnGround {
+			t.Error("青キャラは青専用の足場に着地するべき")
+		}
+		if red.OnGround {
+			t.Error("赤キャラは青専用の足場をすり抜けるべき")
+		}
+	})
+
+	t.Run("キャラ専用のトゲは同じ色のキャラにだけ当たる", func(t *testing.T) {
+		game := &Game{
+			BlueUnit: &Unit{X: 100, Y: 100, Kind: UnitBlue},
+			RedUnit:  &Unit{X: 100, Y: 100, Kind: UnitRed},
+			Stage:    &Stage{Spikes: []Spike{CreateGridUnitSpike(5, 5, UnitRed)}},
+		}
+
+		if dead := game.findDeadUnit(); dead != game.RedUnit {
+			t.Errorf("赤専用のトゲは赤キャラだけに当たるべき: %+v", dead)
+		}
+	})
+
+	t.Run("キャラ専用のゴールは同じ色のキャラでしかクリアにならない", func(t *testing.T) {
+		stage := &Stage{Platforms: []Platform{
+			CreateGridUnitGoalPlatform(5, 20, 2, 1, UnitBlue),
+			CreateGridUnitGoalPlatform(20, 20, 2, 1, UnitRed),
+		}}
+		game := &Game{
+			BlueUnit: &Unit{X: 400, Y: 400, Kind: UnitBlue, OnGround: true},
+			RedUnit:  &Unit{X: 100, Y: 400, Kind: UnitRed, OnGround: true},
+			Stage:    stage,
+		}
+		if game.checkCleared() {
+			t.Error("逆の色のゴールではクリアにならないべき")
+		}
+
+		game.BlueUnit.X, game.RedUnit.X = 100, 400
+		if !game.checkCleared() {
+			t.Error("それぞれの色のゴールに乗ればクリアになるべき")
+		}
+	})
+}
+
+func TestCollectiblesAndChallenges(t *testing.T) {
+	ground := Platform{X: 0, Y: 550, Width: 800, Height: 50, SpeedModifier: 1.0}
+
+	t.Run("どちらのキャラでもスターを取れてクリア時に数が記録される", func(t *testing.T) {
+		game := &Game{
+			BlueUnit: &Unit{X: 100, Y: 530, Direction: 1, Kind: UnitBlue, OnGround: true},
+			RedUnit:  &Unit{X: 600, Y: 530, Direction: -1, Kind: UnitRed, OnGround: true},
+			Stage: &Stage{
+				Platforms: []Platform{ground, {X: 300, Y: 530, Width: 200, Height: 20, IsGoal: true, SpeedModifier: 1.0}},
+				Collectibles: []Collectible{
+					{X: 100, Y: 530},
+					{X: 590, Y: 530},
+					{X: 300, Y: 100},
+				},
+			},
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+			Events:      NewEventBus(),
+			Stats:       NewStatsRecorder(),
+		}
+		game.Stats.SubscribeEvents(game.Events)
+		var collectedBy []UnitKind
+		game.Events.Subscribe(EventCollected, func(e Event) {
+			collectedBy = append(collectedBy, e.Unit)
+		})
+
+		game.stepSimulation(false, false)
+		if game.collectedCount() != 2 || len(collectedBy) != 2 {
+			t.Fatalf("両方のキャラがスターを取るべき: %d %v", game.collectedCount(), collectedBy)
+		}
+		game.stepSimulation(false, false)
+		if len(collectedBy) != 2 {
+			t.Error("同じスターは二度取れないべき")
+		}
+
+		for i := 0; i < 600 && game.State == StatePlaying; i++ {
+			game.stepSimulation(false, false)
+		}
+		if game.State != StateCleared {
+			t.Fatalf("ゴールでクリアになるべき: %v", game.State)
+		}
+		stats := game.Stats.StageStats(1)
+		if stats.BestCollected != 2 || stats.Collectibles != 3 {
+			t.Errorf("取ったスターの数が記録されるべき: %d/%d", stats.BestCollected, stats.Collectibles)
+		}
+	})
+
+	t.Run("アシストなしのクリアだけがチャレンジ達成になる", func(t *testing.T) {
+		saved := StageChallenges
+		t.Cleanup(func() { StageChallenges = saved })
+		StageChallenges = map[int][]Challenge{1: {
+			{Kind: ChallengeCollectAll},
+			{Kind: ChallengeTime, Limit: 8},
+			{Kind: ChallengeJumps, Limit: 2},
+		}}
+
+		stats := NewStatsRecorder()
+		stats.handleEvent(Event{Type: EventAttemptStarted, Stage: 1})
+		stats.handleEvent(Event{Type: EventJumped, Stage: 1})
+		stats.handleEvent(Event{Type: EventStageCleared, Stage: 1, Tick: 300, Collected: 2, Collectibles: 2, Assisted: true})
+
+		s := stats.StageStats(1)
+		for i := range StageChallenges[1] {
+			if s.ChallengeCompleted(i) {
+				t.Errorf("アシスト使用時はチャレンジ達成にならないべき: %d", i)
+			}
+		}
+
+		stats.handleEvent(Event{Type: EventAttemptStarted, Stage: 1})
+		for i := 0; i < 3; i++ {
+			stats.handleEvent(Event{Type: EventJumped, Stage: 1})
+		}
+		stats.handleEvent(Event{Type: EventStageCleared, Stage: 1, Tick: 300, Collected: 2, Collectibles: 2})
+
+		for i, challenge := range StageChallenges[1] {
+			want := challenge.Kind != ChallengeJumps
+			if s.ChallengeCompleted(i) != want {
+				t.Errorf("%s: 達成=%v が期待値 %v と異なる", challenge.Description(), s.ChallengeCompleted(i), want)
+			}
+		}
+	})
+
+	t.Run("スターのステージをジャンプだけで全部集めてクリアできる", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join("testdata", "star_trail.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stage, start, err := ParseStage(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game := &Game{
+			BlueUnit:    &Unit{X: start.BlueX, Y: start.BlueY, Direction: 1, VX: SPEED, Kind: UnitBlue, SpeedModifier: 1.0},
+			RedUnit:     &Unit{X: start.RedX, Y: start.RedY, Direction: -1, VX: -SPEED, Kind: UnitRed, SpeedModifier: 1.0},
+			Stage:       stage,
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+		}
+
+		// Jump just before a block or a pit in the walking direction
+		obstacleAhead := func(unit *Unit) bool {
+			if !unit.OnGround {
+				return false
+			}
+			for d := 1.0; d <= 8; d++ {
+				x := unit.X + UnitSize/2 + float64(unit.Direction)*(UnitSize/2+d)
+				if solidAt(stage, x, unit.Y+UnitSize/2) != nil || solidAt(stage, x, unit.Y+UnitSize+1) == nil {
+					return true
+				}
+			}
+			return false
+		}
+		for i := 0; i < 1200 && game.State == StatePlaying; i++ {
+			game.stepSimulation(obstacleAhead(game.BlueUnit), obstacleAhead(game.RedUnit))
+		}
+		if game.State != StateCleared {
+			t.Fatalf("クリアになるべき: state=%v blue=(%v, %v) red=(%v, %v)", game.State, game.BlueUnit.X, game.BlueUnit.Y, game.RedUnit.X, game.RedUnit.Y)
+		}
+		if game.collectedCount() != len(stage.Collectibles) || len(stage.Collectibles) != 4 {
+			t.Errorf("スターを全て取るべき: %d/%d", game.collectedCount(), len(stage.Collectibles))
+		}
+	})
+
+	t.Run("チャレンジの判定", func(t *testing.T) {
+		result := ClearResult{Ticks: 599, Jumps: 4, Collected: 1, Collectibles: 2}
+		tests := []struct {
+			challenge Challenge
+			want      bool
+		}{
+			{Challenge{Kind: ChallengeTime, Limit: 10}, true},
+			{Challenge{Kind: ChallengeTime, Limit: 9}, false},
+			{Challenge{Kind: ChallengeJumps, Limit: 4}, true},
+			{Challenge{Kind: ChallengeJumps, Limit: 3}, false},
+			{Challenge{Kind: ChallengeCollectAll}, false},
+		}
+		for _, tt := range tests {
+			if got := tt.challenge.Met(result); got != tt.want {
+				t.Errorf("%s: got %v, want %v", tt.challenge.Description(), got, tt.want)
+			}
+		}
+	})
+
+	t.Run("ステージ選択は未クリアの最初のステージを選び端で折り返す", func(t *testing.T) {
+		game := &Game{
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 10},
+			Stats:       NewStatsRecorder(),
+		}
+		game.Stats.StageStats(1).Clears = 1
+		game.Stats.StageStats(2).Clears = 3
+
+		game.openStageSelect()
+		if game.State != StateStageSelect || game.SelectedStage != 3 {
+			t.Fatalf("未クリアのステージ3が選ばれるべき: state=%v stage=%d", game.State, game.SelectedStage)
+		}
+		game.moveStageSelection(-3)
+		if game.SelectedStage != 10 {
+			t.Errorf("先頭から上に移動すると最後のステージになるべき: %d", game.SelectedStage)
+		}
+		if stage := game.stageSelectRowAt(stageSelectListX+10, stageSelectListY+stageSelectRowHeight+5); stage != 2 {
+			t.Errorf("2行目をタップするとステージ2になるべき: %d", stage)
+		}
+	})
+
+	t.Run("ステージ選択は選んだステージが見えるようにスクロールする", func(t *testing.T) {
+		game := &Game{
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 25},
+			Stats:       NewStatsRecorder(),
+		}
+		game.openStageSelect()
+		rowY := func(row int) int { return stageSelectListY + row*stageSelectRowHeight + 5 }
+
+		if stage := game.stageSelectRowAt(stageSelectListX+10, rowY(stageSelectRows)); stage != -1 {
+			t.Errorf("表示しきれない行はタップできないべき: %d", stage)
+		}
+		game.moveStageSelection(12)
+		if game.SelectedStage != 13 || game.stageSelectFirstRow() != 4 {
+			t.Errorf("選んだステージが最下行に来るべき: stage=%d top=%d", game.SelectedStage, game.stageSelectFirstRow())
+		}
+		if stage := game.stageSelectRowAt(stageSelectListX+10, rowY(stageSelectRows-1)); stage != 13 {
+			t.Errorf("最下行をタップすると選んだステージになるべき: %d", stage)
+		}
+		game.moveStageSelection(-13)
+		if game.SelectedStage != 25 || game.stageSelectFirstRow() != 16 {
+			t.Errorf("末尾に折り返すと最後のページが表示されるべき: stage=%d top=%d", game.SelectedStage, game.stageSelectFirstRow())
+		}
+
+		listBottom := stageSelectListY + stageSelectRows*stageSelectRowHeight
+		if direction := game.stageSelectScrollAt(stageSelectListX+10, listBottom+5); direction != 0 {
+			t.Errorf("最後のページでは下にスクロールできないべき: %d", direction)
+		}
+		if direction := game.stageSelectScrollAt(stageSelectListX+10, stageSelectListY-5); direction != -1 {
+			t.Fatalf("上の矢印で上にスクロールするべき: %d", direction)
+		}
+		game.scrollStageSelectPage(-1)
+		if game.stageSelectFirstRow() != 6 || game.SelectedStage != 6 {
+			t.Errorf("1ページ上に移り選択もそのページに移るべき: top=%d stage=%d", game.stageSelectFirstRow(), game.SelectedStage)
+		}
+	})
+}
+
+func TestHazards(t *testing.T) {
+	ground := Platform{X: 0, Y: 550, Width: 800, Height: 50, SpeedModifier: 1.0}
+	newGame := func(stage *Stage) *Game {
+		game := &Game{
+			BlueUnit:    &Unit{X: 20, Y: 530, Direction: 1, Kind: UnitBlue, OnGround: true},
+			RedUnit:     &Unit{X: 760, Y: 530, Direction: -1, Kind: UnitRed, OnGround: true},
+			Stage:       stage,
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+			Events:      NewEventBus(),
+		}
+		game.resetHazards()
+		return game
+	}
+
+	t.Run("歩く敵は足場の端で折り返す", func(t *testing.T) {
+		shelf := Platform{X: 100, Y: 300, Width: 100, Height: 20, SpeedModifier: 1.0}
+		game := newGame(&Stage{Platforms: []Platform{ground, shelf}, Hazards: []Hazard{{Kind: HazardWalker, X: 100, Y: 280, Direction: 1}}})
+
+		turned := false
+		for i := 0; i < 400; i++ {
+			game.updateHazards()
+			walker := game.hazards[0]
+			// Allow one step of overshoot for floating point drift
+			if walker.X < shelf.X-WalkerSpeed || walker.X+CellSize > shelf.X+shelf.Width+WalkerSpeed || walker.Y != 280 {
+				t.Fatalf("足場から落ちないべき: (%.1f, %.1f)", walker.X, walker.Y)
+			}
+			if walker.Direction < 0 {
+				turned = true
+			}
+		}
+		if !turned {
+			t.Error("端で向きを変えるべき")
+		}
+	})
+
+	t.Run("落下ブロックは真下を通ると落ち、着地後は無害になる", func(t *testing.T) {
+		game := newGame(&Stage{Platforms: []Platform{ground}, Hazards: []Hazard{CreateGridFallingBlock(5, 5)}})
+
+		game.BlueUnit.X = 40
+		game.updateHazards()
+		if game.hazards[0].Triggered {
+			t.Fatal("真下にいなければ落ちないべき")
+		}
+		game.BlueUnit.X = 95
+		game.updateHazards()
+		if !game.hazards[0].Triggered {
+			t.Fatal("真下を通ったら作動するべき")
+		}
+		game.BlueUnit.X = 20
+		for i := 0; i < 300 && !game.hazards[0].Landed; i++ {
+			game.updateHazards()
+		}
+		block := game.hazards[0]
+		if !block.Landed || block.Y != ground.Y-CellSize {
+			t.Fatalf("地面に着地するべき: landed=%v y=%.1f", block.Landed, block.Y)
+		}
+		game.BlueUnit.X = block.X
+		if game.hitByHazard(game.BlueUnit) {
+			t.Error("着地したブロックは無害になるべき")
+		}
+	})
+
+	t.Run("落下ブロックは間に足場があると下を通っても落ちない", func(t *testing.T) {
+		shelf := Platform{X: 80, Y: 200, Width: 80, Height: 20, SpeedModifier: 1.0}
+		game := newGame(&Stage{Platforms: []Platform{ground, shelf}, Hazards: []Hazard{CreateGridFallingBlock(5, 5)}})
+		game.BlueUnit.X = 95
+		game.updateHazards()
+		if game.hazards[0].Triggered {
+			t.Fatal("足場の下のキャラには作動しないべき")
+		}
+		game.BlueUnit.Y = shelf.Y - UnitSize
+		game.updateHazards()
+		if !game.hazards[0].Triggered {
+			t.Error("足場の上で真下に来たら作動するべき")
+		}
+	})
+
+	t.Run("レーザーはオンの間だけ当たり、足場で止まる", func(t *testing.T) {
+		shelf := Platform{X: 100, Y: 400, Width: 100, Height: 20, SpeedModifier: 1.0}
+		game := newGame(&Stage{Platforms: []Platform{ground, shelf}, Hazards: []Hazard{CreateGridLaser(6, 5)}})
+		if game.hazards[0].BeamEnd != shelf.Y {
+			t.Fatalf("ビームは足場で止まるべき: %.1f", game.hazards[0].BeamEnd)
+		}
+
+		game.BlueUnit.X, game.BlueUnit.Y = 120, 300
+		game.Tick = 0
+		if !game.hitByHazard(game.BlueUnit) {
+			t.Error("オンのビームに触れたらやられるべき")
+		}
+		game.Tick = LaserOnTicks
+		if game.hitByHazard(game.BlueUnit) {
+			t.Error("オフのビームは無害なべき")
+		}
+		game.Tick = 0
+		game.BlueUnit.Y = 420
+		if game.hitByHazard(game.BlueUnit) {
+			t.Error("足場より下にはビームが届かないべき")
+		}
+	})
+
+	t.Run("砲台の弾は一定間隔で発射され、壁で消える", func(t *testing.T) {
+		wall := Platform{X: 300, Y: 400, Width: 20, Height: 150, SpeedModifier: 1.0}
+		game := newGame(&Stage{Platforms: []Platform{ground, wall}, Hazards: []Hazard{CreateGridTurret(5, 25, 1)}})
+
+		for i := 0; i < TurretInterval-1; i++ {
+			game.updateHazards()
+		}
+		if len(game.projectiles) != 0 {
+			t.Fatal("間隔が来るまでは撃たないべき")
+		}
+		game.updateHazards()
+		if len(game.projectiles) != 1 {
+			t.Fatalf("間隔ごとに1発撃つべき: %d", len(game.projectiles))
+		}
+
+		shot := game.projectiles[0]
+		game.BlueUnit.X, game.BlueUnit.Y = shot.X+30, 495
+		if game.hitByHazard(game.BlueUnit) {
+			t.Fatal("弾が届く前に当たらないべき")
+		}
+		for i := 0; i < 10; i++ {
+			game.updateHazards()
+		}
+		if !game.hitByHazard(game.BlueUnit) {
+			t.Error("弾に当たったらやられるべき")
+		}
+
+		game.BlueUnit.Y = 530
+		for i := 0; i < 60; i++ {
+			game.updateHazards()
+		}
+		if len(game.projectiles) != 0 {
+			t.Errorf("弾は壁で消えるべき: %d", len(game.projectiles))
+		}
+	})
+
+	t.Run("敵に触れるとゲームオーバー、トゲ無効モードでも同じ", func(t *testing.T) {
+		game := newGame(&Stage{Platforms: []Platform{ground}, Hazards: []Hazard{{Kind: HazardWalker, X: 60, Y: 530, Direction: -1}}})
+		for i := 0; i < 30 && game.State == StatePlaying; i++ {
+			game.stepSimulation(false, false)
+		}
+		if game.State != StateGameOver {
+			t.Fatalf("敵に触れたらゲームオーバーになるべき: %v", game.State)
+		}
+
+		game = newGame(&Stage{Platforms: []Platform{ground}, Hazards: []Hazard{{Kind: HazardWalker, X: 60, Y: 530, Direction: -1}}})
+		game.Assist.NoSpikeDeath = true
+		for i := 0; i < 30 && game.State == StatePlaying; i++ {
+			game.stepSimulation(false, false)
+		}
+		if game.State != StateGameOver {
+			t.Errorf("トゲ無効モードはトゲだけに効き、敵ではやられるべき: %v", game.State)
+		}
+	})
+}
+
+func TestSpikeOrientation(t *testing.T) {
+	spikeAt := func(direction SpikeDirection) Spike {
+		return CreateGridSpikeFacing(10, 10, direction)
+	}
+	game := &Game{}
+	unitAt := func(x, y float64) *Unit {
+		return &Unit{X: x, Y: y, Kind: UnitBlue}
+	}
+
+	t.Run("トゲの三角形の外側の角に触れてもやられない", func(t *testing.T) {
+		// Each case overlaps only a 5x5 corner of the spike's cell outside the triangle
+		cases := []struct {
+			direction SpikeDirection
+			x, y      float64
+		}{
+			{SpikeUp, 200 - 15, 200 - 15},
+			{SpikeUp, 200 + 15, 200 - 15},
+			{SpikeDown, 200 - 15, 200 + 15},
+			{SpikeDown, 200 + 15, 200 + 15},
+			{SpikeLeft, 200 - 15, 200 - 15},
+			{SpikeLeft, 200 - 15, 200 + 15},
+			{SpikeRight, 200 + 15, 200 - 15},
+			{SpikeRight, 200 + 15, 200 + 15},
+		}
+		for _, c := range cases {
+			if game.checkUnitSpikeCollision(unitAt(c.x, c.y), spikeAt(c.direction)) {
+				t.Errorf("向き%dのトゲの空白の角では当たらないべき: (%.0f, %.0f)", c.direction, c.x, c.y)
+			}
+		}
+	})
+
+	t.Run("トゲの先端と底辺には当たる", func(t *testing.T) {
+		cases := []struct {
+			direction SpikeDirection
+			x, y      float64
+		}{
+			{SpikeUp, 200, 200 - 18},   // Tip from above
+			{SpikeUp, 200 - 15, 215},   // Base from the side
+			{SpikeDown, 200, 200 + 18}, // Tip from below
+			{SpikeLeft, 200 - 18, 200}, // Tip from the left
+			{SpikeRight, 200 + 18, 200},
+		}
+		for _, c := range cases {
+			if !game.checkUnitSpikeCollision(unitAt(c.x, c.y), spikeAt(c.direction)) {
+				t.Errorf("向き%dのトゲに当たるべき: (%.0f, %.0f)", c.direction, c.x, c.y)
+			}
+		}
+	})
+
+	t.Run("トゲ無効モードでは壁のトゲから押し返されて向きが変わる", func(t *testing.T) {
+		game := &Game{
+			BlueUnit: &Unit{X: 182, Y: 200, Direction: 1, Kind: UnitBlue},
+			RedUnit:  &Unit{X: 600, Y: 500, Direction: -1, Kind: UnitRed},
+			Stage:    &Stage{Spikes: []Spike{spikeAt(SpikeLeft)}},
+			Events:   NewEventBus(),
+		}
+		game.bounceOffSpikes()
+		if game.BlueUnit.Direction != -1 || game.BlueUnit.X != 200-UnitSize {
+			t.Errorf("左向きのトゲからは左へ押し返されるべき: x=%.1f dir=%d", game.BlueUnit.X, game.BlueUnit.Direction)
+		}
+	})
+}
+
+func TestDebugOverlay(t *testing.T) {
+	t.Run("キャラの状態フラグを表示用の文字列にする", func(t *testing.T) {
+		cases := []struct {
+			unit Unit
+			want string
+		}{
+			{Unit{SpeedModifier: 1.0}, ""},
+			{Unit{OnGround: true, SpeedModifier: 1.0}, "G"},
+			{Unit{OnGround: true, Stopped: true, SpeedModifier: 1.3}, "GS x1.3"},
+		}
+		for _, c := range cases {
+			if got := unitDebugFlags(&c.unit); got != c.want {
+				t.Errorf("フラグ表示が違う: got %q, want %q", got, c.want)
+			}
+		}
+	})
+
+	t.Run("HUDの行に位置・速度・状態が含まれる", func(t *testing.T) {
+		unit := &Unit{X: 120, Y: 530, VX: 1.5, VY: -2, OnGround: true, SpeedModifier: 0.7}
+		want := "Blue (120,530) v(+1.50,-2.00) ground x0.70"
+		if got := unitDebugLine("Blue", unit); got != want {
+			t.Errorf("HUDの行が違う: got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("F3キーは設定キーとして扱いゲームを開始しない", func(t *testing.T) {
+		if hasStartKey([]ebiten.Key{DebugOverlayKey}) {
+			t.Error("デバッグ表示キーでゲームが始まってはいけない")
+		}
+	})
+}
+
+func TestFrameStep(t *testing.T) {
+	oldDebugMode := DebugMode
+	DebugMode = true
+	defer func() { DebugMode = oldDebugMode }()
+
+	ground := Platform{X: 0, Y: 550, Width: 800, Height: 50, SpeedModifier: 1.0}
+	newGame := func() *Game {
+		game := &Game{
+			BlueUnit:    &Unit{X: 100, Y: 530, Direction: 1, Kind: UnitBlue, OnGround: true, SpeedModifier: 1.0},
+			RedUnit:     &Unit{X: 600, Y: 530, Direction: -1, Kind: UnitRed, OnGround: true, SpeedModifier: 1.0},
+			Stage:       &Stage{Platforms: []Platform{ground}, Hazards: []Hazard{CreateGridTurret(1, 20, 1)}},
+			State:       StatePlaying,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+			Assist:      DefaultAssistOptions(),
+			Events:      NewEventBus(),
+		}
+		game.resetHazards()
+		return game
+	}
+
+	t.Run("リングバッファは古いスナップショットから捨てる", func(t *testing.T) {
+		var ring snapshotRing
+		for tick := 0; tick < frameHistorySize+5; tick++ {
+			ring.push(GameSnapshot{Tick: tick})
+		}
+		if ring.count != frameHistorySize {
+			t.Fatalf("容量を超えて溜まらないべき: %d", ring.count)
+		}
+		for want := frameHistorySize + 4; want >= 5; want-- {
+			snapshot, ok := ring.pop()
+			if !ok || snapshot.Tick != want {
+				t.Fatalf("新しい順に取り出すべき: got %d, want %d", snapshot.Tick, want)
+			}
+		}
+		if _, ok := ring.pop(); ok {
+			t.Error("空になったら取り出せないべき")
+		}
+	})
+
+	t.Run("巻き戻してから進めると同じ状態になる", func(t *testing.T) {
+		game := newGame()
+		for i := 0; i < TurretInterval+20; i++ {
+			game.advanceSimulation(i == 30, false)
+		}
+		want := game.Snapshot()
+
+		game.handleFrameStepKeys(true, false, false)
+		for i := 0; i < 40; i++ {
+			game.handleFrameStepKeys(false, false, true)
+		}
+		if game.Tick != want.Tick-40 {
+			t.Fatalf("40ティック巻き戻るべき: %d", game.Tick)
+		}
+		if len(game.projectiles) != 0 {
+			t.Errorf("発射前まで戻れば弾は無いべき: %d", len(game.projectiles))
+		}
+		for i := 0; i < 40; i++ {
+			game.handleFrameStepKeys(false, true, false)
+		}
+		got := game.Snapshot()
+		if got.Tick != want.Tick || got.Blue != want.Blue || got.Red != want.Red || len(got.projectiles) != len(want.projectiles) || got.projectiles[0] != want.projectiles[0] {
+			t.Errorf("同じ入力で進め直すと同じ状態になるべき:\ngot  %+v\nwant %+v", got, want)
+		}
+	})
+
+	t.Run("巻き戻すと統計とリプレイも戻る", func(t *testing.T) {
+		game := newGame()
+		game.BlueUnit.Events = game.Events
+		game.RedUnit.Events = game.Events
+		game.Stats = NewStatsRecorder()
+		game.Stats.SubscribeEvents(game.Events)
+		game.Replay = NewReplayRecorder()
+		game.Replay.SubscribeEvents(game.Events)
+		game.beginAttempt()
+		for i := 0; i < 120; i++ {
+			game.advanceSimulation(i == 10 || i == 100, false)
+		}
+		wantStats := *game.Stats.StageStats(1)
+		wantInputs := append([]ReplayInput(nil), game.Replay.current.Inputs...)
+		wantSafePoints := append([]safePoint(nil), game.safePoints...)
+
+		game.handleFrameStepKeys(true, false, false)
+		for i := 0; i < 40; i++ {
+			game.handleFrameStepKeys(false, false, true)
+		}
+		if got := len(game.Replay.current.Inputs); got != 1 {
+			t.Errorf("巻き戻したジャンプはリプレイから消えるべき: %d", got)
+		}
+		for i := 0; i < 40; i++ {
+			game.pendingBlueJump = game.Tick == 100
+			game.handleFrameStepKeys(false, true, false)
+		}
+		if got := *game.Stats.StageStats(1); got.Jumps != wantStats.Jumps || got.Attempts != wantStats.Attempts {
+			t.Errorf("進め直しても統計は二重に数えないべき:\ngot  %+v\nwant %+v", got, wantStats)
+		}
+		if !reflect.DeepEqual(game.Replay.current.Inputs, wantInputs) {
+			t.Errorf("リプレイの入力が一致しない:\ngot  %+v\nwant %+v", game.Replay.current.Inputs, wantInputs)
+		}
+		if !reflect.DeepEqual(game.safePoints, wantSafePoints) {
+			t.Errorf("セーフポイントが一致しない:\ngot  %+v\nwant %+v", game.safePoints, wantSafePoints)
+		}
+	})
+
+	t.Run("一時停止中はステップキーで1ティックずつ進む", func(t *testing.T) {
+		game := newGame()
+		game.handleFrameStepKeys(true, false, false)
+		game.handleFrameStepKeys(false, true, false)
+		if game.Tick != 1 {
+			t.Fatalf("ステップキーで1ティックだけ進むべき: %d", game.Tick)
+		}
+		game.handleFrameStepKeys(true, false, false)
+		if game.frameStep.paused {
+			t.Error("もう一度押すと再開するべき")
+		}
+	})
+
+	t.Run("ゲームオーバーから巻き戻すとプレイ中に戻る", func(t *testing.T) {
+		game := newGame()
+		game.Stage.Spikes = []Spike{{X: 130, Y: 530}}
+		for i := 0; i < 60 && game.State == StatePlaying; i++ {
+			game.advanceSimulation(false, false)
+		}
+		if game.State != StateGameOver {
+			t.Fatalf("トゲでゲームオーバーになるべき: %v", game.State)
+		}
+		game.handleFrameStepKeys(true, false, true)
+		if game.State != StatePlaying || game.checkGameOver() {
+			t.Errorf("死亡直前に戻るべき: %v", game.State)
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("コマンドライン引数を読み込む", func(t *testing.T) {
+		cfg, err := ParseConfigArgs(DefaultConfig(), []string{"-debug", "-stage", "3", "-stages", "levels/", "-mute", "-replay", "best.json", "-fullscreen", "-scale", "2", "-lang", "ja"}, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Config{Debug: true, Stage: 3, StagesDir: "levels/", Mute: true, ReplayFile: "best.json", Fullscreen: true, Scale: 2, Language: stagefile.Japanese}
+		if cfg != want {
+			t.Errorf("設定が一致しない:\ngot  %+v\nwant %+v", cfg, want)
+		}
+	})
+
+	t.Run("指定が無ければ既定値のまま", func(t *testing.T) {
+		cfg, err := ParseConfigArgs(DefaultConfig(), nil, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg != DefaultConfig() {
+			t.Errorf("既定値のままであるべき: %+v", cfg)
+		}
+	})
+
+	t.Run("URLパラメータを同じ設定として読み込む", func(t *testing.T) {
+		query, _ := url.ParseQuery("debug=1&stage=3&mute&scale=1.5&utm_source=x")
+		cfg, err := ParseConfigQuery(DefaultConfig(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Config{Debug: true, Stage: 3, Mute: true, Scale: 1.5, Language: stagefile.English}
+		if cfg != want {
+			t.Errorf("設定が一致しない:\ngot  %+v\nwant %+v", cfg, want)
+		}
+	})
+
+	t.Run("不正な値はエラーになる", func(t *testing.T) {
+		if _, err := ParseConfigArgs(DefaultConfig(), []string{"-scale", "0"}, io.Discard); err == nil {
+			t.Error("倍率0はエラーになるべき")
+		}
+		if _, err := ParseConfigArgs(DefaultConfig(), []string{"-stage", "x"}, io.Discard); err == nil {
+			t.Error("数値でないステージ番号はエラーになるべき")
+		}
+		query, _ := url.ParseQuery("stage=-2")
+		if _, err := ParseConfigQuery(DefaultConfig(), query); err == nil {
+			t.Error("負のステージ番号はエラーになるべき")
+		}
+		if _, err := ParseConfigArgs(DefaultConfig(), []string{"-stage", strconv.Itoa(lastBuiltinStage + 1)}, io.Discard); err == nil {
+			t.Error("組み込みステージに無いステージ番号はエラーになるべき")
+		}
+	})
+
+	t.Run("ステージディレクトリの番号は読み込み後に確認する", func(t *testing.T) {
+		cfg, err := ParseConfigArgs(DefaultConfig(), []string{"-stages", "levels/", "-stage", "20"}, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.CheckStage(20) != nil || cfg.CheckStage(19) == nil {
+			t.Error("最後のステージより後の番号だけがエラーになるべき")
+		}
+	})
+}
+
+func TestStageSource(t *testing.T) {
+	t.Run("ステージファイルから生成コードと同じステージを作る", func(t *testing.T) {
+		source, err := NewDirStageSource(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if source.LastStage() != 10 {
+			t.Errorf("最後のステージは10であるべき: %d", source.LastStage())
+		}
+		builtin := &StageLoader{}
+		for index := 0; index <= source.LastStage(); index++ {
+			stage, start, err := source.LoadStage(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := builtin.LoadStage(index); !reflect.DeepEqual(stage, want) {
+				t.Errorf("ステージ%dが生成コードと一致しない", index)
+			}
+			builtin.CurrentStageIndex = index
+			blueX, blueY, redX, redY := builtin.GetCurrentStageStartPositions()
+			if start != (StartPositions{blueX, blueY, redX, redY}) {
+				t.Errorf("ステージ%dの開始位置が一致しない: %+v", index, start)
+			}
+		}
+	})
+
+	t.Run("読み込めないステージは組み込みステージを使う", func(t *testing.T) {
+		loader := &StageLoader{CurrentStageIndex: 1}
+		loader.SetSource(&DirStageSource{Dir: "missing", files: map[int]string{2: "missing/stage02.txt"}})
+		if loader.TotalStages != 2 {
+			t.Errorf("ステージ数はソースから決まるべき: %d", loader.TotalStages)
+		}
+		if !reflect.DeepEqual(loader.GetCurrentStage(), LoadStage1()) {
+			t.Error("ソースに無いステージは組み込みステージになるべき")
+		}
+	})
+
+	t.Run("不明な文字はエラーになる", func(t *testing.T) {
+		if _, _, err := ParseStage([]byte("..L..\n..?..\n")); err == nil {
+			t.Error("不明な文字はエラーになるべき")
+		}
+	})
+}
+
+func TestReplayPlayback(t *testing.T) {
+	replay, err := ParseReplay([]byte(`{"stage":1,"inputs":[{"tick":5,"unit":1},{"tick":9,"unit":2}],"ticks":20,"cleared":false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ground := Platform{X: 0, Y: 550, Width: 800, Height: 50, SpeedModifier: 1.0}
+	events := NewEventBus()
+	game := &Game{
+		BlueUnit:    &Unit{X: 100, Y: 530, Direction: 1, Kind: UnitBlue, OnGround: true, SpeedModifier: 1.0, Events: events},
+		RedUnit:     &Unit{X: 600, Y: 530, Direction: -1, Kind: UnitRed, OnGround: true, SpeedModifier: 1.0, Events: events},
+		Stage:       &Stage{Platforms: []Platform{ground}},
+		State:       StatePlaying,
+		StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 1},
+		Assist:      DefaultAssistOptions(),
+		Events:      events,
+		Playback:    replay,
+	}
+	recorder := NewReplayRecorder()
+	recorder.SavePath = filepath.Join(t.TempDir(), "replay.json")
+	recorder.SubscribeEvents(events)
+	game.beginAttempt()
+	for i := 0; i < 20; i++ {
+		// Keyboard input is ignored while a replay plays back
+		game.stepSimulation(true, true)
+	}
+	game.Events.Emit(Event{Type: EventDied})
+	if got := recorder.LastCompleted; got == nil || !reflect.DeepEqual(got.Inputs, replay.Inputs) {
+		t.Errorf("リプレイの入力が再現されるべき: %+v", got)
+	}
+
+	// The saved attempt plays back with -replay
+	data, err := os.ReadFile(recorder.SavePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ParseReplay(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, recorder.LastCompleted) {
+		t.Errorf("保存したリプレイが記録と一致しない: %+v", saved)
+	}
+}
+
+func TestHotReload(t *testing.T) {
+	original, err := os.ReadFile("stage01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stage01.txt")
+	modTime := time.Now()
+	save := func(data []byte) {
+		t.Helper()
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Make every save visible even on file systems with coarse timestamps
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(original)
+
+	source, err := NewDirStageSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := &StageLoader{CurrentStageIndex: 1}
+	loader.SetSource(source)
+	events := NewEventBus()
+	game := &Game{
+		BlueUnit:     &Unit{Kind: UnitBlue, Events: events},
+		RedUnit:      &Unit{Kind: UnitRed, Events: events},
+		StageLoader:  loader,
+		Assist:       DefaultAssistOptions(),
+		Events:       events,
+		StageWatcher: NewStageWatcher(source),
+	}
+	game.resetGame()
+	poll := func() {
+		game.StageWatcher.timer = hotReloadInterval - 1
+		game.updateHotReload()
+	}
+
+	t.Run("保存するとステージと開始位置が入れ替わる", func(t *testing.T) {
+		before := game.Stage
+		moved := bytes.Replace(original, []byte("L."), []byte(".L"), 1)
+		save(moved)
+		poll()
+		if game.Stage == before {
+			t.Error("新しいステージに入れ替わるべき")
+		}
+		if game.BlueUnit.X != GridToPixelX(2) || game.Tick != 0 {
+			t.Errorf("新しい開始位置からやり直すべき: x=%.0f tick=%d", game.BlueUnit.X, game.Tick)
+		}
+	})
+
+	t.Run("構文エラーはバナーに出して前のステージを使い続ける", func(t *testing.T) {
+		before := game.Stage
+		save(append([]byte("?"), original[1:]...))
+		poll()
+		if game.StageWatcher.Err == nil {
+			t.Fatal("エラーが記録されるべき")
+		}
+		if game.Stage != before || loader.GetCurrentStage() != before {
+			t.Error("壊れたファイルは読み込まずに前のステージを使うべき")
+		}
+
+		save(original)
+		poll()
+		if game.StageWatcher.Err != nil {
+			t.Errorf("直したらエラーは消えるべき: %v", game.StageWatcher.Err)
+		}
+	})
+
+	t.Run("変更が無ければ再読み込みしない", func(t *testing.T) {
+		before := game.Stage
+		poll()
+		if game.Stage != before {
+			t.Error("変更の無いファイルは再読み込みしないべき")
+		}
+	})
+}
+
+func TestStagePack(t *testing.T) {
+	stage1, _ := os.ReadFile("stage01.txt")
+	stage2, _ := os.ReadFile("stage02.txt")
+	manifest := []byte(`{"name": "Test Pack", "author": "tester", "stages": ["b.txt", "a.txt"]}`)
+	packFS := fstest.MapFS{
+		"pack.json": {Data: manifest},
+		"a.txt":     {Data: stage1},
+		"b.txt":     {Data: stage2},
+	}
+
+	t.Run("マニフェストの順にステージを並べる", func(t *testing.T) {
+		pack, err := LoadStagePack(packFS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pack.Manifest.Name != "Test Pack" || pack.Manifest.Author != "tester" || pack.LastStage() != 2 {
+			t.Errorf("マニフェストが読み込まれるべき: %+v", pack.Manifest)
+		}
+		first, _, _ := pack.LoadStage(1)
+		second, _, _ := pack.LoadStage(2)
+		if !reflect.DeepEqual(first, LoadStage2()) || !reflect.DeepEqual(second, LoadStage1()) {
+			t.Error("マニフェストの順に並ぶべき")
+		}
+		if _, _, err := pack.LoadStage(3); err == nil {
+			t.Error("パックに無いステージはエラーになるべき")
+		}
+	})
+
+	t.Run("フォルダごと圧縮したzipを読み込める", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, file := range packFS {
+			f, _ := w.Create("testpack/" + name)
+			f.Write(file.Data)
+		}
+		w.Close()
+
+		pack, err := LoadDroppedStagePack(fstest.MapFS{"testpack.zip": {Data: buf.Bytes()}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pack.LastStage() != 2 {
+			t.Errorf("2ステージ読み込まれるべき: %d", pack.LastStage())
+		}
+	})
+
+	t.Run("壊れたパックはエラーになる", func(t *testing.T) {
+		if _, err := LoadStagePack(fstest.MapFS{"a.txt": {Data: stage1}}); err == nil {
+			t.Error("マニフェストが無ければエラーになるべき")
+		}
+		broken := fstest.MapFS{"pack.json": {Data: manifest}, "a.txt": {Data: stage1}, "b.txt": {Data: []byte("L?R\n")}}
+		if _, err := LoadStagePack(broken); err == nil || !strings.Contains(err.Error(), "b.txt") {
+			t.Errorf("壊れたステージファイル名を含むエラーになるべき: %v", err)
+		}
+	})
+
+	t.Run("読み込むとステージ数がパックから決まる", func(t *testing.T) {
+		pack, _ := LoadStagePack(packFS)
+		game := &Game{
+			State:       StateStageSelect,
+			StageLoader: &StageLoader{CurrentStageIndex: 5, TotalStages: 10},
+		}
+		game.loadStagePack(pack)
+		if game.StageLoader.TotalStages != 2 || game.StageLoader.CurrentStageIndex != 1 || game.SelectedStage != 1 {
+			t.Errorf("パックの1面から始まるべき: %+v selected=%d", game.StageLoader, game.SelectedStage)
+		}
+		if game.StageLoader.NextStage(); game.StageLoader.NextStage() {
+			t.Error("パックの最終ステージより先には進まないべき")
+		}
+	})
+
+	t.Run("パックの成績は組み込みステージと別に記録されチャレンジも付かない", func(t *testing.T) {
+		saved := StageChallenges
+		t.Cleanup(func() { StageChallenges = saved })
+		StageChallenges = map[int][]Challenge{1: {{Kind: ChallengeJumps, Limit: 2}}}
+
+		pack, _ := LoadStagePack(packFS)
+		game := &Game{
+			State:       StateStageSelect,
+			StageLoader: &StageLoader{CurrentStageIndex: 1, TotalStages: 10},
+			Stats:       NewStatsRecorder(),
+		}
+		game.Stats.StageStats(1).Clears = 3
+		game.loadStagePack(pack)
+		game.Stats.handleEvent(Event{Type: EventAttemptStarted, Stage: 1})
+		game.Stats.handleEvent(Event{Type: EventStageCleared, Stage: 1, Tick: 100})
+
+		if builtin := game.Stats.Stages[1]; builtin.Clears != 3 || builtin.Attempts != 0 {
+			t.Errorf("組み込みステージ1の成績が変わらないべき: %+v", builtin)
+		}
+		if stats := game.stageStats(1); stats.Clears != 1 || stats.ChallengeCompleted(0) {
+			t.Errorf("パックのステージ1として記録されチャレンジは達成にならないべき: %+v", stats)
+		}
+		if len(game.stageChallenges(1)) != 0 {
+			t.Error("パックのステージにはチャレンジが表示されないべき")
+		}
+
+		other, _ := LoadStagePack(fstest.MapFS{
+			StagePackManifestName: {Data: []byte(`{"name": "Test Pack", "stages": ["b.txt"]}`)},
+			"b.txt":               packFS["b.txt"],
+		})
+		game.loadStagePack(other)
+		if stats := game.stageStats(1); stats.Clears != 0 {
+			t.Errorf("同じ名前でも中身の違うパックは別に記録されるべき: %+v", stats)
+		}
+	})
+}
+
+func TestStageCode(t *testing.T) {
+	loader := &StageLoader{}
+
+	t.Run("ステージをコードにして元に戻せる", func(t *testing.T) {
+		for index := 0; index <= lastBuiltinStage; index++ {
+			stage := loader.LoadStage(index)
+			blueX, blueY, redX, redY := loader.StageStartPositions(index)
+			start := StartPositions{blueX, blueY, redX, redY}
+
+			data, err := os.ReadFile(fmt.Sprintf("stage%02d.txt", index))
+			if err != nil {
+				t.Fatal(err)
+			}
+			original, err := stagefile.Parse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := strings.Join(StageGrid(stage, start), "\n"), strings.Join(stagefile.Format(original), "\n"); got != want {
+				t.Errorf("ステージ%dのグリッドが元のファイルと一致しない:\n%s", index, got)
+			}
+
+			code := StageCode(stage, start)
+			if strings.ContainsAny(code, "+/=") {
+				t.Errorf("URLで使える文字だけであるべき: %s", code)
+			}
+			decoded, decodedStart, err := ParseStageCode(code)
+			if err != nil {
+				t.Fatalf("ステージ%d: %v", index, err)
+			}
+			// Codes hold the grid only, not the front matter
+			stage.Info = StageInfo{}
+			if !reflect.DeepEqual(decoded, stage) || decodedStart != start {
+				t.Errorf("ステージ%dがコードから復元されるべき", index)
+			}
+		}
+	})
+
+	t.Run("壊れたコードはエラーになる", func(t *testing.T) {
+		code := StageCode(LoadStage1(), StartPositions{20, 560, 760, 560})
+		tampered := []byte(code)
+		tampered[len(tampered)/2] ^= 1
+		if _, _, err := ParseStageCode(string(tampered)); !errors.Is(err, stagefile.ErrCodeChecksum) && !errors.Is(err, stagefile.ErrInvalidCode) {
+			t.Errorf("書き換えたコードはチェックサムで検出されるべき: %v", err)
+		}
+		if _, _, err := ParseStageCode(code[:len(code)-6]); err == nil {
+			t.Error("途中で切れたコードはエラーになるべき")
+		}
+		if _, _, err := ParseStageCode("not a code!"); !errors.Is(err, stagefile.ErrInvalidCode) {
+			t.Errorf("コードでない文字列はエラーになるべき: %v", err)
+		}
+	})
+
+	t.Run("URLのstageにコードを指定できる", func(t *testing.T) {
+		code := StageCode(LoadStage1(), StartPositions{20, 560, 760, 560})
+		cfg, err := ParseConfigQuery(DefaultConfig(), url.Values{"stage": {code}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.StageCode != code || cfg.Stage != -1 {
+			t.Errorf("数値でないstageはコードとして読むべき: %+v", cfg)
+		}
+	})
+
+	t.Run("コードのステージを1ステージだけ遊ぶ", func(t *testing.T) {
+		events := NewEventBus()
+		game := &Game{
+			BlueUnit:     &Unit{Kind: UnitBlue, Events: events},
+			RedUnit:      &Unit{Kind: UnitRed, Events: events},
+			State:        StateStageSelect,
+			StageLoader:  &StageLoader{CurrentStageIndex: 3, TotalStages: 10},
+			SoundManager: &SoundManager{},
+			Assist:       DefaultAssistOptions(),
+			Events:       events,
+		}
+		want := LoadStage2()
+		want.Info = StageInfo{}
+		game.playStageCode(StageCode(want, StartPositions{20, 560, 760, 560}))
+		if game.State != StatePlaying || game.StageLoader.TotalStages != 1 || !reflect.DeepEqual(game.Stage, want) {
+			t.Errorf("コードのステージが始まるべき: state=%v total=%d", game.State, game.StageLoader.TotalStages)
+		}
+	})
+
+	t.Run("コードのステージは成績に残さずクリア後は組み込みステージに戻る", func(t *testing.T) {
+		saved := StageChallenges
+		t.Cleanup(func() { StageChallenges = saved })
+		StageChallenges = map[int][]Challenge{1: {{Kind: ChallengeJumps, Limit: 2}}}
+
+		events := NewEventBus()
+		game := &Game{
+			BlueUnit:     &Unit{Kind: UnitBlue, Events: events},
+			RedUnit:      &Unit{Kind: UnitRed, Events: events},
+			State:        StateStageSelect,
+			StageLoader:  &StageLoader{CurrentStageIndex: 3, TotalStages: 10},
+			SoundManager: &SoundManager{},
+			Assist:       DefaultAssistOptions(),
+			Events:       events,
+			Stats:        NewStatsRecorder(),
+		}
+		game.Stats.SubscribeEvents(events)
+		game.Stats.StageStats(1).Clears = 2
+
+		game.playStageCode(StageCode(LoadStage2(), StartPositions{20, 560, 760, 560}))
+		events.Emit(Event{Type: EventStageCleared})
+		if stats := game.stageStats(1); stats.Clears != 1 || stats.ChallengeCompleted(0) {
+			t.Errorf("コードのステージの成績は別に数えチャレンジは付かないべき: %+v", stats)
+		}
+
+		game.advanceToNextStageOrRestart()
+		if game.StageLoader.Source != nil || game.StageLoader.TotalStages != lastBuiltinStage || game.State != StateStageSelect {
+			t.Errorf("クリア後は組み込みステージの選択画面に戻るべき: state=%v %+v", game.State, game.StageLoader)
+		}
+		if builtin := game.stageStats(1); builtin.Clears != 2 || builtin.Attempts != 0 {
+			t.Errorf("組み込みステージ1の成績が変わらないべき: %+v", builtin)
+		}
+	})
+}
+
+func TestStageFrontMatter(t *testing.T) {
+	grid := []string{
+		"OOOOO",
+		"L.*.R",
+		"OGGGO",
+	}
+	header := []string{
+		"title: Spring Tour",
+		"author: pankona",
+		"difficulty: 3",
+		"par_time: 12.5",
+		"bgm: spring.mp3",
+		"tutorial: Jump on the pads",
+		"tutorial: to reach the goal",
+	}
+	file := append(append(append([]string{}, header...), ""), grid...)
+
+	t.Run("ステージに引き継がれ、開始時にタイトルカードが出る", func(t *testing.T) {
+		stage, _, err := ParseStage([]byte(strings.Join(file, "\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantInfo := StageInfo{Title: "Spring Tour", Author: "pankona", Difficulty: 3, ParTime: 12.5, BGM: "spring.mp3", Tutorial: "Jump on the pads\nto reach the goal"}
+		if stage.Info != wantInfo {
+			t.Errorf("ステージにメタデータが引き継がれるべき: %+v", stage.Info)
+		}
+
+		manifest := []byte(`{"name":"Tour","author":"pankona","stages":["a.txt","b.txt"]}`)
+		pack, err := LoadStagePack(fstest.MapFS{
+			"pack.json": {Data: manifest},
+			"a.txt":     {Data: []byte(strings.Join(file, "\n"))},
+			"b.txt":     {Data: []byte(strings.Join(grid, "\n"))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := NewEventBus()
+		game := &Game{
+			BlueUnit:     &Unit{Kind: UnitBlue, Events: events},
+			RedUnit:      &Unit{Kind: UnitRed, Events: events},
+			State:        StateStageSelect,
+			StageLoader:  &StageLoader{CurrentStageIndex: 1, TotalStages: 10},
+			SoundManager: &SoundManager{},
+			Events:       events,
+		}
+		game.loadStagePack(pack)
+		game.startStage(1)
+		if game.TitleCardTimer != TitleCardFrames {
+			t.Errorf("タイトルカードが表示されるべき: %d", game.TitleCardTimer)
+		}
+		if lines := game.titleCardLines(); len(lines) != 3 || lines[0] != "Stage 1: Spring Tour" || lines[1] != "by pankona" {
+			t.Errorf("タイトルカードの内容が違う: %q", lines)
+		}
+		game.startStage(2)
+		if game.TitleCardTimer != 0 {
+			t.Errorf("ヘッダーのないステージではタイトルカードを出さない: %d", game.TitleCardTimer)
+		}
+		game.Stage = &Stage{Info: StageInfo{Tutorial: "Jump on the pads"}}
+		if game.showTitleCard(); game.TitleCardTimer != 0 {
+			t.Errorf("ヒントだけのステージではタイトルカードを出さない: %d", game.TitleCardTimer)
+		}
+	})
+
+	t.Run("チュートリアルのステージは説明を説明する場所に置く", func(t *testing.T) {
+		for i, load := range []func() *Stage{LoadStage1, LoadStage2, LoadStage3} {
+			labels := stagefile.TutorialLabels(load().Info.Tutorial)
+			if len(labels) == 0 {
+				t.Errorf("ステージ%dにチュートリアルの説明があるべき", i+1)
+			}
+			for _, label := range labels {
+				if !label.Positioned || label.X >= ScreenWidth || label.Y >= ScreenHeight {
+					t.Errorf("ステージ%dの説明 %q は画面内の位置を持つべき", i+1, label.Text)
+				}
+			}
+		}
+	})
+}
+
+func TestStageRegistry(t *testing.T) {
+	t.Run("すべてのステージファイルが登録されている", func(t *testing.T) {
+		files, err := filepath.Glob("stage*.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := 0
+		for _, file := range files {
+			number, err := stagefile.StageNumber(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := builtinStages[number]; !ok {
+				t.Errorf("%s が登録されていない (make generate-stages を実行する)", file)
+			}
+			last = max(last, number)
+		}
+		if len(builtinStages) != len(files) || lastBuiltinStage != last {
+			t.Errorf("登録数 %d・最終ステージ %d がステージファイル %d 個・最終 %d と一致しない", len(builtinStages), lastBuiltinStage, len(files), last)
+		}
+	})
+
+	t.Run("ステージ数は登録から決まる", func(t *testing.T) {
+		if loader := NewStageLoader(); !DebugMode && loader.TotalStages != lastBuiltinStage {
+			t.Errorf("TotalStages = %d, want %d", loader.TotalStages, lastBuiltinStage)
+		}
+	})
+
+	t.Run("存在しないステージはステージ1になる", func(t *testing.T) {
+		loader := &StageLoader{}
+		if !reflect.DeepEqual(loader.LoadStage(lastBuiltinStage+1), LoadStage1()) {
+			t.Error("存在しないステージはステージ1を読み込むべき")
+		}
+		blueX, blueY, redX, redY := loader.StageStartPositions(-1)
+		wantBlueX, wantBlueY, wantRedX, wantRedY := GetStage1StartPositions()
+		if blueX != wantBlueX || blueY != wantBlueY || redX != wantRedX || redY != wantRedY {
+			t.Error("存在しないステージはステージ1の開始位置になるべき")
+		}
+	})
+}
+
+func TestStagePreview(t *testing.T) {
+	data, err := os.ReadFile("stage01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout, err := stagefile.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("ゲームと同じ色と位置で描かれる", func(t *testing.T) {
+		ApplyPalette(0)
+		img := stagefile.Render(layout, stagefile.DefaultColors, 1)
+		if got := img.Bounds().Size(); got.X != ScreenWidth || got.Y != ScreenHeight {
+			t.Fatalf("画像の大きさがゲーム画面と一致しない: %v", got)
+		}
+		// Sample the centre of a cell of each element
+		at := func(x, y float64) color.RGBA { return img.RGBAAt(int(x)+CellSize/2, int(y)+CellSize/2) }
+		stage := LoadStage1()
+		for _, p := range stage.Platforms {
+			if got := at(p.X, p.Y); got != p.Color {
+				t.Errorf("足場 (%v, %v) の色 %v がゲームの色 %v と一致しない", p.X, p.Y, got, p.Color)
+			}
+		}
+		for _, s := range stage.Spikes {
+			if got := img.RGBAAt(int(s.X)+CellSize/2, int(s.Y)+CellSize-2); got != s.Color {
+				t.Errorf("トゲ (%v, %v) の色 %v がゲームの色 %v と一致しない", s.X, s.Y, got, s.Color)
+			}
+		}
+		blueX, blueY, redX, redY := GetStage1StartPositions()
+		if at(blueX, blueY) != BlueUnitColor || at(redX, redY) != RedUnitColor {
+			t.Error("開始位置にキャラの色の印が描かれるべき")
+		}
+		if got := at(CellSize*10, CellSize*10); got != (color.RGBA{0, 0, 0, 255}) {
+			t.Errorf("何もないマスは黒であるべき: %v", got)
+		}
+	})
+
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/pankona/egj2025/internal/stagefile"
+)
+
+// Palette is a set of gameplay colours.
+// Alternative palettes keep units and gimmicks distinguishable for
+// colour-blind players; shapes, patterns and letter marks carry the same
+// information so nothing relies on colour alone.
+type Palette struct {
+	Name      string
+	Ground    color.RGBA
+	Platform  color.RGBA
+	Goal      color.RGBA
+	Spike     color.RGBA
+	SpeedUp   color.RGBA
+	SpeedDown color.RGBA
+	OneWay    color.RGBA
+	Reverse   color.RGBA
+	Spring    color.RGBA
+	Conveyor  color.RGBA
+	Star      color.RGBA
+	Hazard    color.RGBA
+	BlueUnit  color.RGBA
+	RedUnit   color.RGBA
+}
+
+// Palettes lists the selectable palettes; the first one is the default
+var Palettes = []Palette{
+	{
+		// The colours of generated previews too (see stagefile.DefaultColors)
+		Name:      "Default",
+		Ground:    stagefile.DefaultColors.Ground,
+		Platform:  stagefile.DefaultColors.Platform,
+		Goal:      stagefile.DefaultColors.Goal,
+		Spike:     stagefile.DefaultColors.Spike,
+		SpeedUp:   stagefile.DefaultColors.SpeedUp,
+		SpeedDown: stagefile.DefaultColors.SpeedDown,
+		OneWay:    stagefile.DefaultColors.OneWay,
+		Reverse:   stagefile.DefaultColors.Reverse,
+		Spring:    stagefile.DefaultColors.Spring,
+		Conveyor:  stagefile.DefaultColors.Conveyor,
+		Star:      stagefile.DefaultColors.Star,
+		Hazard:    stagefile.DefaultColors.Hazard,
+		BlueUnit:  stagefile.DefaultColors.BlueUnit,
+		RedUnit:   stagefile.DefaultColors.RedUnit,
+	},
+	{
+		// Red-green (green-weak): blue/orange axis from the Okabe-Ito palette
+		Name:      "Deuteranopia",
+		Ground:    color.RGBA{100, 100, 100, 255},
+		Platform:  color.RGBA{150, 150, 150, 255},
+		Goal:      color.RGBA{240, 228, 66, 255},
+		Spike:     color.RGBA{204, 121, 167, 255},
+		SpeedUp:   color.RGBA{86, 180, 233, 255},
+		SpeedDown: color.RGBA{213, 94, 0, 255},
+		OneWay:    color.RGBA{200, 170, 120, 255},
+		Reverse:   color.RGBA{0, 158, 115, 255},
+		Spring:    color.RGBA{204, 121, 167, 255},
+		Conveyor:  color.RGBA{90, 100, 120, 255},
+		Star:      color.RGBA{255, 240, 150, 255},
+		Hazard:    color.RGBA{213, 94, 0, 255},
+		BlueUnit:  color.RGBA{0, 114, 178, 255},
+		RedUnit:   color.RGBA{230, 159, 0, 255},
+	},
+	{
+		// Red-green (red-weak): reds look dark, so rely on brighter oranges
+		Name:      "Protanopia",
+		Ground:    color.RGBA{100, 100, 100, 255},
+		Platform:  color.RGBA{150, 150, 150, 255},
+		Goal:      color.RGBA{240, 228, 66, 255},
+		Spike:     color.RGBA{230, 159, 0, 255},
+		SpeedUp:   color.RGBA{86, 180, 233, 255},
+		SpeedDown: color.RGBA{255, 200, 120, 255},
+		OneWay:    color.RGBA{200, 170, 120, 255},
+		Reverse:   color.RGBA{0, 158, 115, 255},
+		Spring:    color.RGBA{240, 180, 210, 255},
+		Conveyor:  color.RGBA{90, 100, 120, 255},
+		Star:      color.RGBA{255, 240, 150, 255},
+		Hazard:    color.RGBA{230, 159, 0, 255},
+		BlueUnit:  color.RGBA{0, 90, 200, 255},
+		RedUnit:   color.RGBA{255, 176, 0, 255},
+	},
+	{
+		// Blue-yellow: use red/teal contrasts instead
+		Name:      "Tritanopia",
+		Ground:    color.RGBA{100, 100, 100, 255},
+		Platform:  color.RGBA{150, 150, 150, 255},
+		Goal:      color.RGBA{255, 255, 255, 255},
+		Spike:     color.RGBA{220, 50, 32, 255},
+		SpeedUp:   color.RGBA{0, 160, 160, 255},
+		SpeedDown: color.RGBA{255, 130, 150, 255},
+		OneWay:    color.RGBA{180, 150, 110, 255},
+		Reverse:   color.RGBA{150, 90, 200, 255},
+		Spring:    color.RGBA{255, 180, 190, 255},
+		Conveyor:  color.RGBA{90, 110, 120, 255},
+		Star:      color.RGBA{255, 170, 60, 255},
+		Hazard:    color.RGBA{220, 50, 32, 255},
+		BlueUnit:  color.RGBA{0, 120, 130, 255},
+		RedUnit:   color.RGBA{230, 40, 60, 255},
+	},
+	{
+		Name:      "High Contrast",
+		Ground:    color.RGBA{220, 220, 220, 255},
+		Platform:  color.RGBA{255, 255, 255, 255},
+		Goal:      color.RGBA{255, 255, 0, 255},
+		Spike:     color.RGBA{255, 0, 0, 255},
+		SpeedUp:   color.RGBA{0, 255, 255, 255},
+		SpeedDown: color.RGBA{255, 0, 255, 255},
+		OneWay:    color.RGBA{200, 200, 200, 255},
+		Reverse:   color.RGBA{200, 0, 255, 255},
+		Spring:    color.RGBA{255, 140, 255, 255},
+		Conveyor:  color.RGBA{120, 160, 220, 255},
+		Star:      color.RGBA{255, 255, 120, 255},
+		Hazard:    color.RGBA{255, 60, 60, 255},
+		BlueUnit:  color.RGBA{60, 140, 255, 255},
+		RedUnit:   color.RGBA{255, 80, 80, 255},
+	},
+}
+
+// CurrentPaletteIndex is the index of the palette applied last
+var CurrentPaletteIndex int
+
+// ApplyPalette replaces the shared gameplay colours with the given palette.
+// Stages created afterwards use the new colours.
+func ApplyPalette(index int) Palette {
+	index = ((index % len(Palettes)) + len(Palettes)) % len(Palettes)
+	palette := Palettes[index]
+	CurrentPaletteIndex = index
+
+	GroundColor = palette.Ground
+	PlatformColor = palette.Platform
+	GoalColor = palette.Goal
+	SpikeColor = palette.Spike
+	SpeedUpColor = palette.SpeedUp
+	SpeedDownColor = palette.SpeedDown
+	OneWayColor = palette.OneWay
+	ReverseColor = palette.Reverse
+	SpringColor = palette.Spring
+	ConveyorColor = palette.Conveyor
+	CollectibleColor = palette.Star
+	HazardColor = palette.Hazard
+	BlueUnitColor = palette.BlueUnit
+	RedUnitColor = palette.RedUnit
+	return palette
+}
+
+// applyPalette switches the palette while the game is running
+func (g *Game) applyPalette(index int) {
+	previous := Palettes[CurrentPaletteIndex]
+	palette := ApplyPalette(index)
+	g.BlueUnit.Color = BlueUnitColor
+	g.RedUnit.Color = RedUnitColor
+	// Platforms and spikes store their colours. Repaint the stage in place so
+	// the hazards simulated on it keep their state.
+	if g.Stage != nil {
+		recolorStage(g.Stage, previous)
+	}
+	g.invalidateStaticLayer()
+	g.showNotice("Palette: " + palette.Name)
+}
+
+// recolorStage repaints the platforms and spikes of a stage, built with the
+// previous palette, in the current palette
+func recolorStage(stage *Stage, previous Palette) {
+	for i := range stage.Platforms {
+		stage.Platforms[i].Color = platformColor(stage.Platforms[i], previous)
+	}
+	for i := range stage.Spikes {
+		stage.Spikes[i].Color = SpikeColor
+		if stage.Spikes[i].Only != UnitNone {
+			stage.Spikes[i].Color = unitKindColor(stage.Spikes[i].Only)
+		}
+	}
+}
+
+// platformColor returns the current palette colour of a platform, matching
+// the CreateGrid*Platform function that made it
+func platformColor(p Platform, previous Palette) color.Color {
+	switch {
+	case p.IsGoal:
+		return GoalColor
+	case p.Only != UnitNone:
+		return unitKindColor(p.Only)
+	case p.SpeedModifier > 1:
+		return SpeedUpColor
+	case p.SpeedModifier > 0 && p.SpeedModifier < 1:
+		return SpeedDownColor
+	case p.OneWay:
+		return OneWayColor
+	case p.Reverse:
+		return ReverseColor
+	case p.Bounce > 0:
+		return SpringColor
+	case p.Conveyor != 0:
+		return ConveyorColor
+	case p.Color == color.Color(previous.Ground):
+		return GroundColor
+	default:
+		return PlatformColor
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// EmitterConfig describes how a burst of particles looks and moves
+type EmitterConfig struct {
+	Count   int           // Particles spawned per burst (0 disables the emitter)
+	Speed   float64       // Initial speed in pixels per tick
+	Angle   float64       // Base direction in radians (0 = right, -Pi/2 = up)
+	Spread  float64       // Random deviation from Angle in radians
+	Gravity float64       // Vertical acceleration per tick
+	Life    int           // Lifetime in ticks
+	Size    float32       // Radius in pixels
+	Colors  []*color.RGBA // Colors picked at random for each particle, read when it is spawned
+}
+
+// EffectsConfig configures all visual juice effects
+type EffectsConfig struct {
+	Enabled       bool // Master switch for particles
+	ScreenShake   bool // Shake the screen when a unit dies
+	ReducedMotion bool // Disables particles and screen shake for motion-sensitive players
+
+	JumpDust       EmitterConfig
+	LandingPuff    EmitterConfig
+	WallSparks     EmitterConfig
+	DeathExplosion EmitterConfig
+	GoalConfetti   EmitterConfig
+	StarSparkle    EmitterConfig
+	SpeedTrail     EmitterConfig
+	SpeedTrailRate int // Ticks between speed-platform trail particles
+
+	ShakeDuration  int     // Screen shake length in ticks
+	ShakeMagnitude float64 // Maximum screen shake offset in pixels
+}
+
+// Effect colours that the palettes do not replace
+var (
+	dustColor    = color.RGBA{200, 200, 200, 255}
+	fireColor    = color.RGBA{255, 180, 50, 255}
+	sparkColor   = color.RGBA{255, 255, 150, 255}
+	emberColor   = color.RGBA{255, 255, 100, 255}
+	confettiBlue = color.RGBA{0, 150, 255, 255}
+	confettiPink = color.RGBA{255, 100, 200, 255}
+)
+
+// DefaultEffectsConfig returns the standard effect settings. Emitters point at
+// the palette colours, so particles follow a palette switch.
+func DefaultEffectsConfig() EffectsConfig {
+	return EffectsConfig{
+		Enabled:     true,
+		ScreenShake: true,
+
+		JumpDust:       EmitterConfig{Count: 6, Speed: 1.0, Angle: math.Pi / 2, Spread: math.Pi / 2, Gravity: 0.02, Life: 18, Size: 2, Colors: []*color.RGBA{&dustColor}},
+		LandingPuff:    EmitterConfig{Count: 8, Speed: 1.2, Angle: -math.Pi / 2, Spread: math.Pi / 2, Gravity: 0.05, Life: 15, Size: 2, Colors: []*color.RGBA{&dustColor}},
+		WallSparks:     EmitterConfig{Count: 5, Speed: 2.5, Angle: 0, Spread: math.Pi, Gravity: 0.1, Life: 12, Size: 1.5, Colors: []*color.RGBA{&sparkColor, &WhiteColor}},
+		DeathExplosion: EmitterConfig{Count: 40, Speed: 4.0, Angle: 0, Spread: math.Pi, Gravity: 0.12, Life: 45, Size: 3, Colors: []*color.RGBA{&SpikeColor, &fireColor, &emberColor}},
+		GoalConfetti:   EmitterConfig{Count: 30, Speed: 3.5, Angle: -math.Pi / 2, Spread: math.Pi / 3, Gravity: 0.08, Life: 70, Size: 2.5, Colors: []*color.RGBA{&GoalColor, &SpeedUpColor, &confettiBlue, &confettiPink}},
+		StarSparkle:    EmitterConfig{Count: 12, Speed: 2.0, Angle: 0, Spread: math.Pi, Gravity: 0.03, Life: 25, Size: 2, Colors: []*color.RGBA{&CollectibleColor, &WhiteColor}},
+		SpeedTrail:     EmitterConfig{Count: 1, Speed: 0.3, Angle: -math.Pi / 2, Spread: math.Pi / 4, Gravity: 0, Life: 20, Size: 2},
+		SpeedTrailRate: 4,
+
+		ShakeDuration:  20,
+		ShakeMagnitude: 6,
+	}
+}
+
+// Particle is a single short-lived visual dot
+type Particle struct {
+	X, Y    float64
+	VX, VY  float64
+	Gravity float64
+	Life    int // Remaining lifetime in ticks
+	MaxLife int
+	Size    float32
+	Color   color.RGBA
+}
+
+// ParticleSystem spawns particles in reaction to gameplay events and draws them
+type ParticleSystem struct {
+	Config         EffectsConfig
+	particles      []Particle
+	rng            *rand.Rand
+	shakeRNG       *rand.Rand // Separate source, so shaking never changes the particles spawned
+	tick           int
+	shakeLeft      int
+	shakeX, shakeY float64 // Screen shake offset for the current tick
+}
+
+// NewParticleSystem creates a particle system with the given configuration
+func NewParticleSystem(config EffectsConfig) *ParticleSystem {
+	return &ParticleSystem{
+		Config: config,
+		// Fixed seeds keep effects reproducible between runs and replays
+		rng:      rand.New(rand.NewPCG(2025, 629)),
+		shakeRNG: rand.New(rand.NewPCG(2025, 630)),
+	}
+}
+
+// active reports whether particles should be spawned at all
+func (ps *ParticleSystem) active() bool {
+	return ps != nil && ps.Config.Enabled && !ps.Config.ReducedMotion
+}
+
+// SubscribeEvents registers the effect emitters on the event bus
+func (ps *ParticleSystem) SubscribeEvents(bus *EventBus) {
+	bus.Subscribe(EventJumped, func(e Event) {
+		ps.Emit(ps.Config.JumpDust, e.X+UnitSize/2, e.Y+UnitSize)
+	})
+	bus.Subscribe(EventLanded, func(e Event) {
+		ps.Emit(ps.Config.LandingPuff, e.X+UnitSize/2, e.Y+UnitSize)
+	})
+	bus.Subscribe(EventHitWall, func(e Event) {
+		ps.Emit(ps.Config.WallSparks, e.X+UnitSize/2, e.Y+UnitSize/2)
+	})
+	bus.Subscribe(EventReachedGoal, func(e Event) {
+		ps.Emit(ps.Config.GoalConfetti, e.X+UnitSize/2, e.Y)
+	})
+	bus.Subscribe(EventCollected, func(e Event) {
+		ps.Emit(ps.Config.StarSparkle, e.X+CellSize/2, e.Y+CellSize/2)
+	})
+	bus.Subscribe(EventDied, func(e Event) {
+		ps.Emit(ps.Config.DeathExplosion, e.X+UnitSize/2, e.Y+UnitSize/2)
+		if ps.Config.ScreenShake && !ps.Config.ReducedMotion {
+			ps.shakeLeft = ps.Config.ShakeDuration
+			ps.rollShake()
+		}
+	})
+}
+
+// Emit spawns one burst of particles at the given position
+func (ps *ParticleSystem) Emit(emitter EmitterConfig, x, y float64) {
+	if !ps.active() || len(emitter.Colors) == 0 {
+		return
+	}
+	for i := 0; i < emitter.Count; i++ {
+		angle := emitter.Angle + (ps.rng.Float64()*2-1)*emitter.Spread
+		speed := emitter.Speed * (0.5 + ps.rng.Float64()*0.5)
+		ps.particles = append(ps.particles, Particle{
+			X:       x,
+			Y:       y,
+			VX:      math.Cos(angle) * speed,
+			VY:      math.Sin(angle) * speed,
+			Gravity: emitter.Gravity,
+			Life:    emitter.Life,
+			MaxLife: emitter.Life,
+			Size:    emitter.Size,
+			Color:   *emitter.Colors[ps.rng.IntN(len(emitter.Colors))],
+		})
+	}
+}
+
+// Update ages particles, spawns continuous effects and counts down screen
+// shake. It runs once per simulation tick, so effects follow slow motion and
+// frame stepping.
+func (ps *ParticleSystem) Update(units ...*Unit) {
+	if ps == nil {
+		return
+	}
+	ps.tick++
+
+	// Speed-platform trails are continuous, so they are spawned here instead of from events
+	if ps.Config.SpeedTrailRate > 0 && ps.tick%ps.Config.SpeedTrailRate == 0 {
+		for _, u := range units {
+			if !u.OnGround || u.Stopped || u.SpeedModifier == 1.0 {
+				continue
+			}
+			trail := ps.Config.SpeedTrail
+			if u.SpeedModifier > 1.0 {
+				trail.Colors = []*color.RGBA{&SpeedUpColor}
+			} else {
+				trail.Colors = []*color.RGBA{&SpeedDownColor}
+			}
+			// Spawn behind the unit relative to its walking direction
+			ps.Emit(trail, u.X+UnitSize/2-float64(u.Direction)*UnitSize/2, u.Y+UnitSize-2)
+		}
+	}
+
+	alive := ps.particles[:0]
+	for _, p := range ps.particles {
+		p.Life--
+		if p.Life <= 0 {
+			continue
+		}
+		p.VY += p.Gravity
+		p.X += p.VX
+		p.Y += p.VY
+		alive = append(alive, p)
+	}
+	ps.particles = alive
+
+	if ps.shakeLeft > 0 {
+		ps.shakeLeft--
+		ps.rollShake()
+	}
+}
+
+// rollShake picks the screen shake offset for the current tick. The shake
+// fades out linearly over its duration.
+func (ps *ParticleSystem) rollShake() {
+	if ps.shakeLeft <= 0 || ps.Config.ShakeDuration <= 0 {
+		ps.shakeX, ps.shakeY = 0, 0
+		return
+	}
+	magnitude := ps.Config.ShakeMagnitude * float64(ps.shakeLeft) / float64(ps.Config.ShakeDuration)
+	ps.shakeX = (ps.shakeRNG.Float64()*2 - 1) * magnitude
+	ps.shakeY = (ps.shakeRNG.Float64()*2 - 1) * magnitude
+}
+
+// Clear removes all particles and stops screen shake
+func (ps *ParticleSystem) Clear() {
+	if ps == nil {
+		return
+	}
+	ps.particles = ps.particles[:0]
+	ps.shakeLeft = 0
+}
+
+// ShakeOffset returns the current screen shake translation. The offset is
+// picked once per tick by Update, so drawing (which may run more or less
+// often) neither changes it nor the particles spawned afterwards.
+func (ps *ParticleSystem) ShakeOffset() (dx, dy float64) {
+	if ps == nil || ps.shakeLeft <= 0 || ps.Config.ReducedMotion {
+		return 0, 0
+	}
+	return ps.shakeX, ps.shakeY
+}
+
+// Draw renders all live particles, fading them out over their lifetime
+func (ps *ParticleSystem) Draw(screen *ebiten.Image) {
+	if ps == nil {
+		return
+	}
+	for _, p := range ps.particles {
+		c := p.Color
+		alpha := float64(p.Life) / float64(p.MaxLife)
+		c.R = uint8(float64(c.R) * alpha)
+		c.G = uint8(float64(c.G) * alpha)
+		c.B = uint8(float64(c.B) * alpha)
+		c.A = uint8(float64(c.A) * alpha)
+		vector.DrawFilledCircle(screen, float32(p.X), float32(p.Y), p.Size, c, false)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Renderer draws the gameplay world (stage geometry and units)
+type Renderer interface {
+	// DrawStage draws platforms and spikes
+	DrawStage(dst *ebiten.Image, stage *Stage)
+	// DrawUnit draws a single unit; tick drives animations
+	DrawUnit(dst *ebiten.Image, unit *Unit, tick int)
+	// DrawCheckpoint draws a checkpoint flag, highlighted when it belongs to the active respawn pair
+	DrawCheckpoint(dst *ebiten.Image, checkpoint Checkpoint, active bool)
+	// DrawCollectible draws a collectible star; tick drives its bobbing animation
+	DrawCollectible(dst *ebiten.Image, item Collectible, tick int)
+	// DrawHazard draws an enemy or hazard; tick drives timed effects such as the laser beam
+	DrawHazard(dst *ebiten.Image, hazard Hazard, tick int)
+	// DrawProjectile draws a turret shot
+	DrawProjectile(dst *ebiten.Image, p Projectile)
+}
+
+// VectorRenderer draws the world with plain shapes.
+// It needs no assets, so it is also the fallback when sprites are unavailable.
+type VectorRenderer struct {
+	WhitePixel *ebiten.Image // 1x1 white pixel used as the source for spike triangles
+}
+
+// NewVectorRenderer creates a vector renderer with its own white pixel source image
+func NewVectorRenderer() *VectorRenderer {
+	whitePixel := ebiten.NewImage(1, 1)
+	whitePixel.Fill(color.White)
+	return &VectorRenderer{WhitePixel: whitePixel}
+}
+
+// DrawStage draws platforms as rectangles and spikes as triangles
+func (r *VectorRenderer) DrawStage(dst *ebiten.Image, stage *Stage) {
+	// Draw platforms
+	for _, platform := range stage.Platforms {
+		if platform.OneWay {
+			drawOneWayPlatform(dst, platform)
+			continue
+		}
+		platformColor := platform.Color
+		// Highlight goal platforms
+		if platform.IsGoal {
+			platformColor = GoalColor
+		}
+		vector.DrawFilledRect(dst, float32(platform.X), float32(platform.Y), float32(platform.Width), float32(platform.Height), platformColor, false)
+		drawSpeedPattern(dst, platform)
+		drawGimmickPattern(dst, platform)
+		drawUnitOnlyPattern(dst, platform)
+	}
+
+	// Draw spikes as triangles pointing in their direction (optimized batch rendering)
+	if len(stage.Spikes) > 0 {
+		// Calculate total vertices and indices needed
+		totalSpikes := len(stage.Spikes)
+		vertices := make([]ebiten.Vertex, 0, totalSpikes*3)
+		indices := make([]uint16, 0, totalSpikes*3)
+
+		// Build all spike triangles in batch
+		for i, spike := range stage.Spikes {
+			cr, cg, cb, ca := colorToFloats(spike.Color)
+
+			// Use the same triangle as the hitbox (base corners, then tip)
+			baseIndex := uint16(i * 3)
+			tri := spike.Triangle()
+			spikeVertices := []ebiten.Vertex{
+				{DstX: float32(tri[0].X), DstY: float32(tri[0].Y), SrcX: 0, SrcY: 0, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+				{DstX: float32(tri[1].X), DstY: float32(tri[1].Y), SrcX: 1, SrcY: 0, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+				{DstX: float32(tri[2].X), DstY: float32(tri[2].Y), SrcX: 0.5, SrcY: 1, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+			}
+
+			// Add vertices to batch
+			vertices = append(vertices, spikeVertices...)
+
+			// Add indices to batch (triangle indices for this spike)
+			spikeIndices := []uint16{baseIndex, baseIndex + 1, baseIndex + 2}
+			indices = append(indices, spikeIndices...)
+		}
+
+		// Single DrawTriangles call for all spikes
+		dst.DrawTriangles(vertices, indices, r.WhitePixel, nil)
+	}
+	drawUnitOnlySpikeMarks(dst, stage)
+}
+
+// DrawUnit draws a unit as a circle
+func (r *VectorRenderer) DrawUnit(dst *ebiten.Image, unit *Unit, tick int) {
+	centerX := float32(unit.X) + UnitSize/2
+	centerY := float32(unit.Y) + UnitSize/2
+	vector.DrawFilledCircle(dst, centerX, centerY, UnitSize/2, unit.Color, false)
+}
+
+// DrawCheckpoint draws a checkpoint as a pole with a triangular flag
+func (r *VectorRenderer) DrawCheckpoint(dst *ebiten.Image, checkpoint Checkpoint, active bool) {
+	x := float32(checkpoint.X)
+	y := float32(checkpoint.Y)
+	vector.StrokeLine(dst, x+5, y+1, x+5, y+CellSize, 2, PlatformColor, false)
+
+	cr, cg, cb, ca := colorToFloats(checkpointColor(checkpoint, active))
+	vertices := []ebiten.Vertex{
+		{DstX: x + 6, DstY: y + 1, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+		{DstX: x + 18, DstY: y + 5, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+		{DstX: x + 6, DstY: y + 10, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+	}
+	dst.DrawTriangles(vertices, []uint16{0, 1, 2}, r.WhitePixel, nil)
+}
+
+// DrawCollectible draws a five-pointed star
+func (r *VectorRenderer) DrawCollectible(dst *ebiten.Image, item Collectible, tick int) {
+	centerX := float32(item.X) + CellSize/2
+	centerY := float32(item.Y) + CellSize/2 + collectibleBob(tick)
+
+	var path vector.Path
+	for i := 0; i < 10; i++ {
+		radius := float32(8)
+		if i%2 == 1 {
+			radius = 3.5
+		}
+		angle := -math.Pi/2 + float64(i)*math.Pi/5
+		x := centerX + radius*float32(math.Cos(angle))
+		y := centerY + radius*float32(math.Sin(angle))
+		if i == 0 {
+			path.MoveTo(x, y)
+		} else {
+			path.LineTo(x, y)
+		}
+	}
+	path.Close()
+
+	vertices, indices := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	cr, cg, cb, ca := colorToFloats(CollectibleColor)
+	for i := range vertices {
+		vertices[i].SrcX, vertices[i].SrcY = 0.5, 0.5
+		vertices[i].ColorR, vertices[i].ColorG, vertices[i].ColorB, vertices[i].ColorA = cr, cg, cb, ca
+	}
+	dst.DrawTriangles(vertices, indices, r.WhitePixel, &ebiten.DrawTrianglesOptions{FillRule: ebiten.FillRuleNonZero})
+}
+
+// DrawHazard draws hazards as simple shapes
+func (r *VectorRenderer) DrawHazard(dst *ebiten.Image, hazard Hazard, tick int) {
+	x := float32(hazard.X) + hazardShake(hazard, tick)
+	y := float32(hazard.Y)
+	switch hazard.Kind {
+	case HazardWalker:
+		vector.DrawFilledRect(dst, x+2, y+6, CellSize-4, CellSize-8, HazardColor, false)
+		eyeX := x + 13
+		if hazard.Direction < 0 {
+			eyeX = x + 4
+		}
+		vector.DrawFilledRect(dst, eyeX, y+9, 3, 3, WhiteColor, false)
+	case HazardFallingBlock:
+		vector.DrawFilledRect(dst, x, y, CellSize, CellSize, HazardColor, false)
+		vector.StrokeLine(dst, x+3, y+3, x+CellSize-3, y+CellSize-3, 2, GroundColor, false)
+		vector.StrokeLine(dst, x+CellSize-3, y+3, x+3, y+CellSize-3, 2, GroundColor, false)
+	case HazardLaser:
+		drawLaserBeam(dst, hazard, tick)
+		vector.DrawFilledRect(dst, x+3, y, CellSize-6, CellSize-4, laserEmitterColor(tick), false)
+	case HazardTurret:
+		vector.DrawFilledCircle(dst, x+CellSize/2, y+CellSize/2, 7, HazardColor, false)
+		barrelX := x + CellSize/2
+		if hazard.Direction < 0 {
+			barrelX = x
+		}
+		vector.DrawFilledRect(dst, barrelX, y+CellSize/2-2, CellSize/2, 4, GroundColor, false)
+	}
+}
+
+// DrawProjectile draws a turret shot as a small circle
+func (r *VectorRenderer) DrawProjectile(dst *ebiten.Image, p Projectile) {
+	vector.DrawFilledCircle(dst, float32(p.X)+ProjectileSize/2, float32(p.Y)+ProjectileSize/2, ProjectileSize/2, HazardColor, false)
+}
+
+// drawLaserBeam draws the beam below a laser emitter while it is switched on
+func drawLaserBeam(dst *ebiten.Image, hazard Hazard, tick int) {
+	x, y, w, h, ok := hazardHitbox(hazard, tick)
+	if !ok {
+		return
+	}
+	vector.DrawFilledRect(dst, float32(x)-2, float32(y), float32(w)+4, float32(h), laserGlowColor(), false)
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(w), float32(h), HazardColor, false)
+	vector.DrawFilledRect(dst, float32(x)+2, float32(y), float32(w)-4, float32(h), WhiteColor, false)
+}
+
+// laserEmitterColor flashes the emitter shortly before the beam switches on
+func laserEmitterColor(tick int) color.Color {
+	if laserWarning(tick) && tick/5%2 == 0 {
+		return WhiteColor
+	}
+	return HazardColor
+}
+
+// laserGlowColor returns a translucent hazard colour for the beam's glow
+func laserGlowColor() color.Color {
+	return color.RGBA{HazardColor.R / 2, HazardColor.G / 2, HazardColor.B / 2, 128}
+}
+
+// hazardShake returns the horizontal offset of a triggered falling block that has not dropped yet
+func hazardShake(hazard Hazard, tick int) float32 {
+	if hazard.Kind != HazardFallingBlock || !hazard.Triggered || hazard.Timer == 0 {
+		return 0
+	}
+	return float32(tick%4/2*2 - 1)
+}
+
+// collectibleBob returns the vertical offset of the collectible animation
+func collectibleBob(tick int) float32 {
+	return float32(math.Sin(float64(tick)/10) * 1.5)
+}
+
+// checkpointColor returns the flag colour; flags outside the active pair are dimmed
+func checkpointColor(checkpoint Checkpoint, active bool) color.Color {
+	c := unitKindColor(checkpoint.Unit)
+	if active {
+		return c
+	}
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{R: uint16(r / 2), G: uint16(g / 2), B: uint16(b / 2), A: uint16(a)}
+}
+
+// speedPatternColor is used for pattern overlays so speed platforms are not told apart by hue alone
+var speedPatternColor = color.RGBA{0, 0, 0, 140}
+
+// drawSpeedPattern overlays chevrons on speed-up platforms and bars on speed-down platforms
+func drawSpeedPattern(dst *ebiten.Image, platform Platform) {
+	if platform.IsGoal || platform.SpeedModifier == 1.0 || platform.SpeedModifier == 0 {
+		return
+	}
+	const size = float32(CellSize)
+	for y := float32(platform.Y); y+size <= float32(platform.Y+platform.Height); y += size {
+		for x := float32(platform.X); x+size <= float32(platform.X+platform.Width); x += size {
+			if platform.SpeedModifier > 1.0 {
+				// Double chevron ">>"
+				for _, offset := range []float32{4, 10} {
+					vector.StrokeLine(dst, x+offset, y+4, x+offset+5, y+10, 2, speedPatternColor, false)
+					vector.StrokeLine(dst, x+offset+5, y+10, x+offset, y+16, 2, speedPatternColor, false)
+				}
+			} else {
+				// Double bar "="
+				vector.StrokeLine(dst, x+3, y+7, x+size-3, y+7, 2, speedPatternColor, false)
+				vector.StrokeLine(dst, x+3, y+13, x+size-3, y+13, 2, speedPatternColor, false)
+			}
+		}
+	}
+}
+
+// drawGimmickPattern overlays a symbol on each cell of reverse, spring and conveyor tiles
+func drawGimmickPattern(dst *ebiten.Image, platform Platform) {
+	if !platform.Reverse && platform.Bounce == 0 && platform.Conveyor == 0 {
+		return
+	}
+	const size = float32(CellSize)
+	for y := float32(platform.Y); y+size <= float32(platform.Y+platform.Height); y += size {
+		for x := float32(platform.X); x+size <= float32(platform.X+platform.Width); x += size {
+			switch {
+			case platform.Reverse:
+				// Opposing arrows "⇄"
+				vector.StrokeLine(dst, x+3, y+7, x+17, y+7, 2, speedPatternColor, false)
+				vector.StrokeLine(dst, x+17, y+7, x+13, y+3, 2, speedPatternColor, false)
+				vector.StrokeLine(dst, x+3, y+13, x+17, y+13, 2, speedPatternColor, false)
+				vector.StrokeLine(dst, x+3, y+13, x+7, y+17, 2, speedPatternColor, false)
+			case platform.Bounce > 0:
+				// Coil zigzag
+				for i := float32(0); i < 4; i++ {
+					vector.StrokeLine(dst, x+3, y+4+i*3, x+17, y+5.5+i*3, 2, speedPatternColor, false)
+				}
+			default:
+				// Single arrow in the conveyor direction
+				tip, tail := x+15, x+5
+				if platform.Conveyor < 0 {
+					tip, tail = tail, tip
+				}
+				vector.StrokeLine(dst, tail, y+10, tip, y+10, 2, speedPatternColor, false)
+				vector.StrokeLine(dst, tip, y+10, (tip+tail)/2, y+5, 2, speedPatternColor, false)
+				vector.StrokeLine(dst, tip, y+10, (tip+tail)/2, y+15, 2, speedPatternColor, false)
+			}
+		}
+	}
+}
+
+// drawUnitOnlyPattern outlines platforms tied to one unit in that unit's colour
+// and hatches them ("/" for blue, "\" for red) so they are not told apart by hue alone
+func drawUnitOnlyPattern(dst *ebiten.Image, platform Platform) {
+	if platform.Only == UnitNone {
+		return
+	}
+	unitColor := unitKindColor(platform.Only)
+	const size = float32(CellSize)
+	for y := float32(platform.Y); y+size <= float32(platform.Y+platform.Height); y += size {
+		for x := float32(platform.X); x+size <= float32(platform.X+platform.Width); x += size {
+			for _, offset := range []float32{5, 12} {
+				if platform.Only == UnitBlue {
+					vector.StrokeLine(dst, x+offset-3, y+size-3, x+offset+3, y+3, 2, speedPatternColor, false)
+				} else {
+					vector.StrokeLine(dst, x+offset-3, y+3, x+offset+3, y+size-3, 2, speedPatternColor, false)
+				}
+			}
+		}
+	}
+	vector.StrokeRect(dst, float32(platform.X)+1, float32(platform.Y)+1, float32(platform.Width)-2, float32(platform.Height)-2, 2, unitColor, false)
+}
+
+// drawUnitOnlySpikeMarks marks spikes tied to one unit with the same hatch direction as its platforms
+func drawUnitOnlySpikeMarks(dst *ebiten.Image, stage *Stage) {
+	for _, spike := range stage.Spikes {
+		x, y := float32(spike.X), float32(spike.Y)
+		switch spike.Only {
+		case UnitBlue:
+			vector.StrokeLine(dst, x+7, y+CellSize-3, x+13, y+10, 2, speedPatternColor, false)
+		case UnitRed:
+			vector.StrokeLine(dst, x+7, y+10, x+13, y+CellSize-3, 2, speedPatternColor, false)
+		}
+	}
+}
+
+// drawOneWayPlatform draws jump-through platforms as thin planks on short struts,
+// leaving the space below visibly open
+func drawOneWayPlatform(dst *ebiten.Image, platform Platform) {
+	const size = float32(CellSize)
+	const plank = 6
+	for y := float32(platform.Y); y+size <= float32(platform.Y+platform.Height); y += size {
+		vector.DrawFilledRect(dst, float32(platform.X), y, float32(platform.Width), plank, platform.Color, false)
+		vector.StrokeLine(dst, float32(platform.X), y+plank, float32(platform.X+platform.Width), y+plank, 1, speedPatternColor, false)
+		for x := float32(platform.X); x+size <= float32(platform.X+platform.Width); x += size {
+			vector.StrokeLine(dst, x+4, y+plank, x+4, y+plank+6, 2, platform.Color, false)
+			vector.StrokeLine(dst, x+size-4, y+plank, x+size-4, y+plank+6, 2, platform.Color, false)
+		}
+	}
+}
+
+// colorToFloats converts a color to premultiplied float components for vertices
+func colorToFloats(c color.Color) (r, g, b, a float32) {
+	cr, cg, cb, ca := c.RGBA()
+	return float32(cr) / 0xffff, float32(cg) / 0xffff, float32(cb) / 0xffff, float32(ca) / 0xffff
+}
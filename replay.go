@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// ReplayInput is a single successful jump recorded during an attempt
+type ReplayInput struct {
+	Tick int      `json:"tick"`
+	Unit UnitKind `json:"unit"`
+}
+
+// Replay is the recorded input of one stage attempt.
+// The simulation is deterministic, so replaying the jumps at the same ticks
+// reproduces the attempt.
+type Replay struct {
+	Stage   int           `json:"stage"`
+	Inputs  []ReplayInput `json:"inputs"`
+	Ticks   int           `json:"ticks"`
+	Cleared bool          `json:"cleared"`
+	// Assisted attempts (slow motion, no spike death, restored checkpoints)
+	// cannot be reproduced from the jump inputs alone
+	Assisted bool `json:"assisted"`
+}
+
+// ReplayRecorder records jump inputs from the gameplay event stream
+type ReplayRecorder struct {
+	current Replay
+	// LastCompleted holds the most recent attempt that ended by death or clear
+	LastCompleted *Replay
+	// BestClear holds the fastest cleared attempt per stage
+	BestClear map[int]*Replay
+	// SavePath is the file each finished attempt is written to, for playing
+	// back with -replay (empty = keep replays in memory only)
+	SavePath string
+}
+
+// NewReplayRecorder creates an empty replay recorder
+func NewReplayRecorder() *ReplayRecorder {
+	return &ReplayRecorder{
+		BestClear: make(map[int]*Replay),
+	}
+}
+
+// SubscribeEvents registers the recorder on the event bus
+func (rr *ReplayRecorder) SubscribeEvents(bus *EventBus) {
+	bus.SubscribeAll(rr.handleEvent)
+}
+
+func (rr *ReplayRecorder) handleEvent(event Event) {
+	switch event.Type {
+	case EventAttemptStarted:
+		rr.current = Replay{Stage: event.Stage}
+	case EventJumped:
+		rr.current.Inputs = append(rr.current.Inputs, ReplayInput{Tick: event.Tick, Unit: event.Unit})
+	case EventResumed:
+		// The attempt goes on from a safe point: forget the jumps after it
+		inputs := rr.current.Inputs
+		for len(inputs) > 0 && inputs[len(inputs)-1].Tick > event.Tick {
+			inputs = inputs[:len(inputs)-1]
+		}
+		rr.current.Inputs = inputs
+		rr.current.Assisted = true
+	case EventRespawned:
+		// The jumps alone do not reproduce the jump back to the checkpoint
+		rr.current.Assisted = true
+	case EventDied:
+		rr.finish(event, false)
+	case EventStageCleared:
+		rr.current.Assisted = event.Assisted
+		rr.finish(event, true)
+	}
+}
+
+func (rr *ReplayRecorder) finish(event Event, cleared bool) {
+	completed := rr.current
+	completed.Ticks = event.Tick
+	completed.Cleared = cleared
+	rr.LastCompleted = &completed
+
+	if cleared && !completed.Assisted {
+		best, ok := rr.BestClear[completed.Stage]
+		if !ok || completed.Ticks < best.Ticks {
+			rr.BestClear[completed.Stage] = &completed
+		}
+	}
+
+	if rr.SavePath != "" {
+		if err := completed.Save(rr.SavePath); err != nil {
+			log.Printf("Failed to save replay: %v", err)
+		}
+	}
+}
+
+// replaySnapshot is the recorder state that an attempt can change, saved
+// with each frame-step snapshot so rewinding drops the rewound jumps
+type replaySnapshot struct {
+	current       Replay
+	lastCompleted *Replay
+	bestClear     *Replay // Best clear of the current stage (nil = none)
+}
+
+// snapshot copies the recorder state for the attempt in progress
+func (rr *ReplayRecorder) snapshot() replaySnapshot {
+	current := rr.current
+	// Cap the inputs so jumps recorded after the snapshot cannot overwrite them
+	current.Inputs = current.Inputs[:len(current.Inputs):len(current.Inputs)]
+	return replaySnapshot{current: current, lastCompleted: rr.LastCompleted, bestClear: rr.BestClear[current.Stage]}
+}
+
+// restore puts the recorder back into a snapshot's state
+func (rr *ReplayRecorder) restore(snapshot replaySnapshot) {
+	rr.current = snapshot.current
+	rr.LastCompleted = snapshot.lastCompleted
+	if snapshot.bestClear != nil {
+		rr.BestClear[snapshot.current.Stage] = snapshot.bestClear
+	} else {
+		delete(rr.BestClear, snapshot.current.Stage)
+	}
+}
+
+// Save writes the replay as JSON, in the format ParseReplay reads
+func (r *Replay) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ParseReplay decodes a replay saved as JSON
+func ParseReplay(data []byte) (*Replay, error) {
+	var replay Replay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+// JumpsAt returns which units jumped at the given tick of the recorded attempt
+func (r *Replay) JumpsAt(tick int) (blueJump, redJump bool) {
+	for _, input := range r.Inputs {
+		if input.Tick != tick {
+			continue
+		}
+		switch input.Unit {
+		case UnitBlue:
+			blueJump = true
+		case UnitRed:
+			redJump = true
+		}
+	}
+	return blueJump, redJump
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"log"
 	"time"
@@ -33,6 +34,7 @@ type SoundManager struct {
 	shotPlayerPool  []*audio.Player // Pool of audio players for shot sound
 	maxConcurrent   int             // Maximum number of concurrent sounds
 	bgmPlayer       *audio.Player   // BGM player for background music (with infinite loop)
+	Muted           bool            // Whether all sound effects and music are silenced
 }
 
 // alignBytesToSampleBoundary ensures the byte position aligns to a sample boundary
@@ -319,7 +321,7 @@ func NewSoundManager() *SoundManager {
 }
 
 func (sm *SoundManager) PlayJumpSound() {
-	if sm.jumpSoundBytes == nil {
+	if sm.Muted || sm.jumpSoundBytes == nil {
 		return
 	}
 
@@ -343,7 +345,7 @@ func (sm *SoundManager) PlayJumpSound() {
 }
 
 func (sm *SoundManager) PlayDeadSound() {
-	if sm.deadSoundBytes == nil {
+	if sm.Muted || sm.deadSoundBytes == nil {
 		return
 	}
 
@@ -367,7 +369,7 @@ func (sm *SoundManager) PlayDeadSound() {
 }
 
 func (sm *SoundManager) PlayClearSound() {
-	if sm.clearSoundBytes == nil {
+	if sm.Muted || sm.clearSoundBytes == nil {
 		return
 	}
 
@@ -391,12 +393,33 @@ func (sm *SoundManager) PlayClearSound() {
 }
 
 func (sm *SoundManager) StartBGM() {
-	if sm.bgmPlayer != nil && !sm.bgmPlayer.IsPlaying() {
+	if !sm.Muted && sm.bgmPlayer != nil && !sm.bgmPlayer.IsPlaying() {
 		sm.bgmPlayer.Rewind()
 		sm.bgmPlayer.Play()
 	}
 }
 
+// SetBGM replaces the background music with an MP3 that loops from start to end
+func (sm *SoundManager) SetBGM(mp3Bytes []byte) error {
+	decoded, err := mp3.DecodeWithSampleRate(SampleRate, bytes.NewReader(mp3Bytes))
+	if err != nil {
+		return fmt.Errorf("failed to decode BGM: %w", err)
+	}
+	player, err := sm.audioContext.NewPlayer(audio.NewInfiniteLoop(decoded, decoded.Length()))
+	if err != nil {
+		return fmt.Errorf("failed to create BGM player: %w", err)
+	}
+	wasPlaying := sm.bgmPlayer != nil && sm.bgmPlayer.IsPlaying()
+	if sm.bgmPlayer != nil {
+		sm.bgmPlayer.Close()
+	}
+	sm.bgmPlayer = player
+	if wasPlaying {
+		sm.StartBGM()
+	}
+	return nil
+}
+
 func (sm *SoundManager) StopBGM() {
 	if sm.bgmPlayer != nil && sm.bgmPlayer.IsPlaying() {
 		sm.bgmPlayer.Pause()
@@ -404,7 +427,7 @@ func (sm *SoundManager) StopBGM() {
 }
 
 func (sm *SoundManager) PlayShotSound() {
-	if sm.shotSoundBytes == nil {
+	if sm.Muted || sm.shotSoundBytes == nil {
 		return
 	}
 
@@ -426,3 +449,20 @@ func (sm *SoundManager) PlayShotSound() {
 	}
 	tempPlayer.Play()
 }
+
+// SubscribeEvents connects gameplay events to their sound effects
+func (sm *SoundManager) SubscribeEvents(bus *EventBus) {
+	bus.Subscribe(EventJumped, func(Event) {
+		sm.PlayJumpSound()
+	})
+	bus.Subscribe(EventBounced, func(Event) {
+		sm.PlayJumpSound()
+	})
+	bus.Subscribe(EventDied, func(Event) {
+		sm.PlayDeadSound()
+	})
+	bus.Subscribe(EventStageCleared, func(Event) {
+		sm.StopBGM()
+		sm.PlayClearSound()
+	})
+}
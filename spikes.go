@@ -0,0 +1,69 @@
+package main
+
+// SpikeDirection is the direction a spike's tip points
+type SpikeDirection int
+
+const (
+	SpikeUp    SpikeDirection = iota // Floor spike (default)
+	SpikeDown                        // Ceiling spike
+	SpikeLeft                        // Wall spike on the right side of a gap
+	SpikeRight                       // Wall spike on the left side of a gap
+)
+
+// point is a 2D position in pixels
+type point struct {
+	X, Y float64
+}
+
+// Triangle returns the corners of the spike's triangle: the two base corners, then the tip.
+// Rendering and collision both use this shape so the hitbox matches what is drawn.
+func (s Spike) Triangle() [3]point {
+	const size = CellSize
+	x, y := s.X, s.Y
+	switch s.Direction {
+	case SpikeDown:
+		return [3]point{{x, y}, {x + size, y}, {x + size/2, y + size}}
+	case SpikeLeft:
+		return [3]point{{x + size, y}, {x + size, y + size}, {x, y + size/2}}
+	case SpikeRight:
+		return [3]point{{x, y}, {x, y + size}, {x + size, y + size/2}}
+	default:
+		return [3]point{{x, y + size}, {x + size, y + size}, {x + size/2, y}}
+	}
+}
+
+// rectIntersectsTriangle reports whether an axis-aligned rectangle and a triangle overlap
+// by more than touching edges (separating axis test)
+func rectIntersectsTriangle(left, top, right, bottom float64, tri [3]point) bool {
+	corners := [4]point{{left, top}, {right, top}, {right, bottom}, {left, bottom}}
+
+	// Rectangle axes, then the normal of each triangle edge
+	axes := []point{{1, 0}, {0, 1}}
+	for i := range tri {
+		edge := point{tri[(i+1)%3].X - tri[i].X, tri[(i+1)%3].Y - tri[i].Y}
+		axes = append(axes, point{-edge.Y, edge.X})
+	}
+
+	for _, axis := range axes {
+		rectMin, rectMax := project(corners[:], axis)
+		triMin, triMax := project(tri[:], axis)
+		if rectMax <= triMin || triMax <= rectMin {
+			return false
+		}
+	}
+	return true
+}
+
+// project returns the extent of points projected onto an axis
+func project(points []point, axis point) (lo, hi float64) {
+	for i, p := range points {
+		d := p.X*axis.X + p.Y*axis.Y
+		if i == 0 || d < lo {
+			lo = d
+		}
+		if i == 0 || d > hi {
+			hi = d
+		}
+	}
+	return lo, hi
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // Register PNG decoder for the texture atlas
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Texture atlas layout (generated by cmd/atlasgen)
+const (
+	atlasTileSize = CellSize
+
+	// Row 0: auto-tile variants indexed by neighbour mask
+	atlasAutoTileRow = 0
+	// Row 1: themed tiles
+	atlasThemedRow    = 1
+	atlasSpikeCol     = 0
+	atlasGoalCol      = 1
+	atlasSpeedUpCol   = 2
+	atlasSpeedDownCol = 3
+	atlasFlagCol      = 4
+	atlasOneWayCol    = 5
+	atlasReverseCol   = 6
+	atlasSpringCol    = 7
+	atlasConveyorCol  = 8
+	atlasStarCol      = 9
+	atlasWalkerCol    = 10
+	atlasBlockCol     = 11
+	atlasLaserCol     = 12
+	atlasTurretCol    = 13
+	// Row 2: unit animation frames facing right
+	atlasUnitRow = 2
+)
+
+// Neighbour bits used to pick an auto-tile variant
+const (
+	NeighbourNorth = 1 << iota
+	NeighbourEast
+	NeighbourSouth
+	NeighbourWest
+)
+
+// Unit animation frames in the atlas
+const (
+	UnitFrameIdle  = 0 // 2 frames
+	UnitFrameRun   = 2 // 4 frames
+	UnitFrameJump  = 6
+	UnitFrameFall  = 7
+	unitFrameCount = 8
+)
+
+// TileKind groups platforms that should visually join when auto-tiling
+type TileKind int
+
+const (
+	TileNone TileKind = iota
+	TileSolid
+	TileGoal
+	TileSpeedUp
+	TileSpeedDown
+	TileOneWay
+	TileReverse
+	TileSpring
+	TileConveyor
+	TileBlueOnly // Auto-tiled, but joins only with other blue-only cells
+	TileRedOnly  // Auto-tiled, but joins only with other red-only cells
+)
+
+// SpriteAtlas holds the sub-images cut from the embedded texture atlas
+type SpriteAtlas struct {
+	AutoTiles  [16]*ebiten.Image
+	Spike      *ebiten.Image
+	Goal       *ebiten.Image
+	SpeedUp    *ebiten.Image
+	SpeedDown  *ebiten.Image
+	Flag       *ebiten.Image
+	OneWay     *ebiten.Image
+	Reverse    *ebiten.Image
+	Spring     *ebiten.Image
+	Conveyor   *ebiten.Image // Pushing right; mirrored for left conveyors
+	Star       *ebiten.Image
+	Walker     *ebiten.Image // Facing right; mirrored for walkers going left
+	Block      *ebiten.Image
+	Laser      *ebiten.Image
+	Turret     *ebiten.Image // Facing right; mirrored for turrets firing left
+	UnitFrames [unitFrameCount]*ebiten.Image
+}
+
+// LoadSpriteAtlas decodes the texture atlas and slices it into tiles
+func LoadSpriteAtlas(data []byte) (*SpriteAtlas, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode texture atlas: %w", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() < 16*atlasTileSize || bounds.Dy() < 3*atlasTileSize {
+		return nil, fmt.Errorf("texture atlas is too small: %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	sheet := ebiten.NewImageFromImage(img)
+	tile := func(col, row int) *ebiten.Image {
+		rect := image.Rect(col*atlasTileSize, row*atlasTileSize, (col+1)*atlasTileSize, (row+1)*atlasTileSize)
+		return sheet.SubImage(rect).(*ebiten.Image)
+	}
+
+	atlas := &SpriteAtlas{
+		Spike:     tile(atlasSpikeCol, atlasThemedRow),
+		Goal:      tile(atlasGoalCol, atlasThemedRow),
+		SpeedUp:   tile(atlasSpeedUpCol, atlasThemedRow),
+		SpeedDown: tile(atlasSpeedDownCol, atlasThemedRow),
+		Flag:      tile(atlasFlagCol, atlasThemedRow),
+		OneWay:    tile(atlasOneWayCol, atlasThemedRow),
+		Reverse:   tile(atlasReverseCol, atlasThemedRow),
+		Spring:    tile(atlasSpringCol, atlasThemedRow),
+		Conveyor:  tile(atlasConveyorCol, atlasThemedRow),
+		Star:      tile(atlasStarCol, atlasThemedRow),
+		Walker:    tile(atlasWalkerCol, atlasThemedRow),
+		Block:     tile(atlasBlockCol, atlasThemedRow),
+		Laser:     tile(atlasLaserCol, atlasThemedRow),
+		Turret:    tile(atlasTurretCol, atlasThemedRow),
+	}
+	for mask := range atlas.AutoTiles {
+		atlas.AutoTiles[mask] = tile(mask, atlasAutoTileRow)
+	}
+	for frame := range atlas.UnitFrames {
+		atlas.UnitFrames[frame] = tile(frame, atlasUnitRow)
+	}
+	return atlas, nil
+}
+
+// StageTile is a single grid cell of a platform to draw with a sprite
+type StageTile struct {
+	GridX, GridY int
+	Kind         TileKind
+	Mask         int // Neighbour bits of adjacent cells with the same kind
+	Platform     int // Index into Stage.Platforms
+}
+
+// platformTileKind returns how a platform is themed when drawn with sprites
+func platformTileKind(platform Platform) TileKind {
+	switch {
+	case platform.IsGoal:
+		return TileGoal
+	case platform.Only == UnitBlue:
+		return TileBlueOnly
+	case platform.Only == UnitRed:
+		return TileRedOnly
+	case platform.OneWay:
+		return TileOneWay
+	case platform.Reverse:
+		return TileReverse
+	case platform.Bounce > 0:
+		return TileSpring
+	case platform.Conveyor != 0:
+		return TileConveyor
+	case platform.SpeedModifier > 1.0:
+		return TileSpeedUp
+	case platform.SpeedModifier > 0 && platform.SpeedModifier < 1.0:
+		return TileSpeedDown
+	default:
+		return TileSolid
+	}
+}
+
+// isGridAligned reports whether a platform covers whole grid cells
+func isGridAligned(platform Platform) bool {
+	aligned := func(v float64) bool {
+		return v == float64(int(v)/CellSize*CellSize)
+	}
+	return aligned(platform.X) && aligned(platform.Y) && aligned(platform.Width) && aligned(platform.Height)
+}
+
+// BuildStageTiles splits grid-aligned platforms into cells and picks an
+// auto-tile variant for each cell from its neighbours in the grid.
+// Platforms that are not grid aligned are skipped (the caller draws them as shapes).
+func BuildStageTiles(stage *Stage) []StageTile {
+	kinds := make(map[GridPosition]TileKind)
+	var tiles []StageTile
+	for i, platform := range stage.Platforms {
+		if !isGridAligned(platform) {
+			continue
+		}
+		kind := platformTileKind(platform)
+		startX, startY := PixelToGridX(platform.X), PixelToGridY(platform.Y)
+		endX := startX + int(platform.Width)/CellSize
+		endY := startY + int(platform.Height)/CellSize
+		for y := startY; y < endY; y++ {
+			for x := startX; x < endX; x++ {
+				kinds[GridPosition{X: x, Y: y}] = kind
+				tiles = append(tiles, StageTile{GridX: x, GridY: y, Kind: kind, Platform: i})
+			}
+		}
+	}
+
+	neighbours := []struct {
+		dx, dy int
+		bit    int
+	}{
+		{0, -1, NeighbourNorth},
+		{1, 0, NeighbourEast},
+		{0, 1, NeighbourSouth},
+		{-1, 0, NeighbourWest},
+	}
+	for i := range tiles {
+		for _, n := range neighbours {
+			if kinds[GridPosition{X: tiles[i].GridX + n.dx, Y: tiles[i].GridY + n.dy}] == tiles[i].Kind {
+				tiles[i].Mask |= n.bit
+			}
+		}
+	}
+	return tiles
+}
+
+// unitAnimationFrame picks the atlas frame for a unit's current movement state
+func unitAnimationFrame(unit *Unit, tick int) int {
+	switch {
+	case unit.Stopped:
+		return UnitFrameIdle + (tick/30)%2
+	case !unit.OnGround && unit.VY < 0:
+		return UnitFrameJump
+	case !unit.OnGround:
+		return UnitFrameFall
+	default:
+		return UnitFrameRun + (tick/6)%4
+	}
+}
+
+// SpriteRenderer draws the world with tiles from the texture atlas
+type SpriteRenderer struct {
+	Atlas    *SpriteAtlas
+	Fallback *VectorRenderer // Draws platforms that cannot be tiled
+
+	tiledStage *Stage
+	tiles      []StageTile
+}
+
+// NewSpriteRenderer creates a sprite renderer for the given atlas
+func NewSpriteRenderer(atlas *SpriteAtlas, fallback *VectorRenderer) *SpriteRenderer {
+	return &SpriteRenderer{
+		Atlas:    atlas,
+		Fallback: fallback,
+	}
+}
+
+// DrawStage draws auto-tiled platforms and themed spikes
+func (r *SpriteRenderer) DrawStage(dst *ebiten.Image, stage *Stage) {
+	// Tiles only depend on the stage layout, so rebuild them only when the stage changes
+	if r.tiledStage != stage {
+		r.tiles = BuildStageTiles(stage)
+		r.tiledStage = stage
+	}
+
+	for _, platform := range stage.Platforms {
+		if !isGridAligned(platform) {
+			r.Fallback.DrawStage(dst, &Stage{Platforms: []Platform{platform}})
+		}
+	}
+
+	for _, tile := range r.tiles {
+		var img *ebiten.Image
+		switch tile.Kind {
+		case TileGoal:
+			img = r.Atlas.Goal
+		case TileSpeedUp:
+			img = r.Atlas.SpeedUp
+		case TileSpeedDown:
+			img = r.Atlas.SpeedDown
+		case TileOneWay:
+			img = r.Atlas.OneWay
+		case TileReverse:
+			img = r.Atlas.Reverse
+		case TileSpring:
+			img = r.Atlas.Spring
+		case TileConveyor:
+			img = r.Atlas.Conveyor
+		default:
+			img = r.Atlas.AutoTiles[tile.Mask]
+		}
+		platform := stage.Platforms[tile.Platform]
+		tint := platform.Color
+		if tile.Kind == TileGoal {
+			tint = GoalColor
+		}
+		op := &ebiten.DrawImageOptions{}
+		if tile.Kind == TileConveyor && platform.Conveyor < 0 {
+			op.GeoM.Scale(-1, 1)
+			op.GeoM.Translate(atlasTileSize, 0)
+		}
+		op.GeoM.Translate(GridToPixelX(tile.GridX), GridToPixelY(tile.GridY))
+		op.ColorScale.ScaleWithColor(tint)
+		dst.DrawImage(img, op)
+	}
+
+	// Outline and hatch unit-only platforms (unaligned ones were drawn by the fallback)
+	for _, platform := range stage.Platforms {
+		if isGridAligned(platform) {
+			drawUnitOnlyPattern(dst, platform)
+		}
+	}
+
+	for _, spike := range stage.Spikes {
+		// The spike tile points up; rotate it around its centre for other directions
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(-CellSize/2, -CellSize/2)
+		op.GeoM.Rotate(spikeRotation(spike.Direction))
+		op.GeoM.Translate(spike.X+CellSize/2, spike.Y+CellSize/2)
+		op.ColorScale.ScaleWithColor(spike.Color)
+		dst.DrawImage(r.Atlas.Spike, op)
+	}
+	drawUnitOnlySpikeMarks(dst, stage)
+}
+
+// DrawUnit draws an animated unit sprite facing its walking direction
+func (r *SpriteRenderer) DrawUnit(dst *ebiten.Image, unit *Unit, tick int) {
+	frame := r.Atlas.UnitFrames[unitAnimationFrame(unit, tick)]
+	op := &ebiten.DrawImageOptions{}
+	if unit.Direction < 0 {
+		// Frames face right, so mirror them for units walking left
+		op.GeoM.Scale(-1, 1)
+		op.GeoM.Translate(UnitSize, 0)
+	}
+	op.GeoM.Translate(unit.X, unit.Y)
+	op.ColorScale.ScaleWithColor(unit.Color)
+	dst.DrawImage(frame, op)
+}
+
+// DrawCheckpoint draws a flag sprite tinted with its unit colour
+func (r *SpriteRenderer) DrawCheckpoint(dst *ebiten.Image, checkpoint Checkpoint, active bool) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(checkpoint.X, checkpoint.Y)
+	op.ColorScale.ScaleWithColor(checkpointColor(checkpoint, active))
+	dst.DrawImage(r.Atlas.Flag, op)
+}
+
+// DrawCollectible draws a bobbing star sprite
+func (r *SpriteRenderer) DrawCollectible(dst *ebiten.Image, item Collectible, tick int) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(item.X, item.Y+float64(collectibleBob(tick)))
+	op.ColorScale.ScaleWithColor(CollectibleColor)
+	dst.DrawImage(r.Atlas.Star, op)
+}
+
+// DrawHazard draws a hazard sprite tinted with the hazard colour; laser beams are drawn as shapes
+func (r *SpriteRenderer) DrawHazard(dst *ebiten.Image, hazard Hazard, tick int) {
+	var img *ebiten.Image
+	tint := color.Color(HazardColor)
+	switch hazard.Kind {
+	case HazardWalker:
+		img = r.Atlas.Walker
+	case HazardFallingBlock:
+		img = r.Atlas.Block
+	case HazardLaser:
+		drawLaserBeam(dst, hazard, tick)
+		img = r.Atlas.Laser
+		tint = laserEmitterColor(tick)
+	case HazardTurret:
+		img = r.Atlas.Turret
+	default:
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	if hazard.Direction < 0 {
+		op.GeoM.Scale(-1, 1)
+		op.GeoM.Translate(CellSize, 0)
+	}
+	op.GeoM.Translate(hazard.X+float64(hazardShake(hazard, tick)), hazard.Y)
+	op.ColorScale.ScaleWithColor(tint)
+	dst.DrawImage(img, op)
+}
+
+// DrawProjectile draws a turret shot with the vector fallback
+func (r *SpriteRenderer) DrawProjectile(dst *ebiten.Image, p Projectile) {
+	r.Fallback.DrawProjectile(dst, p)
+}
+
+// spikeRotation returns the angle that turns the upward spike tile to the given direction
+func spikeRotation(direction SpikeDirection) float64 {
+	switch direction {
+	case SpikeDown:
+		return math.Pi
+	case SpikeLeft:
+		return -math.Pi / 2
+	case SpikeRight:
+		return math.Pi / 2
+	default:
+		return 0
+	}
+}
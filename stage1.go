@@ -6,28 +6,31 @@ func LoadStage1() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
-			// Regular platform at (5, 28) size 1x3
-			CreateGridPlatform(5, 28, 1, 3),
+			// Regular platform at (5, 28) size 1x1
+			CreateGridPlatform(5, 28, 1, 1),
 
-			// Regular platform at (34, 28) size 1x3
-			CreateGridPlatform(34, 28, 1, 3),
+			// Regular platform at (34, 28) size 1x1
+			CreateGridPlatform(34, 28, 1, 1),
 
-			// Regular platform at (1, 29) size 39x2
-			CreateGridPlatform(1, 29, 39, 2),
+			// Regular platform at (1, 29) size 38x2
+			CreateGridPlatform(1, 29, 38, 2),
 
 			// Goal platform at (19, 27) size 2x2
 			CreateGridGoalPlatform(19, 27, 2, 2),
 		},
 		Spikes: []Spike{},
+		Info: StageInfo{
+			Tutorial: "@50,440 #c8c8ff Press F key\n@60,460 #c8c8ff to Jump\n@600,440 #ffc8c8 Press J key\n@610,460 #ffc8c8 to Jump\n@340,460 #ffff64 Goal Platform",
+		},
 	}
 }
 
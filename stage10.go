@@ -6,14 +6,14 @@ func LoadStage10() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
 			// Regular platform at (5, 6) size 7x1
 			CreateGridPlatform(5, 6, 7, 1),
@@ -30,8 +30,8 @@ func LoadStage10() *Stage {
 			// Regular platform at (15, 8) size 3x1
 			CreateGridPlatform(15, 8, 3, 1),
 
-			// Regular platform at (38, 8) size 2x1
-			CreateGridPlatform(38, 8, 2, 1),
+			// Regular platform at (38, 8) size 1x1
+			CreateGridPlatform(38, 8, 1, 1),
 
 			// Regular platform at (18, 9) size 3x1
 			CreateGridPlatform(18, 9, 3, 1),
@@ -51,8 +51,8 @@ func LoadStage10() *Stage {
 			// Regular platform at (34, 11) size 2x2
 			CreateGridPlatform(34, 11, 2, 2),
 
-			// Regular platform at (30, 12) size 6x1
-			CreateGridPlatform(30, 12, 6, 1),
+			// Regular platform at (30, 12) size 4x1
+			CreateGridPlatform(30, 12, 4, 1),
 
 			// Regular platform at (1, 13) size 1x1
 			CreateGridPlatform(1, 13, 1, 1),
@@ -84,23 +84,23 @@ func LoadStage10() *Stage {
 			// Regular platform at (30, 17) size 3x2
 			CreateGridPlatform(30, 17, 3, 2),
 
-			// Regular platform at (16, 18) size 6x1
-			CreateGridPlatform(16, 18, 6, 1),
+			// Regular platform at (16, 18) size 5x1
+			CreateGridPlatform(16, 18, 5, 1),
 
-			// Regular platform at (25, 18) size 3x1
-			CreateGridPlatform(25, 18, 3, 1),
+			// Regular platform at (25, 18) size 2x1
+			CreateGridPlatform(25, 18, 2, 1),
 
 			// Regular platform at (33, 18) size 1x1
 			CreateGridPlatform(33, 18, 1, 1),
 
-			// Regular platform at (37, 18) size 3x1
-			CreateGridPlatform(37, 18, 3, 1),
+			// Regular platform at (37, 18) size 2x1
+			CreateGridPlatform(37, 18, 2, 1),
 
-			// Regular platform at (38, 21) size 2x4
-			CreateGridPlatform(38, 21, 2, 4),
+			// Regular platform at (38, 21) size 1x1
+			CreateGridPlatform(38, 21, 1, 1),
 
-			// Regular platform at (37, 22) size 3x3
-			CreateGridPlatform(37, 22, 3, 3),
+			// Regular platform at (37, 22) size 2x1
+			CreateGridPlatform(37, 22, 2, 1),
 
 			// Regular platform at (5, 23) size 2x2
 			CreateGridPlatform(5, 23, 2, 2),
@@ -117,23 +117,23 @@ func LoadStage10() *Stage {
 			// Regular platform at (28, 23) size 1x2
 			CreateGridPlatform(28, 23, 1, 2),
 
-			// Regular platform at (36, 23) size 4x2
-			CreateGridPlatform(36, 23, 4, 2),
+			// Regular platform at (36, 23) size 3x1
+			CreateGridPlatform(36, 23, 3, 1),
 
-			// Regular platform at (10, 24) size 10x1
-			CreateGridPlatform(10, 24, 10, 1),
+			// Regular platform at (10, 24) size 3x1
+			CreateGridPlatform(10, 24, 3, 1),
 
-			// Regular platform at (29, 24) size 11x1
-			CreateGridPlatform(29, 24, 11, 1),
+			// Regular platform at (29, 24) size 10x1
+			CreateGridPlatform(29, 24, 10, 1),
 
-			// Regular platform at (1, 27) size 1x4
-			CreateGridPlatform(1, 27, 1, 4),
+			// Regular platform at (1, 27) size 1x1
+			CreateGridPlatform(1, 27, 1, 1),
 
-			// Regular platform at (2, 28) size 1x3
-			CreateGridPlatform(2, 28, 1, 3),
+			// Regular platform at (1, 28) size 2x1
+			CreateGridPlatform(1, 28, 2, 1),
 
-			// Regular platform at (3, 29) size 3x2
-			CreateGridPlatform(3, 29, 3, 2),
+			// Regular platform at (1, 29) size 5x2
+			CreateGridPlatform(1, 29, 5, 2),
 
 			// Regular platform at (8, 29) size 1x2
 			CreateGridPlatform(8, 29, 1, 2),
@@ -156,20 +156,20 @@ func LoadStage10() *Stage {
 			// Regular platform at (32, 29) size 1x2
 			CreateGridPlatform(32, 29, 1, 2),
 
-			// Regular platform at (37, 29) size 3x2
-			CreateGridPlatform(37, 29, 3, 2),
+			// Regular platform at (37, 29) size 2x1
+			CreateGridPlatform(37, 29, 2, 1),
 
-			// Regular platform at (9, 30) size 5x1
-			CreateGridPlatform(9, 30, 5, 1),
+			// Regular platform at (9, 30) size 4x1
+			CreateGridPlatform(9, 30, 4, 1),
 
-			// Regular platform at (17, 30) size 5x1
-			CreateGridPlatform(17, 30, 5, 1),
+			// Regular platform at (17, 30) size 4x1
+			CreateGridPlatform(17, 30, 4, 1),
 
-			// Regular platform at (25, 30) size 5x1
-			CreateGridPlatform(25, 30, 5, 1),
+			// Regular platform at (25, 30) size 4x1
+			CreateGridPlatform(25, 30, 4, 1),
 
-			// Regular platform at (33, 30) size 7x1
-			CreateGridPlatform(33, 30, 7, 1),
+			// Regular platform at (33, 30) size 6x1
+			CreateGridPlatform(33, 30, 6, 1),
 
 			// Goal platform at (37, 27) size 2x2
 			CreateGridGoalPlatform(37, 27, 2, 2),
@@ -6,14 +6,14 @@ func LoadStage2() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
 			// Regular platform at (1, 29) size 10x2
 			CreateGridPlatform(1, 29, 10, 2),
@@ -21,8 +21,8 @@ func LoadStage2() *Stage {
 			// Regular platform at (13, 29) size 14x2
 			CreateGridPlatform(13, 29, 14, 2),
 
-			// Regular platform at (29, 29) size 11x2
-			CreateGridPlatform(29, 29, 11, 2),
+			// Regular platform at (29, 29) size 10x2
+			CreateGridPlatform(29, 29, 10, 2),
 
 			// Goal platform at (19, 27) size 2x2
 			CreateGridGoalPlatform(19, 27, 2, 2),
@@ -41,6 +41,9 @@ func LoadStage2() *Stage {
 			// Spike at (28, 30)
 			CreateGridSpike(28, 30),
 		},
+		Info: StageInfo{
+			Tutorial: "@160,460 #ff6464 Jump over this\n@480,460 #ff6464 Jump over this",
+		},
 	}
 }
 
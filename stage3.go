@@ -6,23 +6,23 @@ func LoadStage3() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
-			// Regular platform at (19, 16) size 2x9
-			CreateGridPlatform(19, 16, 2, 9),
+			// Regular platform at (19, 16) size 2x8
+			CreateGridPlatform(19, 16, 2, 8),
 
 			// Regular platform at (1, 19) size 5x1
 			CreateGridPlatform(1, 19, 5, 1),
 
-			// Regular platform at (34, 19) size 6x1
-			CreateGridPlatform(34, 19, 6, 1),
+			// Regular platform at (34, 19) size 5x1
+			CreateGridPlatform(34, 19, 5, 1),
 
 			// Regular platform at (14, 24) size 12x1
 			CreateGridPlatform(14, 24, 12, 1),
@@ -33,8 +33,8 @@ func LoadStage3() *Stage {
 			// Regular platform at (13, 29) size 14x2
 			CreateGridPlatform(13, 29, 14, 2),
 
-			// Regular platform at (29, 29) size 11x2
-			CreateGridPlatform(29, 29, 11, 2),
+			// Regular platform at (29, 29) size 10x2
+			CreateGridPlatform(29, 29, 10, 2),
 
 			// Goal platform at (19, 27) size 2x2
 			CreateGridGoalPlatform(19, 27, 2, 2),
@@ -65,6 +65,9 @@ func LoadStage3() *Stage {
 			// Spike at (28, 30)
 			CreateGridSpike(28, 30),
 		},
+		Info: StageInfo{
+			Tutorial: "@160,340 #ff9632 Speed Down\n@480,340 #ff9632 Speed Down\n@140,440 #32ff96 Speed Up\n@540,440 #32ff96 Speed Up",
+		},
 	}
 }
 
@@ -6,20 +6,23 @@ func LoadStage4() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
-			// Regular platform at (19, 10) size 2x15
-			CreateGridPlatform(19, 10, 2, 15),
+			// Regular platform at (19, 10) size 2x13
+			CreateGridPlatform(19, 10, 2, 13),
 
-			// Regular platform at (3, 12) size 34x1
-			CreateGridPlatform(3, 12, 34, 1),
+			// Regular platform at (3, 12) size 16x1
+			CreateGridPlatform(3, 12, 16, 1),
+
+			// Regular platform at (21, 12) size 16x1
+			CreateGridPlatform(21, 12, 16, 1),
 
 			// Regular platform at (1, 17) size 16x2
 			CreateGridPlatform(1, 17, 16, 2),
@@ -33,8 +36,8 @@ func LoadStage4() *Stage {
 			// Regular platform at (31, 17) size 2x2
 			CreateGridPlatform(31, 17, 2, 2),
 
-			// Regular platform at (35, 17) size 5x2
-			CreateGridPlatform(35, 17, 5, 2),
+			// Regular platform at (35, 17) size 4x2
+			CreateGridPlatform(35, 17, 4, 2),
 
 			// Regular platform at (3, 23) size 2x2
 			CreateGridPlatform(3, 23, 2, 2),
@@ -54,8 +57,8 @@ func LoadStage4() *Stage {
 			// Regular platform at (13, 29) size 14x2
 			CreateGridPlatform(13, 29, 14, 2),
 
-			// Regular platform at (29, 29) size 11x2
-			CreateGridPlatform(29, 29, 11, 2),
+			// Regular platform at (29, 29) size 10x2
+			CreateGridPlatform(29, 29, 10, 2),
 
 			// Goal platform at (19, 27) size 2x2
 			CreateGridGoalPlatform(19, 27, 2, 2),
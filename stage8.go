@@ -6,29 +6,29 @@ func LoadStage8() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
+
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
 			// Regular platform at (19, 1) size 2x6
 			CreateGridPlatform(19, 1, 2, 6),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
-
 			// Regular platform at (7, 5) size 2x2
 			CreateGridPlatform(7, 5, 2, 2),
 
 			// Regular platform at (24, 5) size 5x2
 			CreateGridPlatform(24, 5, 5, 2),
 
-			// Regular platform at (9, 6) size 12x1
-			CreateGridPlatform(9, 6, 12, 1),
+			// Regular platform at (9, 6) size 10x1
+			CreateGridPlatform(9, 6, 10, 1),
 
-			// Regular platform at (29, 6) size 11x1
-			CreateGridPlatform(29, 6, 11, 1),
+			// Regular platform at (29, 6) size 10x1
+			CreateGridPlatform(29, 6, 10, 1),
 
 			// Regular platform at (1, 7) size 1x1
 			CreateGridPlatform(1, 7, 1, 1),
@@ -45,11 +45,11 @@ func LoadStage8() *Stage {
 			// Regular platform at (5, 11) size 4x2
 			CreateGridPlatform(5, 11, 4, 2),
 
-			// Regular platform at (14, 11) size 2x2
-			CreateGridPlatform(14, 11, 2, 2),
+			// Regular platform at (14, 11) size 2x1
+			CreateGridPlatform(14, 11, 2, 1),
 
-			// Regular platform at (24, 11) size 2x2
-			CreateGridPlatform(24, 11, 2, 2),
+			// Regular platform at (24, 11) size 2x1
+			CreateGridPlatform(24, 11, 2, 1),
 
 			// Regular platform at (31, 11) size 4x2
 			CreateGridPlatform(31, 11, 4, 2),
@@ -66,14 +66,14 @@ func LoadStage8() *Stage {
 			// Regular platform at (24, 17) size 4x2
 			CreateGridPlatform(24, 17, 4, 2),
 
-			// Regular platform at (30, 17) size 10x2
-			CreateGridPlatform(30, 17, 10, 2),
+			// Regular platform at (30, 17) size 9x2
+			CreateGridPlatform(30, 17, 9, 2),
 
-			// Regular platform at (1, 23) size 4x2
-			CreateGridPlatform(1, 23, 4, 2),
+			// Regular platform at (1, 23) size 4x1
+			CreateGridPlatform(1, 23, 4, 1),
 
-			// Regular platform at (11, 23) size 2x2
-			CreateGridPlatform(11, 23, 2, 2),
+			// Regular platform at (11, 23) size 2x1
+			CreateGridPlatform(11, 23, 2, 1),
 
 			// Regular platform at (15, 23) size 5x2
 			CreateGridPlatform(15, 23, 5, 2),
@@ -81,14 +81,14 @@ func LoadStage8() *Stage {
 			// Regular platform at (22, 23) size 7x2
 			CreateGridPlatform(22, 23, 7, 2),
 
-			// Regular platform at (31, 23) size 2x2
-			CreateGridPlatform(31, 23, 2, 2),
+			// Regular platform at (31, 23) size 2x1
+			CreateGridPlatform(31, 23, 2, 1),
 
-			// Regular platform at (5, 24) size 2x1
-			CreateGridPlatform(5, 24, 2, 1),
+			// Regular platform at (1, 24) size 6x1
+			CreateGridPlatform(1, 24, 6, 1),
 
-			// Regular platform at (9, 24) size 11x1
-			CreateGridPlatform(9, 24, 11, 1),
+			// Regular platform at (9, 24) size 6x1
+			CreateGridPlatform(9, 24, 6, 1),
 
 			// Regular platform at (29, 24) size 6x1
 			CreateGridPlatform(29, 24, 6, 1),
@@ -114,17 +114,17 @@ func LoadStage8() *Stage {
 			// Regular platform at (32, 29) size 1x2
 			CreateGridPlatform(32, 29, 1, 2),
 
-			// Regular platform at (35, 29) size 5x2
-			CreateGridPlatform(35, 29, 5, 2),
+			// Regular platform at (35, 29) size 4x2
+			CreateGridPlatform(35, 29, 4, 2),
 
-			// Regular platform at (8, 30) size 9x1
-			CreateGridPlatform(8, 30, 9, 1),
+			// Regular platform at (8, 30) size 8x1
+			CreateGridPlatform(8, 30, 8, 1),
 
-			// Regular platform at (20, 30) size 5x1
-			CreateGridPlatform(20, 30, 5, 1),
+			// Regular platform at (20, 30) size 4x1
+			CreateGridPlatform(20, 30, 4, 1),
 
-			// Regular platform at (28, 30) size 5x1
-			CreateGridPlatform(28, 30, 5, 1),
+			// Regular platform at (28, 30) size 4x1
+			CreateGridPlatform(28, 30, 4, 1),
 
 			// Goal platform at (1, 27) size 2x2
 			CreateGridGoalPlatform(1, 27, 2, 2),
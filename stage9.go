@@ -6,17 +6,17 @@ func LoadStage9() *Stage {
 	return &Stage{
 		Platforms: []Platform{
 
-			// Regular platform at (0, 0) size 40x1
-			CreateGridPlatform(0, 0, 40, 1),
+			// Regular platform at (0, 0) size 1x31
+			CreateGridPlatform(0, 0, 1, 31),
 
-			// Regular platform at (0, 1) size 1x30
-			CreateGridPlatform(0, 1, 1, 30),
+			// Regular platform at (1, 0) size 38x1
+			CreateGridPlatform(1, 0, 38, 1),
 
-			// Regular platform at (39, 1) size 1x30
-			CreateGridPlatform(39, 1, 1, 30),
+			// Regular platform at (39, 0) size 1x31
+			CreateGridPlatform(39, 0, 1, 31),
 
-			// Regular platform at (23, 6) size 17x1
-			CreateGridPlatform(23, 6, 17, 1),
+			// Regular platform at (23, 6) size 16x1
+			CreateGridPlatform(23, 6, 16, 1),
 
 			// Regular platform at (5, 11) size 4x2
 			CreateGridPlatform(5, 11, 4, 2),
@@ -24,8 +24,8 @@ func LoadStage9() *Stage {
 			// Regular platform at (30, 11) size 1x2
 			CreateGridPlatform(30, 11, 1, 2),
 
-			// Regular platform at (11, 12) size 20x1
-			CreateGridPlatform(11, 12, 20, 1),
+			// Regular platform at (11, 12) size 19x1
+			CreateGridPlatform(11, 12, 19, 1),
 
 			// Regular platform at (1, 13) size 1x1
 			CreateGridPlatform(1, 13, 1, 1),
@@ -60,23 +60,23 @@ func LoadStage9() *Stage {
 			// Regular platform at (34, 17) size 1x1
 			CreateGridPlatform(34, 17, 1, 1),
 
-			// Regular platform at (7, 18) size 6x1
-			CreateGridPlatform(7, 18, 6, 1),
+			// Regular platform at (7, 18) size 5x1
+			CreateGridPlatform(7, 18, 5, 1),
 
-			// Regular platform at (25, 18) size 3x1
-			CreateGridPlatform(25, 18, 3, 1),
+			// Regular platform at (25, 18) size 2x1
+			CreateGridPlatform(25, 18, 2, 1),
 
 			// Regular platform at (31, 18) size 3x1
 			CreateGridPlatform(31, 18, 3, 1),
 
-			// Regular platform at (37, 18) size 3x3
-			CreateGridPlatform(37, 18, 3, 3),
+			// Regular platform at (37, 18) size 2x3
+			CreateGridPlatform(37, 18, 2, 3),
 
-			// Regular platform at (13, 22) size 1x3
-			CreateGridPlatform(13, 22, 1, 3),
+			// Regular platform at (13, 22) size 1x1
+			CreateGridPlatform(13, 22, 1, 1),
 
-			// Regular platform at (26, 22) size 1x3
-			CreateGridPlatform(26, 22, 1, 3),
+			// Regular platform at (26, 22) size 1x1
+			CreateGridPlatform(26, 22, 1, 1),
 
 			// Regular platform at (5, 23) size 2x2
 			CreateGridPlatform(5, 23, 2, 2),
@@ -111,20 +111,20 @@ func LoadStage9() *Stage {
 			// Regular platform at (32, 29) size 1x2
 			CreateGridPlatform(32, 29, 1, 2),
 
-			// Regular platform at (37, 29) size 3x2
-			CreateGridPlatform(37, 29, 3, 2),
+			// Regular platform at (37, 29) size 2x1
+			CreateGridPlatform(37, 29, 2, 1),
 
-			// Regular platform at (9, 30) size 5x1
-			CreateGridPlatform(9, 30, 5, 1),
+			// Regular platform at (9, 30) size 4x1
+			CreateGridPlatform(9, 30, 4, 1),
 
-			// Regular platform at (17, 30) size 5x1
-			CreateGridPlatform(17, 30, 5, 1),
+			// Regular platform at (17, 30) size 4x1
+			CreateGridPlatform(17, 30, 4, 1),
 
-			// Regular platform at (25, 30) size 5x1
-			CreateGridPlatform(25, 30, 5, 1),
+			// Regular platform at (25, 30) size 4x1
+			CreateGridPlatform(25, 30, 4, 1),
 
-			// Regular platform at (33, 30) size 7x1
-			CreateGridPlatform(33, 30, 7, 1),
+			// Regular platform at (33, 30) size 6x1
+			CreateGridPlatform(33, 30, 6, 1),
 
 			// Goal platform at (37, 27) size 2x2
 			CreateGridGoalPlatform(37, 27, 2, 2),
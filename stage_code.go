@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/pankona/egj2025/internal/stagefile"
+)
+
+// Stage code keys (stage select screen)
+const (
+	CopyStageCodeKey  = ebiten.KeyC
+	PasteStageCodeKey = ebiten.KeyV
+)
+
+// StageGrid converts a stage back into the lines of its stage file
+func StageGrid(stage *Stage, start StartPositions) []string {
+	grid := make([][]byte, GridHeight)
+	for y := range grid {
+		grid[y] = []byte(strings.Repeat(".", GridWidth))
+	}
+	set := func(px, py float64, glyph byte) {
+		x, y := int(px)/CellSize, int(py)/CellSize
+		if y >= 0 && y < len(grid) && x >= 0 && x < GridWidth {
+			grid[y][x] = glyph
+		}
+	}
+
+	for _, p := range stage.Platforms {
+		glyph := platformGlyph(p)
+		for y := p.Y; y < p.Y+p.Height; y += CellSize {
+			for x := p.X; x < p.X+p.Width; x += CellSize {
+				set(x, y, glyph)
+			}
+		}
+	}
+	for _, s := range stage.Spikes {
+		set(s.X, s.Y, spikeGlyph(s))
+	}
+	for _, c := range stage.Checkpoints {
+		set(c.X, c.Y, map[UnitKind]byte{UnitBlue: 'l', UnitRed: 'r'}[c.Unit])
+	}
+	for _, c := range stage.Collectibles {
+		set(c.X, c.Y, '*')
+	}
+	for _, h := range stage.Hazards {
+		set(h.X, h.Y, map[HazardKind]byte{HazardWalker: 'w', HazardFallingBlock: '#', HazardLaser: '!', HazardTurret: 'T'}[h.Kind])
+	}
+	set(start.BlueX, start.BlueY, 'L')
+	set(start.RedX, start.RedY, 'R')
+
+	lines := make([]string, len(grid))
+	for y, row := range grid {
+		lines[y] = string(row)
+	}
+	return lines
+}
+
+// platformGlyph returns the stage file glyph of a platform
+func platformGlyph(p Platform) byte {
+	switch {
+	case p.Only == UnitBlue && p.IsGoal:
+		return '1'
+	case p.Only == UnitRed && p.IsGoal:
+		return '2'
+	case p.Only == UnitBlue:
+		return '3'
+	case p.Only == UnitRed:
+		return '4'
+	case p.IsGoal:
+		return 'G'
+	case p.SpeedModifier > 1:
+		return 'u'
+	case p.SpeedModifier < 1:
+		return 'd'
+	case p.OneWay:
+		return '-'
+	case p.Reverse:
+		return 'X'
+	case p.Bounce != 0:
+		return 's'
+	case p.Conveyor > 0:
+		return '}'
+	case p.Conveyor < 0:
+		return '{'
+	default:
+		return 'O'
+	}
+}
+
+// spikeGlyph returns the stage file glyph of a spike
+func spikeGlyph(s Spike) byte {
+	switch {
+	case s.Only == UnitBlue:
+		return '5'
+	case s.Only == UnitRed:
+		return '6'
+	}
+	return map[SpikeDirection]byte{SpikeUp: '^', SpikeDown: 'v', SpikeLeft: '<', SpikeRight: '>'}[s.Direction]
+}
+
+// StageCode encodes a stage into a shareable code
+func StageCode(stage *Stage, start StartPositions) string {
+	return stagefile.EncodeGrid(StageGrid(stage, start))
+}
+
+// ParseStageCode builds the stage a shared code describes
+func ParseStageCode(code string) (*Stage, StartPositions, error) {
+	lines, err := stagefile.DecodeGrid(code)
+	if err != nil {
+		return nil, StartPositions{}, err
+	}
+	return ParseStage([]byte(strings.Join(lines, "\n")))
+}
+
+// SharedStagePack wraps the stage of a shared code in a one-stage pack
+func SharedStagePack(code string) (*StagePack, error) {
+	stage, start, err := ParseStageCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return &StagePack{
+		Manifest: StagePackManifest{Name: "Shared stage", Author: "a friend"},
+		stages:   []*loadedStage{newLoadedStage(stage, start)},
+		shared:   true,
+	}, nil
+}
+
+// playStageCode starts playing the stage of a shared code
+func (g *Game) playStageCode(code string) {
+	pack, err := SharedStagePack(code)
+	if err != nil {
+		g.showNotice(fmt.Sprintf("Stage code: %v", err))
+		return
+	}
+	g.loadStagePack(pack)
+	g.startStage(1)
+	g.SoundManager.StartBGM()
+}
+
+// playingSharedStage reports whether the stage of a shared code is being played
+func (g *Game) playingSharedStage() bool {
+	pack, ok := g.StageLoader.Source.(*StagePack)
+	return ok && pack.shared
+}
+
+// leaveSharedStage goes back to the built-in stages after a shared stage
+func (g *Game) leaveSharedStage() {
+	g.StageLoader.UseBuiltinStages()
+	g.StageLoader.ResetToFirstStage()
+	g.clearCheckpoint()
+	g.syncStatsSource()
+}
+
+// copyStageCode copies the code of the given stage to the clipboard
+func (g *Game) copyStageCode(index int) {
+	blueX, blueY, redX, redY := g.StageLoader.StageStartPositions(index)
+	code := StageCode(g.StageLoader.LoadStage(index), StartPositions{blueX, blueY, redX, redY})
+	log.Printf("Stage %d code: %s", index, code)
+	if err := writeClipboard(code); err != nil {
+		g.showNotice("Stage code printed to the log")
+		return
+	}
+	g.showNotice(fmt.Sprintf("Copied the code of stage %d", index))
+}
+
+// clipboardText is the result of reading the clipboard
+type clipboardText struct {
+	text string
+	err  error
+}
+
+// pasteStageCode starts reading a stage code from the clipboard.
+// The browser reads the clipboard asynchronously, so the code is played by
+// updateStageCodePaste once it arrives.
+func (g *Game) pasteStageCode() {
+	if g.clipboardPaste == nil {
+		g.clipboardPaste = make(chan clipboardText, 1)
+	}
+	paste := g.clipboardPaste
+	readClipboard(func(text string, err error) {
+		select {
+		case paste <- clipboardText{text: text, err: err}:
+		default: // A paste is already waiting
+		}
+	})
+}
+
+// updateStageCodePaste plays a stage code that was read from the clipboard
+func (g *Game) updateStageCodePaste() {
+	select {
+	case pasted := <-g.clipboardPaste:
+		if pasted.err != nil {
+			g.showNotice(fmt.Sprintf("Cannot paste: %v", pasted.err))
+			return
+		}
+		g.playStageCode(pasted.text)
+	default:
+	}
+}
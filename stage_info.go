@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/pankona/egj2025/internal/stagefile"
+)
+
+// StageInfo is the optional front matter of a stage file (see stagefile.Metadata)
+type StageInfo struct {
+	Title      string
+	Author     string
+	Difficulty int     // 1 (easy) to stagefile.MaxDifficulty (0 = not rated)
+	ParTime    float64 // Target clear time in seconds (0 = none)
+	BGM        string  // Background music track name
+	Tutorial   string  // Hint shown while playing (lines separated by "\n", see stagefile.TutorialLabel)
+}
+
+// Title card layout
+const (
+	TitleCardFrames     = 150 // 2.5 seconds at 60 FPS
+	titleCardFadeFrames = 30
+	titleCardWidth      = 480
+	titleCardY          = 150
+	titleCardLineGap    = 26
+	tutorialY           = StageTextY + 40 // Below the stage number and notices
+)
+
+var (
+	titleCardBackground = color.RGBA{20, 30, 50, 220}
+	titleCardDimColor   = color.RGBA{180, 190, 210, 255}
+	tutorialColor       = color.RGBA{255, 255, 160, 255}
+)
+
+// showTitleCard shows the current stage's title card, if its front matter has
+// anything for it besides the tutorial
+func (g *Game) showTitleCard() {
+	g.TitleCardTimer = 0
+	if g.Stage == nil {
+		return
+	}
+	info := g.Stage.Info
+	info.Tutorial = ""
+	if info != (StageInfo{}) {
+		g.TitleCardTimer = TitleCardFrames
+	}
+}
+
+// titleCardLines returns the lines of the title card: the heading, then details
+func (g *Game) titleCardLines() []string {
+	info := g.Stage.Info
+	heading := fmt.Sprintf("Stage %d", g.StageLoader.CurrentStageIndex)
+	if info.Title != "" {
+		heading += ": " + info.Title
+	}
+	lines := []string{heading}
+	if info.Author != "" {
+		lines = append(lines, "by "+info.Author)
+	}
+	var details []string
+	if info.Difficulty > 0 {
+		details = append(details, fmt.Sprintf("Difficulty %d/%d", info.Difficulty, stagefile.MaxDifficulty))
+	}
+	if info.ParTime > 0 {
+		details = append(details, fmt.Sprintf("Par %gs", info.ParTime))
+	}
+	if info.BGM != "" {
+		details = append(details, "BGM: "+info.BGM)
+	}
+	if len(details) > 0 {
+		lines = append(lines, strings.Join(details, "   "))
+	}
+	return lines
+}
+
+// drawTitleCard draws the title card over the start of the stage, fading it out at the end
+func (g *Game) drawTitleCard(screen *ebiten.Image) {
+	if g.TitleCardTimer <= 0 || g.Stage == nil {
+		return
+	}
+	alpha := min(1, float32(g.TitleCardTimer)/titleCardFadeFrames)
+	lines := g.titleCardLines()
+
+	background := titleCardBackground
+	background.A = uint8(float32(background.A) * alpha)
+	height := float32(30 + titleCardLineGap*len(lines))
+	vector.DrawFilledRect(screen, (ScreenWidth-titleCardWidth)/2, titleCardY, titleCardWidth, height, background, false)
+
+	for i, line := range lines {
+		face, c := g.MarkFont, titleCardDimColor
+		if i == 0 {
+			face, c = g.Font, WhiteColor
+		}
+		drawCenteredText(screen, line, face, titleCardY+15+float64(i*titleCardLineGap), c, alpha)
+	}
+}
+
+// drawStageTutorial draws the stage's tutorial labels at their positions, and
+// the lines without one centred below the top of the screen
+func (g *Game) drawStageTutorial(screen *ebiten.Image) {
+	if g.Stage == nil || g.Stage.Info.Tutorial == "" {
+		return
+	}
+	centred := 0
+	for _, label := range stagefile.TutorialLabels(g.Stage.Info.Tutorial) {
+		if !label.Positioned {
+			drawCenteredText(screen, label.Text, g.MarkFont, tutorialY+float64(centred*22), tutorialColor, 1)
+			centred++
+			continue
+		}
+		var c color.Color = tutorialColor
+		if label.Color.A != 0 {
+			c = label.Color
+		}
+		drawText(screen, label.Text, g.Font, float64(label.X), float64(label.Y), c)
+	}
+}
+
+// drawCenteredText draws text centred horizontally on the screen with the given opacity
+func drawCenteredText(screen *ebiten.Image, s string, face *text.GoTextFace, y float64, c color.Color, alpha float32) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(ScreenWidth/2, y)
+	op.PrimaryAlign = text.AlignCenter
+	op.ColorScale.ScaleWithColor(c)
+	op.ColorScale.ScaleAlpha(alpha)
+	text.Draw(screen, s, face, op)
+}
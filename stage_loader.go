@@ -1,11 +1,31 @@
 package main
 
-import "image/color"
+import (
+	"log"
+
+	"github.com/pankona/egj2025/internal/stagefile"
+)
 
 // StageLoader manages stage loading and progression
 type StageLoader struct {
 	CurrentStageIndex int
 	TotalStages       int
+	Source            StageSource // Stages loaded at runtime (nil = built-in stages only)
+}
+
+// builtinStage is a stage compiled into the game. The stages are listed in
+// builtinStages, which stagegen generates into stage_registry.go.
+type builtinStage struct {
+	Load           func() *Stage
+	StartPositions func() (blueX, blueY, redX, redY float64)
+}
+
+// builtinStageOrDefault returns the built-in stage with the given index, or stage 1 if there is none
+func builtinStageOrDefault(stageIndex int) builtinStage {
+	if stage, ok := builtinStages[stageIndex]; ok {
+		return stage
+	}
+	return builtinStages[1]
 }
 
 // NewStageLoader creates a new stage loader
@@ -14,52 +34,55 @@ func NewStageLoader() *StageLoader {
 	if DebugMode {
 		startStage = 0 // Start from debug stage
 	}
-	// Determine total stages based on debug mode
-	totalStages := 10
+	return &StageLoader{
+		CurrentStageIndex: startStage,
+		// The built-in stages come from the generated registry (make generate-stages).
+		// Stage packs and stage directories set it from their contents (SetSource).
+		TotalStages: builtinTotalStages(),
+	}
+}
+
+// builtinTotalStages returns the number of built-in stages to play
+func builtinTotalStages() int {
 	if DebugMode {
 		// In debug mode, set to 1 for easier testing of all stages cleared screen
-		totalStages = 1
+		return 1
 	}
+	return lastBuiltinStage
+}
 
-	return &StageLoader{
-		CurrentStageIndex: startStage,
-		// NOTE: TotalStages is hardcoded and must be updated manually when adding stages
-		// TODO: Consider dynamic stage counting for better scalability
-		TotalStages: totalStages,
+// UseBuiltinStages switches back from a stage source to the built-in stages
+func (sl *StageLoader) UseBuiltinStages() {
+	sl.Source = nil
+	sl.TotalStages = builtinTotalStages()
+}
+
+// SetSource loads stages from source instead of the built-in ones
+func (sl *StageLoader) SetSource(source StageSource) {
+	sl.Source = source
+	sl.TotalStages = source.LastStage()
+}
+
+// loadFromSource loads a stage from the runtime source, falling back to the
+// built-in stage (ok = false) if there is no source or the stage cannot be loaded
+func (sl *StageLoader) loadFromSource(stageIndex int) (stage *Stage, start StartPositions, ok bool) {
+	if sl.Source == nil {
+		return nil, StartPositions{}, false
 	}
+	stage, start, err := sl.Source.LoadStage(stageIndex)
+	if err != nil {
+		log.Printf("Using built-in stage %d: %v", stageIndex, err)
+		return nil, StartPositions{}, false
+	}
+	return stage, start, true
 }
 
 // LoadStage loads the stage by index
-// NOTE: Using switch statement for stage loading. For better scalability with many stages,
-// consider using a map[int]func() *Stage approach for dynamic stage registration.
 func (sl *StageLoader) LoadStage(stageIndex int) *Stage {
-	switch stageIndex {
-	case 0:
-		return LoadStage0()
-	case 1:
-		return LoadStage1()
-	case 2:
-		return LoadStage2()
-	case 3:
-		return LoadStage3()
-	case 4:
-		return LoadStage4()
-	case 5:
-		return LoadStage5()
-	case 6:
-		return LoadStage6()
-	case 7:
-		return LoadStage7()
-	case 8:
-		return LoadStage8()
-	case 9:
-		return LoadStage9()
-	case 10:
-		return LoadStage10()
-	default:
-		// Default to stage 1 if invalid index
-		return LoadStage1()
+	if stage, _, ok := sl.loadFromSource(stageIndex); ok {
+		return stage
 	}
+	return builtinStageOrDefault(stageIndex).Load()
 }
 
 // GetCurrentStage returns the current stage
@@ -76,13 +99,17 @@ func (sl *StageLoader) NextStage() bool {
 	return false
 }
 
-// PreviousStage goes back to the previous stage
-func (sl *StageLoader) PreviousStage() bool {
-	minStage := 1
+// FirstStageIndex returns the lowest selectable stage (the debug stage 0 in debug mode)
+func (sl *StageLoader) FirstStageIndex() int {
 	if DebugMode {
-		minStage = 0
+		return 0
 	}
-	if sl.CurrentStageIndex > minStage {
+	return 1
+}
+
+// PreviousStage goes back to the previous stage
+func (sl *StageLoader) PreviousStage() bool {
+	if sl.CurrentStageIndex > sl.FirstStageIndex() {
 		sl.CurrentStageIndex--
 		return true
 	}
@@ -96,43 +123,31 @@ func (sl *StageLoader) ResetToFirstStage() {
 
 // GetCurrentStageStartPositions returns the starting positions for the current stage
 func (sl *StageLoader) GetCurrentStageStartPositions() (blueX, blueY, redX, redY float64) {
-	switch sl.CurrentStageIndex {
-	case 0:
-		return GetStage0StartPositions()
-	case 1:
-		return GetStage1StartPositions()
-	case 2:
-		return GetStage2StartPositions()
-	case 3:
-		return GetStage3StartPositions()
-	case 4:
-		return GetStage4StartPositions()
-	case 5:
-		return GetStage5StartPositions()
-	case 6:
-		return GetStage6StartPositions()
-	case 7:
-		return GetStage7StartPositions()
-	case 8:
-		return GetStage8StartPositions()
-	case 9:
-		return GetStage9StartPositions()
-	case 10:
-		return GetStage10StartPositions()
-	default:
-		// Default to stage 1 positions if invalid index
-		return GetStage1StartPositions()
+	return sl.StageStartPositions(sl.CurrentStageIndex)
+}
+
+// StageStartPositions returns the starting positions for the stage with the given index
+func (sl *StageLoader) StageStartPositions(stageIndex int) (blueX, blueY, redX, redY float64) {
+	if _, start, ok := sl.loadFromSource(stageIndex); ok {
+		return start.BlueX, start.BlueY, start.RedX, start.RedY
 	}
+	return builtinStageOrDefault(stageIndex).StartPositions()
 }
 
 // Common platform colors and definitions
 var (
-	GroundColor    = color.RGBA{100, 100, 100, 255} // Gray for ground
-	PlatformColor  = color.RGBA{150, 150, 150, 255} // Light gray for platforms
-	GoalColor      = color.RGBA{255, 255, 0, 255}   // Yellow for goal platforms
-	SpikeColor     = color.RGBA{255, 0, 0, 255}     // Red for spikes
-	SpeedUpColor   = color.RGBA{0, 255, 100, 255}   // Green for speed-up platforms
-	SpeedDownColor = color.RGBA{255, 100, 0, 255}   // Orange for speed-down platforms
+	GroundColor      = stagefile.DefaultColors.Ground    // Gray for ground
+	PlatformColor    = stagefile.DefaultColors.Platform  // Light gray for platforms
+	GoalColor        = stagefile.DefaultColors.Goal      // Yellow for goal platforms
+	SpikeColor       = stagefile.DefaultColors.Spike     // Red for spikes
+	SpeedUpColor     = stagefile.DefaultColors.SpeedUp   // Green for speed-up platforms
+	SpeedDownColor   = stagefile.DefaultColors.SpeedDown // Orange for speed-down platforms
+	OneWayColor      = stagefile.DefaultColors.OneWay    // Wooden brown for one-way platforms
+	ReverseColor     = stagefile.DefaultColors.Reverse   // Purple for direction-reversal tiles
+	SpringColor      = stagefile.DefaultColors.Spring    // Pink for spring pads
+	ConveyorColor    = stagefile.DefaultColors.Conveyor  // Steel blue for conveyors
+	CollectibleColor = stagefile.DefaultColors.Star      // Gold for collectible stars
+	HazardColor      = stagefile.DefaultColors.Hazard    // Crimson for enemies and hazards
 )
 
 // Helper functions for common platform types
@@ -229,6 +244,55 @@ func CreateGridSpeedDownPlatform(x, y, width, height int) Platform {
 	return GridPlatformToPlatform(gridPlatform, SpeedDownColor)
 }
 
+// CreateGridOneWayPlatform creates a jump-through platform using grid coordinates
+func CreateGridOneWayPlatform(x, y, width, height int) Platform {
+	gridPlatform := GridPlatform{
+		Position:      GridPosition{X: x, Y: y},
+		Size:          GridSize{Width: width, Height: height},
+		IsGoal:        false,
+		SpeedModifier: 1.0,
+		OneWay:        true,
+	}
+	return GridPlatformToPlatform(gridPlatform, OneWayColor)
+}
+
+// CreateGridReversePlatform creates a tile that flips the direction of units walking onto it
+func CreateGridReversePlatform(x, y, width, height int) Platform {
+	gridPlatform := GridPlatform{
+		Position:      GridPosition{X: x, Y: y},
+		Size:          GridSize{Width: width, Height: height},
+		IsGoal:        false,
+		SpeedModifier: 1.0,
+		Reverse:       true,
+	}
+	return GridPlatformToPlatform(gridPlatform, ReverseColor)
+}
+
+// CreateGridSpringPlatform creates a spring pad that launches units higher than a jump
+func CreateGridSpringPlatform(x, y, width, height int) Platform {
+	gridPlatform := GridPlatform{
+		Position:      GridPosition{X: x, Y: y},
+		Size:          GridSize{Width: width, Height: height},
+		IsGoal:        false,
+		SpeedModifier: 1.0,
+		Bounce:        SPRING_STRENGTH,
+	}
+	return GridPlatformToPlatform(gridPlatform, SpringColor)
+}
+
+// CreateGridConveyorPlatform creates a conveyor using grid coordinates
+// direction: 1 pushes units right, -1 pushes them left
+func CreateGridConveyorPlatform(x, y, width, height, direction int) Platform {
+	gridPlatform := GridPlatform{
+		Position:      GridPosition{X: x, Y: y},
+		Size:          GridSize{Width: width, Height: height},
+		IsGoal:        false,
+		SpeedModifier: 1.0,
+		Conveyor:      CONVEYOR_SPEED * float64(direction),
+	}
+	return GridPlatformToPlatform(gridPlatform, ConveyorColor)
+}
+
 // CreateGridSpike creates a spike using grid coordinates
 func CreateGridSpike(x, y int) Spike {
 	return Spike{
@@ -237,3 +301,42 @@ func CreateGridSpike(x, y int) Spike {
 		Color: SpikeColor,
 	}
 }
+
+// CreateGridSpikeFacing creates a spike whose tip points in the given direction
+func CreateGridSpikeFacing(x, y int, direction SpikeDirection) Spike {
+	spike := CreateGridSpike(x, y)
+	spike.Direction = direction
+	return spike
+}
+
+// CreateGridUnitGoalPlatform creates a goal platform that only accepts the given unit
+func CreateGridUnitGoalPlatform(x, y, width, height int, unit UnitKind) Platform {
+	gridPlatform := GridPlatform{
+		Position:      GridPosition{X: x, Y: y},
+		Size:          GridSize{Width: width, Height: height},
+		IsGoal:        true,
+		SpeedModifier: 1.0,
+		Only:          unit,
+	}
+	return GridPlatformToPlatform(gridPlatform, GoalColor)
+}
+
+// CreateGridUnitPlatform creates a platform that is solid only for the given unit
+func CreateGridUnitPlatform(x, y, width, height int, unit UnitKind) Platform {
+	gridPlatform := GridPlatform{
+		Position:      GridPosition{X: x, Y: y},
+		Size:          GridSize{Width: width, Height: height},
+		IsGoal:        false,
+		SpeedModifier: 1.0,
+		Only:          unit,
+	}
+	return GridPlatformToPlatform(gridPlatform, unitKindColor(unit))
+}
+
+// CreateGridUnitSpike creates a spike that is harmful only to the given unit
+func CreateGridUnitSpike(x, y int, unit UnitKind) Spike {
+	spike := CreateGridSpike(x, y)
+	spike.Color = unitKindColor(unit)
+	spike.Only = unit
+	return spike
+}
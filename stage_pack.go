@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+// StagePackManifestName is the manifest file at the root of a stage pack
+const StagePackManifestName = "pack.json"
+
+// StagePackManifest describes a stage pack: a directory or zip file holding a
+// pack.json manifest and the stage files it lists, for example
+//
+//	{
+//	  "name": "Spring Tour",
+//	  "author": "pankona",
+//	  "music": "bgm.mp3",
+//	  "stages": ["intro.txt", "springs.txt", "finale.txt"]
+//	}
+type StagePackManifest struct {
+	Name   string   `json:"name"`
+	Author string   `json:"author"`
+	Music  string   `json:"music,omitempty"` // MP3 played instead of the built-in BGM (optional)
+	Stages []string `json:"stages"`          // Stage files in play order (stage 1 first)
+}
+
+// StagePack is a loaded stage pack. Stages are numbered from 1 in manifest order.
+type StagePack struct {
+	Manifest StagePackManifest
+	Music    []byte // Contents of the music file (nil = keep the built-in BGM)
+	stages   []*loadedStage
+	shared   bool // Whether the pack holds the stage of a shared code (see SharedStagePack)
+}
+
+// OpenStagePack loads a stage pack from a directory or a zip file on disk
+func OpenStagePack(name string) (*StagePack, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return LoadStagePack(os.DirFS(name))
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ReadStagePackZip(data)
+}
+
+// ReadStagePackZip loads a stage pack from the contents of a zip file
+func ReadStagePackZip(data []byte) (*StagePack, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stage pack zip: %w", err)
+	}
+	return LoadStagePack(archive)
+}
+
+// LoadStagePack loads a stage pack whose manifest is at the root of fsys or
+// in a top-level folder (as when a pack folder is zipped), parsing every stage
+func LoadStagePack(fsys fs.FS) (*StagePack, error) {
+	root, err := findStagePackRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(root, StagePackManifestName)
+	if err != nil {
+		return nil, err
+	}
+	pack := &StagePack{}
+	if err := json.Unmarshal(data, &pack.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StagePackManifestName, err)
+	}
+	if len(pack.Manifest.Stages) == 0 {
+		return nil, fmt.Errorf("%s lists no stages", StagePackManifestName)
+	}
+
+	for _, name := range pack.Manifest.Stages {
+		data, err := fs.ReadFile(root, name)
+		if err != nil {
+			return nil, err
+		}
+		stage, start, err := ParseStage(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		pack.stages = append(pack.stages, newLoadedStage(stage, start))
+	}
+	if pack.Manifest.Music != "" {
+		if pack.Music, err = fs.ReadFile(root, pack.Manifest.Music); err != nil {
+			return nil, err
+		}
+	}
+	return pack, nil
+}
+
+// findStagePackRoot returns the folder of fsys that holds the manifest
+func findStagePackRoot(fsys fs.FS) (fs.FS, error) {
+	if _, err := fs.Stat(fsys, StagePackManifestName); err == nil {
+		return fsys, nil
+	}
+	matches, err := fs.Glob(fsys, path.Join("*", StagePackManifestName))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("no %s found in the stage pack", StagePackManifestName)
+	}
+	return fs.Sub(fsys, path.Dir(matches[0]))
+}
+
+// LoadDroppedStagePack loads a stage pack dropped onto the window:
+// a pack folder, the files of a pack, or a zip file
+func LoadDroppedStagePack(dropped fs.FS) (*StagePack, error) {
+	pack, err := LoadStagePack(dropped)
+	if err == nil {
+		return pack, nil
+	}
+	entries, dirErr := fs.ReadDir(dropped, ".")
+	if dirErr != nil {
+		return nil, dirErr
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(path.Ext(entry.Name()), ".zip") {
+			continue
+		}
+		data, err := fs.ReadFile(dropped, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		return ReadStagePackZip(data)
+	}
+	return nil, fmt.Errorf("drop a stage pack folder or zip file: %w", err)
+}
+
+// LoadStage returns the pack's stage with the given number
+func (p *StagePack) LoadStage(index int) (*Stage, StartPositions, error) {
+	if index < 1 || index > len(p.stages) {
+		return nil, StartPositions{}, fmt.Errorf("stage pack %q has no stage %d", p.Manifest.Name, index)
+	}
+	loaded := p.stages[index-1]
+	return loaded.current(), loaded.start, nil
+}
+
+// LastStage returns the number of stages in the pack
+func (p *StagePack) LastStage() int {
+	return len(p.stages)
+}
+
+// loadStagePack switches the game to a stage pack, starting again from its first stage
+func (g *Game) loadStagePack(pack *StagePack) {
+	g.StageLoader.SetSource(pack)
+	g.StageLoader.ResetToFirstStage()
+	g.syncStatsSource()
+	g.clearCheckpoint()
+	g.StageWatcher = nil // Only the stage directory is watched
+	if pack.Music != nil && g.SoundManager != nil {
+		if err := g.SoundManager.SetBGM(pack.Music); err != nil {
+			log.Printf("Keeping the built-in BGM: %v", err)
+		}
+	}
+	if g.State == StateStageSelect {
+		g.openStageSelect()
+	}
+	g.showNotice(fmt.Sprintf("Loaded %q by %s (%d stages)", pack.Manifest.Name, pack.Manifest.Author, pack.LastStage()))
+}
+
+// handleDroppedStagePack loads a stage pack dropped onto the window on the title
+// and stage select screens
+func (g *Game) handleDroppedStagePack(dropped fs.FS) {
+	if dropped == nil || (g.State != StateTitle && g.State != StateStageSelect) {
+		return
+	}
+	pack, err := LoadDroppedStagePack(dropped)
+	if err != nil {
+		g.showNotice(fmt.Sprintf("Stage pack error: %v", err))
+		return
+	}
+	g.loadStagePack(pack)
+}
@@ -0,0 +1,21 @@
+// Code generated by stagegen; DO NOT EDIT.
+
+package main
+
+// builtinStages lists the stages compiled into the game by stage number
+var builtinStages = map[int]builtinStage{
+	0:  {Load: LoadStage0, StartPositions: GetStage0StartPositions},   // stage00.txt
+	1:  {Load: LoadStage1, StartPositions: GetStage1StartPositions},   // stage01.txt
+	2:  {Load: LoadStage2, StartPositions: GetStage2StartPositions},   // stage02.txt
+	3:  {Load: LoadStage3, StartPositions: GetStage3StartPositions},   // stage03.txt
+	4:  {Load: LoadStage4, StartPositions: GetStage4StartPositions},   // stage04.txt
+	5:  {Load: LoadStage5, StartPositions: GetStage5StartPositions},   // stage05.txt
+	6:  {Load: LoadStage6, StartPositions: GetStage6StartPositions},   // stage06.txt
+	7:  {Load: LoadStage7, StartPositions: GetStage7StartPositions},   // stage07.txt
+	8:  {Load: LoadStage8, StartPositions: GetStage8StartPositions},   // stage08.txt
+	9:  {Load: LoadStage9, StartPositions: GetStage9StartPositions},   // stage09.txt
+	10: {Load: LoadStage10, StartPositions: GetStage10StartPositions}, // stage10.txt
+}
+
+// lastBuiltinStage is the highest built-in stage number
+const lastBuiltinStage = 10
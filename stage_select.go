@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Stage select layout
+const (
+	stageSelectListX     = 60
+	stageSelectListY     = 90
+	stageSelectRowHeight = 46
+	stageSelectRows      = 10 // Rows that fit above the key help; longer lists scroll
+	stageSelectRowWidth  = 320
+	stageSelectPanelX    = 420
+)
+
+var (
+	stageSelectBackground = color.RGBA{20, 30, 50, 255}
+	stageSelectHighlight  = color.RGBA{60, 90, 140, 255}
+	stageSelectDimColor   = color.RGBA{160, 170, 190, 255}
+)
+
+// openStageSelect shows the stage select screen with the first uncleared stage selected
+func (g *Game) openStageSelect() {
+	g.State = StateStageSelect
+	g.SelectedStage = g.StageLoader.FirstStageIndex()
+	for stage := g.StageLoader.FirstStageIndex(); stage <= g.StageLoader.TotalStages; stage++ {
+		if g.stageStats(stage).Clears == 0 {
+			g.SelectedStage = stage
+			break
+		}
+	}
+	g.scrollStageSelectToSelection()
+}
+
+// moveStageSelection moves the selection by delta, wrapping around the stage list
+func (g *Game) moveStageSelection(delta int) {
+	first := g.StageLoader.FirstStageIndex()
+	count := g.StageLoader.TotalStages - first + 1
+	g.SelectedStage = first + ((g.SelectedStage-first+delta)%count+count)%count
+	g.scrollStageSelectToSelection()
+}
+
+// stageSelectFirstRow returns the stage shown in the top row of the list
+func (g *Game) stageSelectFirstRow() int {
+	first := g.StageLoader.FirstStageIndex()
+	last := max(first, g.StageLoader.TotalStages-stageSelectRows+1)
+	return min(max(g.stageSelectTop, first), last)
+}
+
+// scrollStageSelectToSelection scrolls the list as little as needed to show the selected stage
+func (g *Game) scrollStageSelectToSelection() {
+	top := g.stageSelectFirstRow()
+	if g.SelectedStage < top {
+		top = g.SelectedStage
+	} else if g.SelectedStage >= top+stageSelectRows {
+		top = g.SelectedStage - stageSelectRows + 1
+	}
+	g.stageSelectTop = top
+	g.stageSelectTop = g.stageSelectFirstRow() // Keep the last page full
+}
+
+// scrollStageSelectPage scrolls the list by a page (direction 1 = down, -1 = up),
+// moving the selection onto the page if it scrolled out of view
+func (g *Game) scrollStageSelectPage(direction int) {
+	g.stageSelectTop = g.stageSelectFirstRow() + direction*stageSelectRows
+	top := g.stageSelectFirstRow()
+	g.stageSelectTop = top // Stop at either end of the list
+	if g.SelectedStage < top || g.SelectedStage >= top+stageSelectRows {
+		g.SelectedStage = top
+	}
+}
+
+// startStage begins playing the given stage from its spawn positions and shows its title card
+func (g *Game) startStage(stage int) {
+	g.StageLoader.CurrentStageIndex = stage
+	g.clearCheckpoint()
+	g.resetGame()
+	g.showTitleCard()
+}
+
+// stageStats returns saved stats for a stage without creating an entry
+func (g *Game) stageStats(stage int) StageStats {
+	if g.Stats == nil {
+		return StageStats{}
+	}
+	return g.Stats.SavedStageStats(stage)
+}
+
+// stageChallenges returns the challenges of a stage; stage packs and stage
+// directories number their stages independently, so they have none
+func (g *Game) stageChallenges(stage int) []Challenge {
+	if g.StageLoader.Source != nil {
+		return nil
+	}
+	return StageChallenges[stage]
+}
+
+// stageSelectRowAt returns the stage whose row contains the given screen position, or -1
+func (g *Game) stageSelectRowAt(x, y int) int {
+	if x < stageSelectListX || x >= stageSelectListX+stageSelectRowWidth || y < stageSelectListY {
+		return -1
+	}
+	row := (y - stageSelectListY) / stageSelectRowHeight
+	stage := g.stageSelectFirstRow() + row
+	if row >= stageSelectRows || stage > g.StageLoader.TotalStages {
+		return -1
+	}
+	return stage
+}
+
+// stageSelectScrollAt returns the scroll direction of the arrow at the given
+// screen position above or below the list (-1 = up, 1 = down), or 0
+func (g *Game) stageSelectScrollAt(x, y int) int {
+	if x < stageSelectListX || x >= stageSelectListX+stageSelectRowWidth {
+		return 0
+	}
+	top := g.stageSelectFirstRow()
+	listBottom := stageSelectListY + stageSelectRows*stageSelectRowHeight
+	switch {
+	case y >= stageSelectListY-stageSelectRowHeight/2 && y < stageSelectListY && top > g.StageLoader.FirstStageIndex():
+		return -1
+	case y >= listBottom && y < listBottom+stageSelectRowHeight/2 && top+stageSelectRows <= g.StageLoader.TotalStages:
+		return 1
+	}
+	return 0
+}
+
+// updateStageSelect handles stage select input
+func (g *Game) updateStageSelect() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		g.moveStageSelection(-1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		g.moveStageSelection(1)
+	case inpututil.IsKeyJustPressed(ebiten.KeySpace), inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		g.startStage(g.SelectedStage)
+		g.SoundManager.StartBGM()
+		return
+	case inpututil.IsKeyJustPressed(CopyStageCodeKey):
+		g.copyStageCode(g.SelectedStage)
+	case inpututil.IsKeyJustPressed(PasteStageCodeKey):
+		g.pasteStageCode()
+	}
+
+	// Tapping a row selects it; tapping the selected row starts it.
+	// Tapping the arrows above or below the list scrolls it by a page.
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		if direction := g.stageSelectScrollAt(ebiten.TouchPosition(id)); direction != 0 {
+			g.scrollStageSelectPage(direction)
+			continue
+		}
+		stage := g.stageSelectRowAt(ebiten.TouchPosition(id))
+		if stage < 0 {
+			continue
+		}
+		if stage == g.SelectedStage {
+			g.startStage(stage)
+			g.SoundManager.StartBGM()
+			return
+		}
+		g.SelectedStage = stage
+	}
+}
+
+// drawStageSelect draws the stage list with collected stars and the selected stage's challenges
+func (g *Game) drawStageSelect(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, ScreenWidth, ScreenHeight, stageSelectBackground, false)
+	drawText(screen, "SELECT STAGE", g.Font, stageSelectListX, 30, WhiteColor)
+
+	top := g.stageSelectFirstRow()
+	last := min(g.StageLoader.TotalStages, top+stageSelectRows-1)
+	if top > g.StageLoader.FirstStageIndex() {
+		drawText(screen, "▲", g.MarkFont, stageSelectListX+stageSelectRowWidth/2, stageSelectListY-22, stageSelectDimColor)
+	}
+	if last < g.StageLoader.TotalStages {
+		drawText(screen, "▼", g.MarkFont, stageSelectListX+stageSelectRowWidth/2, stageSelectListY+stageSelectRows*stageSelectRowHeight, stageSelectDimColor)
+	}
+	for stage := top; stage <= last; stage++ {
+		y := float64(stageSelectListY + (stage-top)*stageSelectRowHeight)
+		if stage == g.SelectedStage {
+			vector.DrawFilledRect(screen, stageSelectListX, float32(y), stageSelectRowWidth, stageSelectRowHeight-6, stageSelectHighlight, false)
+		}
+		stats := g.stageStats(stage)
+		label := fmt.Sprintf("Stage %d", stage)
+		if stats.Clears > 0 {
+			label += "  CLEAR"
+		}
+		drawText(screen, label, g.Font, stageSelectListX+10, y+4, WhiteColor)
+		if stats.Collectibles > 0 {
+			drawText(screen, starCounter(stats.BestCollected, stats.Collectibles), g.MarkFont, stageSelectListX+250, y+12, CollectibleColor)
+		}
+	}
+
+	// Details of the selected stage
+	stats := g.stageStats(g.SelectedStage)
+	panelY := float64(stageSelectListY)
+	drawText(screen, fmt.Sprintf("Stage %d", g.SelectedStage), g.Font, stageSelectPanelX, panelY, WhiteColor)
+	panelY += 40
+	if stats.BestClearTicks > 0 {
+		drawText(screen, fmt.Sprintf("Best time: %.2fs", float64(stats.BestClearTicks)/ebiten.DefaultTPS), g.MarkFont, stageSelectPanelX, panelY, WhiteColor)
+		panelY += 24
+	}
+	if stats.Collectibles > 0 {
+		drawText(screen, "Stars: "+starCounter(stats.BestCollected, stats.Collectibles), g.MarkFont, stageSelectPanelX, panelY, CollectibleColor)
+		panelY += 24
+	}
+	challenges := g.stageChallenges(g.SelectedStage)
+	if len(challenges) > 0 {
+		panelY += 10
+		drawText(screen, "Challenges", g.MarkFont, stageSelectPanelX, panelY, stageSelectDimColor)
+		panelY += 24
+		for i, challenge := range challenges {
+			mark := "○ "
+			if stats.ChallengeCompleted(i) {
+				mark = "● "
+			}
+			drawText(screen, mark+challenge.Description(), g.MarkFont, stageSelectPanelX, panelY, WhiteColor)
+			panelY += 22
+		}
+	}
+
+	drawText(screen, "UP/DOWN: select   SPACE: start   (tap a stage twice)   C: copy stage code   V: paste code", g.MarkFont, stageSelectListX, ScreenHeight-30, stageSelectDimColor)
+}
+
+// drawClearResult draws the stars and challenges of the clear that just happened
+func (g *Game) drawClearResult(screen *ebiten.Image, y float64) {
+	if g.Stats == nil {
+		return
+	}
+	result := g.Stats.LastClear
+	x := float64(ScreenWidth/2 - 140)
+	if result.Collectibles > 0 {
+		drawText(screen, "Stars: "+starCounter(result.Collected, result.Collectibles), g.MarkFont, x, y, CollectibleColor)
+		y += 22
+	}
+	for _, challenge := range g.stageChallenges(g.StageLoader.CurrentStageIndex) {
+		mark := "○ "
+		if !g.attemptAssisted && challenge.Met(result) {
+			mark = "● "
+		}
+		drawText(screen, mark+challenge.Description(), g.MarkFont, x, y, WhiteColor)
+		y += 22
+	}
+}
+
+// starCounter formats collected stars such as "★★☆ 2/3"
+func starCounter(collected, total int) string {
+	stars := ""
+	for i := 0; i < total && i < 5; i++ {
+		if i < collected {
+			stars += "★"
+		} else {
+			stars += "☆"
+		}
+	}
+	return fmt.Sprintf("%s %d/%d", stars, collected, total)
+}
+
+// drawText draws a single line of text at the given position
+func drawText(screen *ebiten.Image, s string, face *text.GoTextFace, x, y float64, c color.Color) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x, y)
+	op.ColorScale.ScaleWithColor(c)
+	text.Draw(screen, s, face, op)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pankona/egj2025/internal/stagefile"
+)
+
+// StartPositions are the spawn positions of both units in pixels
+type StartPositions struct {
+	BlueX, BlueY float64
+	RedX, RedY   float64
+}
+
+// StageSource provides stages that replace the built-in (generated) ones
+type StageSource interface {
+	// LoadStage returns the stage with the given index and its start positions
+	LoadStage(index int) (*Stage, StartPositions, error)
+	// LastStage returns the highest stage index the source provides
+	LastStage() int
+}
+
+// DirStageSource loads stageNN.txt files from a directory at runtime.
+// Each file is parsed on first use and kept until it is reloaded.
+type DirStageSource struct {
+	Dir    string
+	files  map[int]string       // Stage index -> file path
+	stages map[int]*loadedStage // Last successfully parsed version of each stage
+}
+
+// loadedStage is a parsed stage file
+type loadedStage struct {
+	stage   *Stage
+	start   StartPositions
+	palette int // Index of the palette the stage's colours come from
+}
+
+// newLoadedStage keeps a stage built with the current palette
+func newLoadedStage(stage *Stage, start StartPositions) *loadedStage {
+	return &loadedStage{stage: stage, start: start, palette: CurrentPaletteIndex}
+}
+
+// current returns the stage, repainting it first if the palette has changed since it was built
+func (l *loadedStage) current() *Stage {
+	if l.palette != CurrentPaletteIndex {
+		recolorStage(l.stage, Palettes[l.palette])
+		l.palette = CurrentPaletteIndex
+	}
+	return l.stage
+}
+
+// NewDirStageSource finds the stage files in a directory
+func NewDirStageSource(dir string) (*DirStageSource, error) {
+	source := &DirStageSource{Dir: dir, stages: make(map[int]*loadedStage)}
+	if err := source.scan(); err != nil {
+		return nil, err
+	}
+	if len(source.files) == 0 {
+		return nil, fmt.Errorf("no stageNN.txt files in %s", dir)
+	}
+	return source, nil
+}
+
+// scan lists the stage files currently in the directory
+func (s *DirStageSource) scan() error {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read stage directory: %w", err)
+	}
+	s.files = make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if index, err := stagefile.StageNumber(entry.Name()); err == nil {
+			s.files[index] = filepath.Join(s.Dir, entry.Name())
+		}
+	}
+	return nil
+}
+
+// LoadStage returns the stage with the given index, parsing its file on first use
+func (s *DirStageSource) LoadStage(index int) (*Stage, StartPositions, error) {
+	if loaded, ok := s.stages[index]; ok {
+		return loaded.current(), loaded.start, nil
+	}
+	if err := s.Reload(index); err != nil {
+		return nil, StartPositions{}, err
+	}
+	loaded := s.stages[index]
+	return loaded.current(), loaded.start, nil
+}
+
+// Reload parses the stage file again. If it cannot be parsed, the previously
+// loaded version is kept so a half-edited file does not break the game.
+func (s *DirStageSource) Reload(index int) error {
+	path, ok := s.files[index]
+	if !ok {
+		return fmt.Errorf("stage %d not found in %s", index, s.Dir)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stage, start, err := ParseStage(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.stages[index] = newLoadedStage(stage, start)
+	return nil
+}
+
+// LastStage returns the highest stage number found in the directory
+func (s *DirStageSource) LastStage() int {
+	last := 0
+	for index := range s.files {
+		last = max(last, index)
+	}
+	return last
+}
+
+// ParseStage builds a stage from a stage file in the ASCII art format
+func ParseStage(data []byte) (*Stage, StartPositions, error) {
+	layout, err := stagefile.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, StartPositions{}, err
+	}
+	stage, start := BuildStage(layout)
+	return stage, start, nil
+}
+
+// BuildStage converts a parsed layout into a stage, producing the same stage
+// as the code cmd/stagegen generates for it
+func BuildStage(layout *stagefile.Layout) (*Stage, StartPositions) {
+	stage := &Stage{
+		Platforms: []Platform{},
+		Spikes:    []Spike{},
+		Info: StageInfo{
+			Title:      layout.Metadata.Title,
+			Author:     layout.Metadata.Author,
+			Difficulty: layout.Metadata.Difficulty,
+			ParTime:    layout.Metadata.ParTime,
+			BGM:        layout.Metadata.BGM,
+			Tutorial:   layout.Metadata.Tutorial,
+		},
+	}
+	for _, p := range layout.Platforms {
+		stage.Platforms = append(stage.Platforms, buildPlatform(p))
+	}
+	for _, s := range layout.Spikes {
+		if s.Unit != stagefile.UnitNone {
+			stage.Spikes = append(stage.Spikes, CreateGridUnitSpike(s.X, s.Y, unitKind(s.Unit)))
+			continue
+		}
+		// stagefile lists spike directions in the same order as SpikeDirection
+		stage.Spikes = append(stage.Spikes, CreateGridSpikeFacing(s.X, s.Y, SpikeDirection(s.Direction)))
+	}
+	for _, c := range layout.Checkpoints {
+		stage.Checkpoints = append(stage.Checkpoints, CreateGridCheckpoint(c.X, c.Y, unitKind(c.Unit)))
+	}
+	for _, c := range layout.Collectibles {
+		stage.Collectibles = append(stage.Collectibles, CreateGridCollectible(c.X, c.Y))
+	}
+	for _, h := range layout.Hazards {
+		switch h.Kind {
+		case stagefile.HazardWalker:
+			stage.Hazards = append(stage.Hazards, CreateGridWalker(h.X, h.Y))
+		case stagefile.HazardFallingBlock:
+			stage.Hazards = append(stage.Hazards, CreateGridFallingBlock(h.X, h.Y))
+		case stagefile.HazardLaser:
+			stage.Hazards = append(stage.Hazards, CreateGridLaser(h.X, h.Y))
+		case stagefile.HazardTurret:
+			stage.Hazards = append(stage.Hazards, CreateGridTurret(h.X, h.Y, h.Direction))
+		}
+	}
+
+	start := StartPositions{
+		BlueX: GridToPixelX(layout.BlueStart.X),
+		BlueY: GridToPixelY(layout.BlueStart.Y),
+		RedX:  GridToPixelX(layout.RedStart.X),
+		RedY:  GridToPixelY(layout.RedStart.Y),
+	}
+	return stage, start
+}
+
+// buildPlatform creates the platform for a parsed rectangle
+func buildPlatform(p stagefile.Platform) Platform {
+	if p.Unit != stagefile.UnitNone {
+		if p.Kind == stagefile.PlatformGoal {
+			return CreateGridUnitGoalPlatform(p.X, p.Y, p.Width, p.Height, unitKind(p.Unit))
+		}
+		return CreateGridUnitPlatform(p.X, p.Y, p.Width, p.Height, unitKind(p.Unit))
+	}
+	switch p.Kind {
+	case stagefile.PlatformGoal:
+		return CreateGridGoalPlatform(p.X, p.Y, p.Width, p.Height)
+	case stagefile.PlatformSpeedUp:
+		return CreateGridSpeedUpPlatform(p.X, p.Y, p.Width, p.Height)
+	case stagefile.PlatformSpeedDown:
+		return CreateGridSpeedDownPlatform(p.X, p.Y, p.Width, p.Height)
+	case stagefile.PlatformOneWay:
+		return CreateGridOneWayPlatform(p.X, p.Y, p.Width, p.Height)
+	case stagefile.PlatformReverse:
+		return CreateGridReversePlatform(p.X, p.Y, p.Width, p.Height)
+	case stagefile.PlatformSpring:
+		return CreateGridSpringPlatform(p.X, p.Y, p.Width, p.Height)
+	case stagefile.PlatformConveyor:
+		return CreateGridConveyorPlatform(p.X, p.Y, p.Width, p.Height, p.Direction)
+	default:
+		return CreateGridPlatform(p.X, p.Y, p.Width, p.Height)
+	}
+}
+
+// unitKind converts a stage file unit to the game's unit kind
+func unitKind(unit stagefile.Unit) UnitKind {
+	switch unit {
+	case stagefile.UnitBlue:
+		return UnitBlue
+	case stagefile.UnitRed:
+		return UnitRed
+	default:
+		return UnitNone
+	}
+}
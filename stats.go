@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// StageStats holds statistics collected for a single stage
+type StageStats struct {
+	Attempts       int    `json:"attempts"`         // Number of attempts started
+	Clears         int    `json:"clears"`           // Number of times the stage was cleared
+	AssistedClears int    `json:"assisted_clears"`  // Number of clears made with assist options enabled
+	Deaths         int    `json:"deaths"`           // Number of deaths
+	Jumps          int    `json:"jumps"`            // Number of jumps by either unit
+	WallHits       int    `json:"wall_hits"`        // Number of wall/platform-side bounces
+	BestClearTicks int    `json:"best_clear_ticks"` // Fewest ticks needed for an unassisted clear (0 = never)
+	BestCollected  int    `json:"best_collected"`   // Most stars collected in a single clear
+	Collectibles   int    `json:"collectibles"`     // Stars available in the stage when it was last cleared
+	Challenges     []bool `json:"challenges"`       // Completed flags for StageChallenges (unassisted clears only)
+}
+
+// ChallengeCompleted reports whether the i-th challenge of the stage has been completed
+func (s *StageStats) ChallengeCompleted(i int) bool {
+	return i < len(s.Challenges) && s.Challenges[i]
+}
+
+// ClearedOnlyWithAssist reports whether every clear of the stage used assist options
+func (s *StageStats) ClearedOnlyWithAssist() bool {
+	return s.Clears > 0 && s.AssistedClears == s.Clears
+}
+
+// StatsRecorder collects per-stage statistics from the gameplay event stream
+type StatsRecorder struct {
+	Stages    map[int]*StageStats            `json:"stages"`            // Built-in stages
+	Sources   map[string]map[int]*StageStats `json:"sources,omitempty"` // Stage packs and stage directories, by statsSourceKey
+	LastClear ClearResult                    `json:"-"`                 // Result of the most recent clear (shown on the cleared screen)
+
+	persist      bool                // Whether to write the stats to the save data after each clear
+	attemptJumps int                 // Jumps in the current attempt, for jump challenges
+	source       string              // Key of the stage source being played ("" = built-in stages)
+	unsaved      map[int]*StageStats // Stats of a source that is not saved (nil = none)
+}
+
+// NewStatsRecorder creates an empty statistics recorder
+func NewStatsRecorder() *StatsRecorder {
+	return &StatsRecorder{
+		Stages:  make(map[int]*StageStats),
+		Sources: make(map[string]map[int]*StageStats),
+	}
+}
+
+// LoadStatsRecorder creates a recorder initialised from the save data.
+// Stats are saved again whenever a stage is cleared.
+func LoadStatsRecorder() *StatsRecorder {
+	sr := NewStatsRecorder()
+	sr.persist = true
+
+	data, err := readSaveData()
+	if err != nil {
+		log.Printf("Failed to read saved stats: %v", err)
+		return sr
+	}
+	if data == nil {
+		return sr
+	}
+	if err := json.Unmarshal(data, sr); err != nil {
+		log.Printf("Failed to parse saved stats: %v", err)
+		sr.Stages = make(map[int]*StageStats)
+		sr.Sources = nil
+	}
+	if sr.Stages == nil {
+		sr.Stages = make(map[int]*StageStats)
+	}
+	if sr.Sources == nil {
+		sr.Sources = make(map[string]map[int]*StageStats)
+	}
+	return sr
+}
+
+// statsSourceKey identifies the stats of a stage source: "" for the built-in
+// stages, and the pack name and contents or the directory for others.
+// Shared stage codes and unknown sources are not saved (save = false).
+func statsSourceKey(source StageSource) (key string, save bool) {
+	switch source := source.(type) {
+	case nil:
+		return "", true
+	case *StagePack:
+		if source.shared {
+			return "shared", false
+		}
+		hash := sha256.New()
+		for index := 1; index <= source.LastStage(); index++ {
+			stage, start, err := source.LoadStage(index)
+			if err == nil {
+				fmt.Fprintln(hash, StageCode(stage, start))
+			}
+		}
+		return fmt.Sprintf("pack:%s:%x", source.Manifest.Name, hash.Sum(nil)[:8]), true
+	case *DirStageSource:
+		dir, err := filepath.Abs(source.Dir)
+		if err != nil {
+			dir = source.Dir
+		}
+		return "dir:" + dir, true
+	default:
+		return fmt.Sprintf("%T", source), false
+	}
+}
+
+// SetSource switches the recorder to the stats of the stages being played.
+// Stats of a source that is not saved start empty and are dropped on the next switch.
+func (sr *StatsRecorder) SetSource(key string, save bool) {
+	sr.source = key
+	sr.unsaved = nil
+	if !save {
+		sr.unsaved = make(map[int]*StageStats)
+	}
+}
+
+// BuiltinStages reports whether the recorder is on the built-in stages,
+// the only ones StageChallenges apply to
+func (sr *StatsRecorder) BuiltinStages() bool {
+	return sr.source == "" && sr.unsaved == nil
+}
+
+// syncStatsSource points the stats at the stage source being played
+func (g *Game) syncStatsSource() {
+	if g.Stats != nil {
+		g.Stats.SetSource(statsSourceKey(g.StageLoader.Source))
+	}
+}
+
+// stages returns the per-stage stats of the current source
+func (sr *StatsRecorder) stages() map[int]*StageStats {
+	switch {
+	case sr.unsaved != nil:
+		return sr.unsaved
+	case sr.source == "":
+		return sr.Stages
+	}
+	stages, ok := sr.Sources[sr.source]
+	if !ok {
+		stages = make(map[int]*StageStats)
+		sr.Sources[sr.source] = stages
+	}
+	return stages
+}
+
+// SavedStageStats returns the stats of a stage of the current source without creating an entry
+func (sr *StatsRecorder) SavedStageStats(stage int) StageStats {
+	if stats := sr.stages()[stage]; stats != nil {
+		return *stats
+	}
+	return StageStats{}
+}
+
+// Save writes the stats to the save data
+func (sr *StatsRecorder) Save() error {
+	data, err := json.Marshal(sr)
+	if err != nil {
+		return err
+	}
+	return writeSaveData(data)
+}
+
+// StageStats returns the statistics for a stage of the current source, creating them if needed
+func (sr *StatsRecorder) StageStats(stage int) *StageStats {
+	stages := sr.stages()
+	stats, ok := stages[stage]
+	if !ok {
+		stats = &StageStats{}
+		stages[stage] = stats
+	}
+	return stats
+}
+
+// SubscribeEvents registers the recorder on the event bus
+func (sr *StatsRecorder) SubscribeEvents(bus *EventBus) {
+	bus.SubscribeAll(sr.handleEvent)
+}
+
+func (sr *StatsRecorder) handleEvent(event Event) {
+	stats := sr.StageStats(event.Stage)
+	switch event.Type {
+	case EventAttemptStarted:
+		stats.Attempts++
+		sr.attemptJumps = 0
+	case EventJumped:
+		stats.Jumps++
+		sr.attemptJumps++
+	case EventHitWall:
+		stats.WallHits++
+	case EventDied:
+		stats.Deaths++
+	case EventStageCleared:
+		stats.Clears++
+		if event.Assisted {
+			stats.AssistedClears++
+		} else if stats.BestClearTicks == 0 || event.Tick < stats.BestClearTicks {
+			stats.BestClearTicks = event.Tick
+		}
+		stats.Collectibles = event.Collectibles
+		stats.BestCollected = min(max(stats.BestCollected, event.Collected), event.Collectibles)
+		sr.LastClear = ClearResult{
+			Ticks:        event.Tick,
+			Jumps:        sr.attemptJumps,
+			Collected:    event.Collected,
+			Collectibles: event.Collectibles,
+		}
+		if !event.Assisted && sr.BuiltinStages() {
+			sr.recordChallenges(event.Stage, stats)
+		}
+		if sr.persist && sr.unsaved == nil {
+			if err := sr.Save(); err != nil {
+				log.Printf("Failed to save stats: %v", err)
+			}
+		}
+	}
+}
+
+// statsSnapshot is the part of the stats that an attempt can change, saved
+// with each frame-step snapshot so rewinding does not count events twice
+type statsSnapshot struct {
+	stage        int
+	stats        StageStats
+	lastClear    ClearResult
+	attemptJumps int
+}
+
+// snapshot copies the stats of a stage
+func (sr *StatsRecorder) snapshot(stage int) statsSnapshot {
+	stats := *sr.StageStats(stage)
+	stats.Challenges = append([]bool(nil), stats.Challenges...)
+	return statsSnapshot{stage: stage, stats: stats, lastClear: sr.LastClear, attemptJumps: sr.attemptJumps}
+}
+
+// restore puts the stats of a stage back into a snapshot's state, saving
+// them again if a clear was undone
+func (sr *StatsRecorder) restore(snapshot statsSnapshot) {
+	stats := sr.StageStats(snapshot.stage)
+	undoneClear := stats.Clears != snapshot.stats.Clears
+	*stats = snapshot.stats
+	stats.Challenges = append([]bool(nil), snapshot.stats.Challenges...)
+	sr.LastClear = snapshot.lastClear
+	sr.attemptJumps = snapshot.attemptJumps
+	if sr.persist && undoneClear {
+		if err := sr.Save(); err != nil {
+			log.Printf("Failed to save stats: %v", err)
+		}
+	}
+}
+
+// recordChallenges marks the stage challenges fulfilled by the last clear
+func (sr *StatsRecorder) recordChallenges(stage int, stats *StageStats) {
+	challenges := StageChallenges[stage]
+	for len(stats.Challenges) < len(challenges) {
+		stats.Challenges = append(stats.Challenges, false)
+	}
+	for i, challenge := range challenges {
+		if challenge.Met(sr.LastClear) {
+			stats.Challenges[i] = true
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// saveDataPath returns the save file location in the user's config directory
+func saveDataPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "union-jumpers", "save.json"), nil
+}
+
+// readSaveData reads the save file for non-WASM builds (nil if none exists yet)
+func readSaveData() ([]byte, error) {
+	path, err := saveDataPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// writeSaveData writes the save file for non-WASM builds
+func writeSaveData(data []byte) error {
+	path, err := saveDataPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
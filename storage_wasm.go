@@ -0,0 +1,43 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// saveDataKey is the localStorage key holding the save data
+const saveDataKey = "union-jumpers-save"
+
+// localStorage returns window.localStorage, or an error if it is unavailable (e.g. private browsing)
+func localStorage() (js.Value, error) {
+	storage := js.Global().Get("window").Get("localStorage")
+	if !storage.Truthy() {
+		return js.Value{}, errors.New("localStorage is not available")
+	}
+	return storage, nil
+}
+
+// readSaveData reads the save data from localStorage for WASM builds (nil if none exists yet)
+func readSaveData() ([]byte, error) {
+	storage, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+	item := storage.Call("getItem", saveDataKey)
+	if item.IsNull() || item.IsUndefined() {
+		return nil, nil
+	}
+	return []byte(item.String()), nil
+}
+
+// writeSaveData writes the save data to localStorage for WASM builds
+func writeSaveData(data []byte) error {
+	storage, err := localStorage()
+	if err != nil {
+		return err
+	}
+	storage.Call("setItem", saveDataKey, string(data))
+	return nil
+}